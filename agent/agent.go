@@ -0,0 +1,107 @@
+// Package agent implements the unlock agent: a background process that holds
+// the derived vault key in memory and encrypts or decrypts values on behalf of
+// other vault-cli processes over a Unix domain socket.
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Request operations understood by the agent
+const (
+	opPing    = "ping"
+	opEncrypt = "encrypt"
+	opDecrypt = "decrypt"
+	opLock    = "lock"
+	opStatus  = "status"
+	opRenew   = "renew"
+)
+
+// Error codes returned by the agent so clients can rebuild typed errors
+const (
+	codeIntegrity = "integrity"
+)
+
+// request is a single message sent from a client to the agent
+type request struct {
+	Op      string   `json:"op"`
+	Data    string   `json:"data,omitempty"`
+	AAD     []byte   `json:"aad,omitempty"`
+	Options *Options `json:"options,omitempty"` // Session limits to renew with
+}
+
+// response is the agent's reply to a request
+type response struct {
+	Data   string  `json:"data,omitempty"`
+	Status *Status `json:"status,omitempty"`
+	Error  string  `json:"error,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+// Default session limits used when unlock does not override them
+const (
+	DefaultIdleTimeout = 15 * time.Minute
+	DefaultMaxLifetime = 8 * time.Hour
+)
+
+// Options controls when an agent session relocks itself. A zero duration disables that limit.
+type Options struct {
+	IdleTimeout time.Duration // Relock after this long without a secret being accessed
+	MaxLifetime time.Duration // Relock this long after unlocking, regardless of activity
+}
+
+// Status describes a running agent session
+type Status struct {
+	UnlockedAt  time.Time     `json:"unlocked_at"`
+	LastAccess  time.Time     `json:"last_access"`
+	IdleTimeout time.Duration `json:"idle_timeout"`
+	MaxLifetime time.Duration `json:"max_lifetime"`
+}
+
+// ExpiresAt returns when the session will relock, or the zero time if it never will
+func (s Status) ExpiresAt() time.Time {
+	var expires time.Time
+	if s.IdleTimeout > 0 {
+		expires = s.LastAccess.Add(s.IdleTimeout)
+	}
+	if s.MaxLifetime > 0 {
+		lifetimeEnd := s.UnlockedAt.Add(s.MaxLifetime)
+		if expires.IsZero() || lifetimeEnd.Before(expires) {
+			expires = lifetimeEnd
+		}
+	}
+	return expires
+}
+
+// Remaining returns the time left before the session relocks, or -1 if it never will
+func (s Status) Remaining(now time.Time) time.Duration {
+	expires := s.ExpiresAt()
+	if expires.IsZero() {
+		return -1
+	}
+	if remaining := expires.Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// SocketDir returns the directory holding agent sockets. It uses $XDG_RUNTIME_DIR
+// when set, falling back to a per-user directory under the system temp dir.
+func SocketDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "vault-cli")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("vault-cli-%d", os.Getuid()))
+}
+
+// SocketPath returns the path of the agent socket for the vault stored at vaultPath.
+// Each vault file gets its own agent, so vaults are locked and unlocked independently.
+func SocketPath(vaultPath string) string {
+	sum := sha256.Sum256([]byte(vaultPath))
+	return filepath.Join(SocketDir(), "agent-"+hex.EncodeToString(sum[:6])+".sock")
+}
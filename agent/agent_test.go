@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	db "vault-cli/database"
+)
+
+// startServer runs an agent on a temporary socket
+func startServer(t *testing.T) (*Server, *Client) {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "agent", "agent.sock") // Listen creates the directory
+
+	server, err := Listen(socketPath, bytes.Repeat([]byte{0x42}, 32), Options{})
+	if err != nil {
+		t.Fatalf("failed to start agent: %v", err)
+	}
+	go server.Serve()
+	t.Cleanup(server.Close)
+
+	return server, Dial(socketPath)
+}
+
+func TestSocketPermissions(t *testing.T) {
+	_, client := startServer(t)
+
+	info, err := os.Stat(client.socketPath)
+	if err != nil {
+		t.Fatalf("failed to stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected socket permissions 0600, got %o", perm)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	_, client := startServer(t)
+
+	aad := []byte("example.com")
+	ciphertext, err := client.Encrypt("mypassword", aad)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	plaintext, err := client.Decrypt(ciphertext, aad)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if plaintext != "mypassword" {
+		t.Errorf("expected 'mypassword', got %q", plaintext)
+	}
+
+	// Integrity errors keep their type across the socket
+	if _, err := client.Decrypt(ciphertext, []byte("other.com")); !errors.Is(err, db.ErrIntegrity) {
+		t.Errorf("expected ErrIntegrity, got %v", err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	_, client := startServer(t)
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("expected agent to be running: %v", err)
+	}
+	if err := client.Lock(); err != nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+	if err := client.Ping(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning after lock, got %v", err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { requestTimeout = timeout }(requestTimeout)
+	requestTimeout = 100 * time.Millisecond
+	_, client := startServer(t)
+
+	// A client that connects and never sends is disconnected instead of holding the agent
+	conn, err := net.Dial("unix", client.socketPath)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected the agent to close the idle connection, got %v", err)
+	}
+
+	if err := client.Ping(); err != nil {
+		t.Errorf("expected agent to keep running: %v", err)
+	}
+}
+
+func TestListenRefusesRunningAgent(t *testing.T) {
+	_, client := startServer(t)
+
+	if _, err := Listen(client.socketPath, bytes.Repeat([]byte{0x42}, 32), Options{}); err == nil {
+		t.Error("expected error starting a second agent on the same socket")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "agent", "agent.sock") // Listen creates the directory
+	server, err := Listen(socketPath, bytes.Repeat([]byte{0x42}, 32), Options{IdleTimeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to start agent: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	served := make(chan struct{})
+	go func() {
+		server.Serve()
+		close(served)
+	}()
+
+	// Accessing secrets keeps the session alive
+	client := Dial(socketPath)
+	for i := 0; i < 3; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if _, err := client.Encrypt("value", nil); err != nil {
+			t.Fatalf("expected session to stay alive while in use: %v", err)
+		}
+	}
+
+	select {
+	case <-served:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected agent to relock after the idle timeout")
+	}
+	if err := client.Ping(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning after idle timeout, got %v", err)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	server, client := startServer(t)
+
+	// A session that never expired gets an idle timeout
+	if err := client.Renew(Options{IdleTimeout: 100 * time.Millisecond}); err != nil {
+		t.Fatalf("failed to renew: %v", err)
+	}
+	status, err := client.Status()
+	if err != nil || status.IdleTimeout != 100*time.Millisecond {
+		t.Fatalf("expected the renewed idle timeout, got %+v, %v", status, err)
+	}
+	select {
+	case <-server.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected agent to relock after the renewed idle timeout")
+	}
+}
+
+func TestListenRefusesUnsafeDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permissions only")
+	}
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	// A directory other users can write to
+	shared := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(shared, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(filepath.Join(shared, "agent.sock"), key, Options{}); err == nil {
+		t.Error("expected Listen to refuse a directory accessible to others")
+	}
+	if err := Dial(filepath.Join(shared, "agent.sock")).Ping(); err == nil || errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected clients to refuse a directory accessible to others, got %v", err)
+	}
+
+	// A symbolic link to a private directory
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(filepath.Join(link, "agent.sock"), key, Options{}); err == nil {
+		t.Error("expected Listen to refuse a symbolic link")
+	}
+}
+
+func TestStatusExpiresAt(t *testing.T) {
+	unlockedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status Status
+		want   time.Time
+	}{
+		{"no limits", Status{UnlockedAt: unlockedAt, LastAccess: unlockedAt}, time.Time{}},
+		{"idle only", Status{UnlockedAt: unlockedAt, LastAccess: unlockedAt.Add(time.Minute), IdleTimeout: 15 * time.Minute}, unlockedAt.Add(16 * time.Minute)},
+		{"lifetime only", Status{UnlockedAt: unlockedAt, LastAccess: unlockedAt, MaxLifetime: time.Hour}, unlockedAt.Add(time.Hour)},
+		{"lifetime first", Status{UnlockedAt: unlockedAt, LastAccess: unlockedAt.Add(55 * time.Minute), IdleTimeout: 15 * time.Minute, MaxLifetime: time.Hour}, unlockedAt.Add(time.Hour)},
+	}
+
+	for _, test := range tests {
+		if got := test.status.ExpiresAt(); !got.Equal(test.want) {
+			t.Errorf("%s: ExpiresAt() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"path/filepath"
+	"time"
+
+	db "vault-cli/database"
+)
+
+// dialTimeout bounds how long a client waits for the agent
+const dialTimeout = 2 * time.Second
+
+// ErrNotRunning is returned when no agent is listening on the socket
+var ErrNotRunning = errors.New("agent is not running")
+
+// Client talks to a running agent. It implements database.Keyring.
+type Client struct {
+	socketPath string
+}
+
+// Dial returns a client for the agent listening on socketPath
+func Dial(socketPath string) *Client {
+	return &Client{socketPath: socketPath}
+}
+
+// Ping checks that the agent is running and holds a key
+func (c *Client) Ping() error {
+	_, err := c.call(request{Op: opPing})
+	return err
+}
+
+// Lock asks the agent to wipe its key and exit
+func (c *Client) Lock() error {
+	_, err := c.call(request{Op: opLock})
+	return err
+}
+
+// Renew restarts the agent's session with new limits, as if the vault had just been unlocked
+func (c *Client) Renew(options Options) error {
+	_, err := c.call(request{Op: opRenew, Options: &options})
+	return err
+}
+
+// Status returns the agent's session status
+func (c *Client) Status() (Status, error) {
+	resp, err := c.do(request{Op: opStatus})
+	if err != nil {
+		return Status{}, err
+	}
+	if resp.Status == nil {
+		return Status{}, errors.New("agent did not report a status")
+	}
+	return *resp.Status, nil
+}
+
+// Encrypt asks the agent to encrypt plaintext bound to aad
+func (c *Client) Encrypt(plaintext string, aad []byte) (string, error) {
+	return c.call(request{Op: opEncrypt, Data: plaintext, AAD: aad})
+}
+
+// Decrypt asks the agent to decrypt ciphertext bound to aad
+func (c *Client) Decrypt(ciphertext string, aad []byte) (string, error) {
+	return c.call(request{Op: opDecrypt, Data: ciphertext, AAD: aad})
+}
+
+// call sends a single request and returns the response data
+func (c *Client) call(req request) (string, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Data, nil
+}
+
+// do sends a single request and waits for the response
+func (c *Client) do(req request) (response, error) {
+	// Secrets are only sent to an agent whose socket other users cannot have planted
+	if err := checkSocketDir(filepath.Dir(c.socketPath)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return response{}, ErrNotRunning
+		}
+		return response{}, fmt.Errorf("refusing to use the agent: %w", err)
+	}
+	conn, err := net.DialTimeout("unix", c.socketPath, dialTimeout)
+	if err != nil {
+		return response{}, ErrNotRunning
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return response{}, err
+	}
+
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return response{}, err
+	}
+
+	if resp.Error != "" {
+		if resp.Code == codeIntegrity {
+			return response{}, db.ErrIntegrity
+		}
+		return response{}, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+var _ db.Keyring = (*Client)(nil)
@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	db "vault-cli/database"
+)
+
+// requestTimeout bounds how long a connection may take to send its request and read the response,
+// so that a client which connects and never sends cannot keep the agent or its goroutine alive
+var requestTimeout = 5 * time.Second
+
+// Server holds the vault key and answers requests on a Unix domain socket
+type Server struct {
+	mu         sync.Mutex
+	keyring    *db.LocalKeyring
+	listener   net.Listener
+	done       chan struct{}
+	renewed    chan struct{} // Signals watch that the session was renewed
+	options    Options
+	unlockedAt time.Time
+	lastAccess time.Time
+}
+
+// Listen creates the agent socket with 0600 permissions inside a 0700 directory, refusing a directory
+// that already exists but is not owned by the user or is accessible to others.
+// A stale socket left by an agent that is no longer running is removed.
+func Listen(socketPath string, key []byte, options Options) (*Server, error) {
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %v", err)
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(socketPath); err == nil {
+		if Dial(socketPath).Ping() == nil {
+			return nil, errors.New("an agent is already running")
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %v", err)
+		}
+	}
+
+	listener, err := listenUnix(socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", socketPath, err)
+	}
+
+	now := time.Now()
+	return &Server{
+		keyring:    db.NewLocalKeyring(key),
+		listener:   listener,
+		done:       make(chan struct{}),
+		renewed:    make(chan struct{}, 1),
+		options:    options,
+		unlockedAt: now,
+		lastAccess: now,
+	}, nil
+}
+
+// Serve answers requests until the agent is locked, closed or its session expires
+func (s *Server) Serve() error {
+	go s.watch()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close wipes the key and stops the server. It is safe to call more than once.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	close(s.done)
+	s.keyring.Wipe()
+	s.listener.Close()
+}
+
+// status returns the current session status. The caller must hold s.mu.
+func (s *Server) status() Status {
+	return Status{
+		UnlockedAt:  s.unlockedAt,
+		LastAccess:  s.lastAccess,
+		IdleTimeout: s.options.IdleTimeout,
+		MaxLifetime: s.options.MaxLifetime,
+	}
+}
+
+// watch closes the server once the idle timeout or maximum lifetime is reached
+func (s *Server) watch() {
+	for {
+		s.mu.Lock()
+		expires := s.status().ExpiresAt()
+		s.mu.Unlock()
+
+		var timeout <-chan time.Time
+		if !expires.IsZero() {
+			wait := time.Until(expires)
+			if wait <= 0 {
+				s.Close()
+				return
+			}
+			timeout = time.After(wait)
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-s.renewed:
+		case <-timeout:
+		}
+	}
+}
+
+// handle answers a single request on conn
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	resp := s.dispatch(req)
+	_ = json.NewEncoder(conn).Encode(resp)
+
+	if req.Op == opLock {
+		s.Close()
+	}
+}
+
+// dispatch performs the requested operation while holding the key
+func (s *Server) dispatch(req request) response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return response{Error: "agent is locked"}
+	default:
+	}
+
+	var (
+		data string
+		err  error
+	)
+	switch req.Op {
+	case opPing, opLock:
+	case opStatus:
+		status := s.status()
+		return response{Status: &status}
+	case opRenew:
+		if req.Options == nil {
+			err = errors.New("no session options to renew with")
+			break
+		}
+		now := time.Now()
+		s.options, s.unlockedAt, s.lastAccess = *req.Options, now, now
+		select {
+		case s.renewed <- struct{}{}:
+		default: // watch has a renewal pending already
+		}
+	case opEncrypt:
+		s.lastAccess = time.Now()
+		data, err = s.keyring.Encrypt(req.Data, req.AAD)
+	case opDecrypt:
+		s.lastAccess = time.Now()
+		data, err = s.keyring.Decrypt(req.Data, req.AAD)
+	default:
+		err = fmt.Errorf("unknown operation: %q", req.Op)
+	}
+
+	if err != nil {
+		resp := response{Error: err.Error()}
+		if errors.Is(err, db.ErrIntegrity) {
+			resp.Code = codeIntegrity
+		}
+		return resp
+	}
+	return response{Data: data}
+}
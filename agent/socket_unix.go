@@ -0,0 +1,44 @@
+//go:build unix
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// umaskMutex serializes changes to the process umask, which is shared by every goroutine
+var umaskMutex sync.Mutex
+
+// checkSocketDir verifies that dir is a real directory owned by the current user and accessible
+// only by them. Otherwise another user could have created it first to stand up a fake agent.
+func checkSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 || !info.IsDir() {
+		return fmt.Errorf("agent socket directory %s is not a directory", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("agent socket directory %s is owned by another user", dir)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("agent socket directory %s has permissions %o, expected 700", dir, perm)
+	}
+	return nil
+}
+
+// listenUnix creates the socket at path with 0600 permissions from the start, so that it is never
+// accessible to other users, even briefly
+func listenUnix(path string) (net.Listener, error) {
+	umaskMutex.Lock()
+	defer umaskMutex.Unlock()
+
+	previous := syscall.Umask(0177)
+	defer syscall.Umask(previous)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,128 @@
+// Package breach looks up passwords in a local copy of the Pwned Passwords list.
+//
+// The list is the SHA-1 version ordered by hash, as downloaded from
+// https://haveibeenpwned.com/Passwords: one "HASH:COUNT" line per password, sorted by
+// hash. Lookups binary-search the file by byte offset, so it is never loaded into
+// memory and nothing is sent over the network.
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DB is an open Pwned Passwords file
+type DB struct {
+	file *os.File
+	size int64
+}
+
+// Open opens the Pwned Passwords file at path
+func Open(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breach database: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open breach database: %w", err)
+	}
+	return &DB{file: file, size: info.Size()}, nil
+}
+
+// Close closes the file
+func (d *DB) Close() error {
+	return d.file.Close()
+}
+
+// Count returns how often password appears in the breach data, or 0 if it does not
+func (d *DB) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	return d.CountHash(hex.EncodeToString(sum[:]))
+}
+
+// CountHash returns the count recorded for a hex-encoded SHA-1 hash, or 0 if it is not listed
+func (d *DB) CountHash(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+
+	// Every line starting in [lo, hi) may hold the hash
+	lo, hi := int64(0), d.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := d.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		lineHash, count, _ := strings.Cut(line, ":")
+		if len(lineHash) != sha1.Size*2 {
+			return 0, fmt.Errorf("invalid line in breach database at offset %d: expected a SHA-1 hash", start)
+		}
+		switch strings.Compare(hash, strings.ToUpper(lineHash)) {
+		case 0:
+			return parseCount(count)
+		case -1:
+			hi = mid
+		default:
+			lo = start + 1
+		}
+	}
+	return 0, nil
+}
+
+// lineAt returns the first line starting at or after offset and its start, without
+// the line ending. At the end of the file, start is the file size.
+func (d *DB) lineAt(offset int64) (int64, string, error) {
+	start := int64(0)
+	if offset > 0 {
+		// Skip to the end of the line holding the byte before offset; if that byte
+		// is a newline, a line starts at offset itself
+		start = offset - 1
+	}
+	// Lines are short, so a small buffer keeps each probe to a single small read
+	reader := bufio.NewReaderSize(io.NewSectionReader(d.file, start, d.size-start), 128)
+	if offset > 0 {
+		skipped, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return d.size, "", nil
+		}
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to read breach database: %w", err)
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, "", fmt.Errorf("failed to read breach database: %w", err)
+	}
+	if line == "" {
+		return d.size, "", nil
+	}
+	return start, strings.TrimRight(line, "\r\n"), nil
+}
+
+// parseCount parses the count of a line, treating lines without one as a single occurrence
+func parseCount(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 1, nil
+	}
+	count, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid line in breach database: count %q", s)
+	}
+	return count, nil
+}
@@ -0,0 +1,100 @@
+package breach
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeDB writes a Pwned Passwords file listing passwords, each with a count of its index + 1
+func writeDB(t *testing.T, passwords []string, lineEnding string) string {
+	t.Helper()
+	var lines []string
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, lineEnding)+lineEnding), 0600); err != nil {
+		t.Fatalf("Failed to write breach database: %v", err)
+	}
+	return path
+}
+
+func TestCount(t *testing.T) {
+	var passwords []string
+	for i := 0; i < 500; i++ {
+		passwords = append(passwords, fmt.Sprintf("password%d", i))
+	}
+
+	for name, lineEnding := range map[string]string{"lf": "\n", "crlf": "\r\n"} {
+		t.Run(name, func(t *testing.T) {
+			db, err := Open(writeDB(t, passwords, lineEnding))
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer db.Close()
+
+			for i, password := range passwords {
+				count, err := db.Count(password)
+				if err != nil {
+					t.Fatalf("Count failed: %v", err)
+				}
+				if count != i+1 {
+					t.Fatalf("Expected %q to be counted %d times, got %d", password, i+1, count)
+				}
+			}
+			for _, password := range []string{"", "correct horse battery staple", "password500"} {
+				if count, err := db.Count(password); err != nil || count != 0 {
+					t.Errorf("Expected %q not to be found, got %d, %v", password, count, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCountHashBounds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	content := "0000000000000000000000000000000000000001:5\nFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:7"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write breach database: %v", err)
+	}
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	tests := map[string]int{
+		"0000000000000000000000000000000000000000": 0,
+		"0000000000000000000000000000000000000001": 5,
+		"8000000000000000000000000000000000000000": 0,
+		"ffffffffffffffffffffffffffffffffffffffff": 7,
+	}
+	for hash, want := range tests {
+		if count, err := db.CountHash(hash); err != nil || count != want {
+			t.Errorf("CountHash(%s) = %d, %v; expected %d", hash, count, err, want)
+		}
+	}
+}
+
+func TestCountEmptyDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatalf("Failed to write breach database: %v", err)
+	}
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+	if count, err := db.Count("password"); err != nil || count != 0 {
+		t.Errorf("Expected no match, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,140 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// BackendAuto selects a backend from the environment
+const BackendAuto = "auto"
+
+// Names lists the backends accepted by ForName
+var Names = []string{BackendAuto, "wl-copy", "xclip", "xsel", "pbcopy", "osc52"}
+
+// commandBackend runs external programs to copy and paste
+type commandBackend struct {
+	name  string
+	copy  []string
+	paste []string
+}
+
+func (c commandBackend) Name() string {
+	return c.name
+}
+
+func (c commandBackend) Copy(text string) error {
+	// xclip and wl-copy fork a child that serves the selection until it changes. The child inherits
+	// stderr, so it goes to a file: with a pipe, Run would wait for the child to exit.
+	stderr, err := os.CreateTemp("", "vault-cli-clipboard-*")
+	if err != nil {
+		return fmt.Errorf("%s failed: %v", c.name, err)
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cmd := exec.Command(c.copy[0], c.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		output, _ := os.ReadFile(stderr.Name())
+		return fmt.Errorf("%s failed: %v %s", c.name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (c commandBackend) Paste() (string, error) {
+	out, err := exec.Command(c.paste[0], c.paste[1:]...).Output()
+	if err != nil {
+		// wl-paste and xclip fail when the clipboard is empty
+		return "", nil
+	}
+	return string(out), nil
+}
+
+// available reports whether the backend's programs are installed
+func (c commandBackend) available() bool {
+	for _, program := range []string{c.copy[0], c.paste[0]} {
+		if _, err := exec.LookPath(program); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+var commandBackends = map[string]commandBackend{
+	"wl-copy": {name: "wl-copy", copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}},
+	"xclip":   {name: "xclip", copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+	"xsel":    {name: "xsel", copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}},
+	"pbcopy":  {name: "pbcopy", copy: []string{"pbcopy"}, paste: []string{"pbpaste"}},
+}
+
+// OSC52 writes the clipboard through the terminal with the OSC 52 escape sequence.
+// This also works over SSH, but the clipboard cannot be read back.
+type OSC52 struct {
+	Out io.Writer
+}
+
+func (o OSC52) Name() string {
+	return "osc52"
+}
+
+func (o OSC52) Copy(text string) error {
+	_, err := fmt.Fprintf(o.Out, "\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+func (o OSC52) Paste() (string, error) {
+	return "", ErrCannotRead
+}
+
+// ForName returns the named backend. BackendAuto picks one from the environment:
+// wl-copy under Wayland, xclip or xsel under X11, pbcopy on macOS, and otherwise
+// OSC 52 if tty is a terminal.
+func ForName(name string, tty io.Writer) (Backend, error) {
+	switch name {
+	case BackendAuto, "":
+		return detect(tty)
+	case "osc52":
+		if tty == nil {
+			return nil, ErrUnavailable
+		}
+		return OSC52{Out: tty}, nil
+	}
+
+	backend, ok := commandBackends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown clipboard backend %q: expected one of %v", name, Names)
+	}
+	if !backend.available() {
+		return nil, fmt.Errorf("clipboard backend %s is not installed", name)
+	}
+	return backend, nil
+}
+
+// detect picks the first usable backend for the current session
+func detect(tty io.Writer) (Backend, error) {
+	var candidates []string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append(candidates, "wl-copy")
+	}
+	if os.Getenv("DISPLAY") != "" {
+		candidates = append(candidates, "xclip", "xsel")
+	}
+	if runtime.GOOS == "darwin" {
+		candidates = append(candidates, "pbcopy")
+	}
+	for _, name := range candidates {
+		if backend := commandBackends[name]; backend.available() {
+			return backend, nil
+		}
+	}
+	if tty != nil {
+		return OSC52{Out: tty}, nil
+	}
+	return nil, ErrUnavailable
+}
@@ -0,0 +1,52 @@
+// Package clipboard copies secrets to the system clipboard and clears them again.
+//
+// Several backends are supported (wl-copy, xclip, xsel, pbcopy and the OSC 52
+// terminal escape sequence). Clearing only happens if the clipboard still holds
+// the copied value, so anything the user copied in the meantime is kept.
+package clipboard
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// ErrUnavailable is returned when no clipboard backend can be used
+var ErrUnavailable = errors.New("no clipboard available: install wl-clipboard, xclip or xsel, or use a terminal that supports OSC 52")
+
+// ErrCannotRead is returned by backends that can write the clipboard but not read it
+var ErrCannotRead = errors.New("clipboard contents cannot be read")
+
+// Backend reads and writes the clipboard
+type Backend interface {
+	// Name identifies the backend, e.g. "xclip"
+	Name() string
+	// Copy replaces the clipboard contents with text
+	Copy(text string) error
+	// Paste returns the clipboard contents, or ErrCannotRead
+	Paste() (string, error)
+}
+
+// Digest returns the fingerprint used to recognize a copied value without keeping it
+func Digest(text string) []byte {
+	sum := sha256.Sum256([]byte(text))
+	return sum[:]
+}
+
+// ClearIfUnchanged clears the clipboard if it still holds the value with the given digest.
+// Backends that cannot read the clipboard are cleared unconditionally. It reports whether
+// the clipboard was cleared.
+func ClearIfUnchanged(b Backend, digest []byte) (bool, error) {
+	current, err := b.Paste()
+	if err != nil && !errors.Is(err, ErrCannotRead) {
+		return false, fmt.Errorf("failed to read clipboard: %w", err)
+	}
+	if err == nil && subtle.ConstantTimeCompare(Digest(current), digest) != 1 {
+		return false, nil
+	}
+	if err := b.Copy(""); err != nil {
+		return false, fmt.Errorf("failed to clear clipboard: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClearIfUnchanged(t *testing.T) {
+	fake := &Fake{}
+	if err := fake.Copy("s3cret"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	cleared, err := ClearIfUnchanged(fake, Digest("s3cret"))
+	if err != nil {
+		t.Fatalf("ClearIfUnchanged failed: %v", err)
+	}
+	if !cleared {
+		t.Error("Expected the clipboard to be cleared")
+	}
+	if contents, _ := fake.Paste(); contents != "" {
+		t.Errorf("Expected an empty clipboard, got %q", contents)
+	}
+}
+
+func TestClearIfUnchangedKeepsNewContents(t *testing.T) {
+	fake := &Fake{}
+	fake.Copy("s3cret")
+	// The user copies something else before the timeout
+	fake.Copy("shopping list")
+
+	cleared, err := ClearIfUnchanged(fake, Digest("s3cret"))
+	if err != nil {
+		t.Fatalf("ClearIfUnchanged failed: %v", err)
+	}
+	if cleared {
+		t.Error("Expected the clipboard to be left alone")
+	}
+	if contents, _ := fake.Paste(); contents != "shopping list" {
+		t.Errorf("Expected the user's clipboard to be kept, got %q", contents)
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	var out bytes.Buffer
+	backend := OSC52{Out: &out}
+
+	if err := backend.Copy("s3cret"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	expected := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("s3cret")) + "\x07"
+	if out.String() != expected {
+		t.Errorf("Copy wrote %q, want %q", out.String(), expected)
+	}
+
+	// OSC 52 cannot be read back, so clearing is unconditional
+	out.Reset()
+	cleared, err := ClearIfUnchanged(backend, Digest("s3cret"))
+	if err != nil || !cleared {
+		t.Fatalf("ClearIfUnchanged() = %v, %v; want true, nil", cleared, err)
+	}
+	if out.String() != "\x1b]52;c;\x07" {
+		t.Errorf("Clear wrote %q", out.String())
+	}
+}
+
+func TestForName(t *testing.T) {
+	if _, err := ForName("clippy", nil); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+	if _, err := ForName("osc52", nil); err == nil {
+		t.Error("Expected an error for OSC 52 without a terminal")
+	}
+
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("DISPLAY", "")
+	var tty bytes.Buffer
+	backend, err := ForName(BackendAuto, &tty)
+	if err != nil {
+		t.Fatalf("ForName failed: %v", err)
+	}
+	if backend.Name() != "osc52" && backend.Name() != "pbcopy" {
+		t.Errorf("Expected OSC 52 without a display server, got %s", backend.Name())
+	}
+}
+
+// fakeCopyProgram writes a shell script standing in for a clipboard program
+func fakeCopyProgram(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "fake-copy")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommandBackendForkingCopy(t *testing.T) {
+	// Like xclip, the program forks a child that outlives it and keeps stderr open
+	program := fakeCopyProgram(t, "cat >/dev/null\nsleep 10 &\nexit 0\n")
+	backend := commandBackend{name: "fake", copy: []string{program}}
+
+	start := time.Now()
+	if err := backend.Copy("s3cret"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Copy waited %v for the forked child", elapsed)
+	}
+}
+
+func TestCommandBackendCopyError(t *testing.T) {
+	program := fakeCopyProgram(t, "echo 'Error: cannot open display' >&2\nexit 1\n")
+	backend := commandBackend{name: "fake", copy: []string{program}}
+
+	err := backend.Copy("s3cret")
+	if err == nil || !strings.Contains(err.Error(), "cannot open display") {
+		t.Errorf("Expected the program's error output, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package clipboard
+
+import "sync"
+
+// Fake is an in-memory clipboard for tests
+type Fake struct {
+	mu       sync.Mutex
+	contents string
+	copies   int
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Copy(text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.contents = text
+	f.copies++
+	return nil
+}
+
+func (f *Fake) Paste() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.contents, nil
+}
+
+// Copies returns how many times Copy was called
+func (f *Fake) Copies() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.copies
+}
@@ -0,0 +1,10 @@
+//go:build unix
+
+package clipboard
+
+import "syscall"
+
+// detachedProcAttr starts the clearer in its own session so it survives the terminal closing
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
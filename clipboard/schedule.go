@@ -0,0 +1,52 @@
+package clipboard
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ScheduleClear launches `vault-cli clipboard-clear` as a detached background process that
+// clears the clipboard after delay if it still holds text. Only the digest of text is
+// handed over, on a pipe. It is a variable so tests can replace it.
+var ScheduleClear = func(backend Backend, text string, delay time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, "clipboard-clear",
+		"--backend", backend.Name(),
+		"--after", delay.String(),
+	)
+	cmd.SysProcAttr = detachedProcAttr()
+	if _, ok := backend.(OSC52); ok {
+		// The escape sequence has to reach the same terminal
+		cmd.Stderr = os.Stderr
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(stdin, hex.EncodeToString(Digest(text))+"\n"); err != nil {
+		cmd.Process.Kill()
+		return fmt.Errorf("failed to start clipboard clearer: %v", err)
+	}
+	stdin.Close()
+
+	// The clearer outlives this process
+	return cmd.Process.Release()
+}
+
+// ClearAfter waits for delay and then clears the clipboard if it still holds the value with digest
+func ClearAfter(b Backend, digest []byte, delay time.Duration) (bool, error) {
+	time.Sleep(delay)
+	return ClearIfUnchanged(b, digest)
+}
@@ -3,12 +3,13 @@ package cmd
 import (
 	"bufio"
 	db "vault-cli/database"
+	"vault-cli/kinds"
+	"vault-cli/vault"
 	"fmt"
 	"os"
 	"strings"
 	"syscall"
 
-	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -16,111 +17,126 @@ import (
 var addCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new sensitive data entry to the vault",
-	Long:  `Add a new sensitive data entry to the vault with the specified service, identifier, and value.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Add a new sensitive data entry to the vault with the specified service, identifier, and value.
+The kind of entry (login, api_key, ssh_key, database, credit_card, secure_note, totp or a custom
+kind from the config file) decides which fields are prompted for and how they are checked; run
+"kinds" to list them. Notes, URLs, custom fields and tags can be stored with it using the flags below.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
 		service, _ := cmd.Flags().GetString("service")
-
-		// Check if the vault is locked
-		isLocked, err := db.GetVaultState()
+		clip, _ := cmd.Flags().GetBool("clip")
+		kindName, _ := cmd.Flags().GetString("kind")
+		details, tags, err := detailsFromFlags(cmd, db.EntryDetails{}, nil)
 		if err != nil {
-			fmt.Println("Error retrieving vault state:", err)
-			return
+			return err
 		}
-
-		if isLocked {
-			fmt.Println("Error: Vault is locked. Please unlock the vault using `unlock` before adding new entries.")
-			return
+		var kind kinds.Kind
+		if kindName != "" {
+			if kind, err = kindRegistry.Lookup(kindName); err != nil {
+				return usageErrorf("%v", err)
+			}
 		}
 
-		// Prompt for identifier type
-		idType := promptIdentifierType()
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
 
-		// Prompt for identifier based on selected identifier type
-		identifier := promptForIdentifier(idType)
+		// Prompt for the kind of entry unless it was given
+		if kindName == "" {
+			if kind, err = promptKind(); err != nil {
+				return err
+			}
+		}
 
-		value := promptPassword("Enter data value for the service (or press Enter to auto-generate): ")
+		// Prompt for the identifier, value and fields described by the kind's schema
+		identifier := promptForIdentifier(kind)
+		value, err := promptValue(kind)
+		if err != nil {
+			return err
+		}
+		if details.Fields, err = promptSchemaFields(kind, details.Fields); err != nil {
+			return err
+		}
+		if err := validateEntry(kind, identifier, value, details.Fields); err != nil {
+			return err
+		}
+		if kind.Name == kinds.TOTP {
+			if value, details, err = totpSeed(service, identifier, value, details); err != nil {
+				return err
+			}
+		}
 
-		// Automatically generate a random password if not provided
+		// Automatically generate a random password following the service's policy if not provided
 		if value == "" {
 			// Auto-generate a password if none provided
 			fmt.Println("No password entered. Generating a random password...")
-			value, err = generateRandomPassword(12) // Adjust the length as needed
+			value, err = generateForService(store, service)
 			if err != nil {
-				fmt.Println("Error generating password:", err)
-				return
+				return fmt.Errorf("error generating password: %w", err)
+			}
+			if !clip {
+				fmt.Printf("Generated password for %s: %s\n", service, value)
 			}
-			fmt.Printf("Generated password for %s: %s\n", service, value)
+		} else if kind.Generate {
+			// Only values that could have been generated are passwords worth checking
+			if err := checkServicePolicy(store, service, value); err != nil {
+				return err
+			}
+			warnAboutValue(value)
 		}
 
 		// Add the sensitive data to the vault
-		err = db.AddSensitiveData(service, identifier, value, idType) // Assuming idType is username
+		err = db.AddSensitiveDataWithDetails(store, keyring, service, identifier, value, kind.Name, details, tags)
 		if err != nil {
-			fmt.Println("Error adding Sensitive data entry:", err)
-			return
+			return fmt.Errorf("error adding sensitive data entry: %w", err)
 		}
 
 		fmt.Println("Sensitive data entry added successfully.")
+		if clip {
+			return copyToClipboard(cmd, value, fmt.Sprintf("the value of %s/%s", service, identifier))
+		}
+		return nil
 	},
 }
 
 func init() {
+	addCmd.Annotations = requiresDatabase
+
 	addCmd.Flags().StringP("service", "s", "", "Service name (required)")
+	addCmd.Flags().StringP("kind", "k", "", "Kind of entry, such as login or ssh_key (prompted for if not given)")
+	addCmd.Flags().BoolP("clip", "c", false, "Copy the value to the clipboard instead of printing a generated password")
+	addDetailFlags(addCmd)
 
 	addCmd.MarkFlagRequired("service")
 }
 
-// promptIdentifierType prompts the user to select an identifier type using arrow keys
-func promptIdentifierType() string {
-	// Available identifier types
-	idTypes := []string{"username", "email", "api_key", "secret_key"}
-
-	// Prompt UI for selecting the identifier type
-	prompt := promptui.Select{
-		Label: "Select an identifier type",
-		Items: idTypes,
-	}
-
-	// Run the prompt and get the selected index
-	_, selectedType, err := prompt.Run()
-	if err != nil {
-		fmt.Printf("Prompt failed %v\n", err)
-		return ""
-	}
-
-	return selectedType
-}
-
-// promptForIdentifier prompts the user for an identifier value based on the identifier type
-func promptForIdentifier(idType string) string {
-	var prompt string
-	switch idType {
-	case "username":
-		prompt = "Enter the username: "
-	case "email":
-		prompt = "Enter the email address: "
-	case "api_key":
-		prompt = "Enter the API key: "
-	case "secret_key":
-		prompt = "Enter the secret key: "
-	default:
-		prompt = "Enter the identifier: "
-	}
-
-	fmt.Print(prompt)
+// promptForIdentifier prompts the user for an identifier using the label from the kind's schema
+func promptForIdentifier(kind kinds.Kind) string {
+	fmt.Printf("%s: ", kind.Identifier.Label)
 	reader := bufio.NewReader(os.Stdin)
 	identifier, _ := reader.ReadString('\n')
 
 	return strings.TrimSpace(identifier)
 }
 
+// promptValue prompts the user for the value of a new entry, hiding the input unless it spans several lines.
+// An empty value is returned when the kind generates values and none is entered.
+func promptValue(kind kinds.Kind) (string, error) {
+	prompt := kind.Value.Label
+	if kind.Generate {
+		prompt += " (or press Enter to auto-generate)"
+	}
+	return promptField(kind.Value, prompt, true)
+}
+
 // promptPassword prompts the user for a password and hides input
-func promptPassword(prompt string) string {
+func promptPassword(prompt string) (string, error) {
 	fmt.Print(prompt)
 	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
 	fmt.Println() // Move to the next line after password input
 	if err != nil {
-		fmt.Println("Error reading password:", err)
-		os.Exit(1)
+		return "", fmt.Errorf("error reading password: %w", err)
 	}
-	return strings.TrimSpace(string(bytePassword))
+	return strings.TrimSpace(string(bytePassword)), nil
 }
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"vault-cli/agent"
+
+	"github.com/spf13/cobra"
+)
+
+// agentCmd runs the unlock agent. It is started by `unlock` and is not meant to be run by hand.
+var agentCmd = &cobra.Command{
+	Use:    "agent",
+	Short:  "Run the unlock agent (started by `unlock`)",
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, _ := cmd.Flags().GetString("socket")
+		idleTimeout, _ := cmd.Flags().GetDuration("idle-timeout")
+		maxLifetime, _ := cmd.Flags().GetDuration("max-lifetime")
+
+		// The key is handed over on stdin by `unlock`
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read key: %v", err)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(line))
+		if err != nil {
+			return fmt.Errorf("failed to decode key: %v", err)
+		}
+
+		server, err := agent.Listen(socketPath, key, agent.Options{IdleTimeout: idleTimeout, MaxLifetime: maxLifetime})
+		if err != nil {
+			return err
+		}
+
+		// Wipe the key if the agent is terminated
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-signals
+			server.Close()
+		}()
+
+		return server.Serve()
+	},
+}
+
+func init() {
+	agentCmd.Flags().String("socket", "", "Path of the agent socket (required)")
+	agentCmd.Flags().Duration("idle-timeout", agent.DefaultIdleTimeout, "Lock after this long without accessing secrets (0 disables)")
+	agentCmd.Flags().Duration("max-lifetime", agent.DefaultMaxLifetime, "Lock this long after unlocking (0 disables)")
+	agentCmd.MarkFlagRequired("socket")
+}
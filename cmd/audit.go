@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"vault-cli/breach"
+	db "vault-cli/database"
+	"vault-cli/strength"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// auditEntry is the audit result for one entry. It never contains the value itself.
+type auditEntry struct {
+	Service         string    `json:"service"`
+	Identifier      string    `json:"identifier"`
+	Score           int       `json:"score"`
+	Entropy         float64   `json:"entropy_bits"`
+	Warning         string    `json:"warning,omitempty"`
+	Weak            bool      `json:"weak"`
+	ReusedWith      []string  `json:"reused_with"`
+	ValueUpdatedAt  time.Time `json:"value_updated_at"`
+	AgeDays         int       `json:"age_days"`
+	Old             bool      `json:"old"`
+	PolicyViolation string    `json:"policy_violation,omitempty"`
+	Breaches        int       `json:"breaches"` // Occurrences in the breach database, if one was checked
+}
+
+// healthy reports whether the audit found no problems with the entry
+func (e auditEntry) healthy() bool {
+	return !e.Weak && len(e.ReusedWith) == 0 && !e.Old && e.PolicyViolation == "" && e.Breaches == 0
+}
+
+// auditSummary counts the problems found in the vault
+type auditSummary struct {
+	Entries          int  `json:"entries"`
+	Healthy          int  `json:"healthy"`
+	Weak             int  `json:"weak"`
+	Reused           int  `json:"reused"`
+	Old              int  `json:"old"`
+	PolicyViolations int  `json:"policy_violations"`
+	Breached         int  `json:"breached"`
+	BreachChecked    bool `json:"breach_checked"`
+	Score            int  `json:"score"` // Percentage of healthy entries
+}
+
+// auditOptions sets the thresholds used by auditEntries
+type auditOptions struct {
+	MinScore int           // Values scoring below this are weak
+	MaxAge   time.Duration // Values not changed for longer are old, unless 0
+	Breaches *breach.DB    // Breach database to check values against, if any
+	Now      time.Time
+}
+
+type auditReport struct {
+	Summary auditSummary `json:"summary"`
+	Entries []auditEntry `json:"entries"`
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Report weak, reused, old and non-compliant values",
+	Long: `Decrypt every entry and report values that are weak, reused across entries, have not been
+changed for longer than the audit.max_age setting, or violate their service's password policy.
+With --breach-db or the audit.breach_db setting, values are also looked up in a local copy of the
+Pwned Passwords list (the SHA-1 version ordered by hash). Nothing is sent over the network.
+
+Strength is estimated by looking for patterns an attacker would try first, such as common passwords,
+dictionary words, keyboard rows, sequences, repeats and years. Values scoring below the
+audit.min_score setting (0-4) are reported as weak. The report never includes the values themselves.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		opts := auditOptions{Now: time.Now()}
+		var err error
+		if opts.MinScore, err = cfg.Int("audit.min_score"); err != nil {
+			return err
+		}
+		if opts.MaxAge, err = cfg.Duration("audit.max_age"); err != nil {
+			return err
+		}
+		breachPath, err := stringSetting(cmd, "breach-db", "audit.breach_db")
+		if err != nil {
+			return err
+		}
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+
+		entries, err := db.GetAllSensitiveData(store, keyring, "")
+		if err != nil {
+			return fmt.Errorf("error fetching sensitive data: %w", err)
+		}
+		// Only passwords are audited; notes, keys and card numbers are not meant to be memorable or generated
+		entries = slices.DeleteFunc(entries, func(entry db.SensitiveData) bool { return !kindRegistry.Resolve(entry.Kind).Generate })
+
+		if breachPath != "" {
+			if opts.Breaches, err = breach.Open(breachPath); err != nil {
+				return err
+			}
+			defer opts.Breaches.Close()
+		}
+		report, err := auditEntries(store, entries, opts)
+		if err != nil {
+			return err
+		}
+		return render(cmd, report, func(w io.Writer, color bool) error {
+			printAuditReport(w, report)
+			return nil
+		})
+	},
+}
+
+func init() {
+	auditCmd.Annotations = requiresDatabase
+
+	auditCmd.Flags().String("breach-db", "", "Pwned Passwords file to check values against (default from the audit.breach_db setting)")
+}
+
+// auditEntries checks every entry and summarizes the results
+func auditEntries(store db.Store, entries []db.SensitiveData, opts auditOptions) (auditReport, error) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Service != entries[j].Service {
+			return entries[i].Service < entries[j].Service
+		}
+		return entries[i].Identifier < entries[j].Identifier
+	})
+
+	// Group entries sharing a value
+	byValue := make(map[string][]string)
+	for _, entry := range entries {
+		byValue[entry.Value] = append(byValue[entry.Value], entry.Service+"/"+entry.Identifier)
+	}
+
+	report := auditReport{Entries: make([]auditEntry, 0, len(entries))}
+	report.Summary.BreachChecked = opts.Breaches != nil
+	for _, entry := range entries {
+		estimate := strength.Estimate(entry.Value)
+		result := auditEntry{
+			Service:        entry.Service,
+			Identifier:     entry.Identifier,
+			Score:          estimate.Score,
+			Entropy:        math.Round(estimate.Entropy*10) / 10,
+			Warning:        estimate.Warning,
+			Weak:           estimate.Score < opts.MinScore,
+			ReusedWith:     []string{},
+			ValueUpdatedAt: entry.ValueUpdatedAt.UTC().Truncate(time.Second),
+			AgeDays:        int(opts.Now.Sub(entry.ValueUpdatedAt).Hours() / 24),
+			Old:            opts.MaxAge > 0 && opts.Now.Sub(entry.ValueUpdatedAt) > opts.MaxAge,
+		}
+
+		self := entry.Service + "/" + entry.Identifier
+		for _, other := range byValue[entry.Value] {
+			if other != self {
+				result.ReusedWith = append(result.ReusedWith, other)
+			}
+		}
+
+		policy, err := servicePolicy(store, entry.Service)
+		if err != nil {
+			return auditReport{}, err
+		}
+		if err := policy.Check(entry.Value); err != nil {
+			result.PolicyViolation = err.Error()
+		}
+
+		if opts.Breaches != nil {
+			if result.Breaches, err = opts.Breaches.Count(entry.Value); err != nil {
+				return auditReport{}, err
+			}
+		}
+
+		report.Entries = append(report.Entries, result)
+		report.Summary.add(result)
+	}
+
+	report.Summary.Score = 100
+	if report.Summary.Entries > 0 {
+		report.Summary.Score = report.Summary.Healthy * 100 / report.Summary.Entries
+	}
+	return report, nil
+}
+
+// add counts the problems found with entry
+func (s *auditSummary) add(entry auditEntry) {
+	s.Entries++
+	if entry.healthy() {
+		s.Healthy++
+	}
+	if entry.Weak {
+		s.Weak++
+	}
+	if len(entry.ReusedWith) > 0 {
+		s.Reused++
+	}
+	if entry.Old {
+		s.Old++
+	}
+	if entry.PolicyViolation != "" {
+		s.PolicyViolations++
+	}
+	if entry.Breaches > 0 {
+		s.Breached++
+	}
+}
+
+// printAuditReport prints the summary followed by the problems found with each entry
+func printAuditReport(w io.Writer, report auditReport) {
+	summary := report.Summary
+	fmt.Fprintf(w, "Audited %d entries: score %d/100\n", summary.Entries, summary.Score)
+	fmt.Fprintf(w, "  Weak values:       %d\n", summary.Weak)
+	fmt.Fprintf(w, "  Reused values:     %d\n", summary.Reused)
+	fmt.Fprintf(w, "  Old values:        %d\n", summary.Old)
+	fmt.Fprintf(w, "  Policy violations: %d\n", summary.PolicyViolations)
+	if summary.BreachChecked {
+		fmt.Fprintf(w, "  Breached values:   %d\n", summary.Breached)
+	} else {
+		fmt.Fprintln(w, "  Breached values:   not checked (use --breach-db)")
+	}
+
+	if summary.Healthy == summary.Entries {
+		fmt.Fprintln(w, "\nNo problems found.")
+		return
+	}
+	for _, entry := range report.Entries {
+		if entry.healthy() {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s/%s (%s, %.0f bits)\n", entry.Service, entry.Identifier, strength.ScoreName(entry.Score), entry.Entropy)
+		if entry.Weak {
+			fmt.Fprintf(w, "  - weak value: %s\n", describeString(entry.Warning, "easy to guess"))
+		}
+		if len(entry.ReusedWith) > 0 {
+			fmt.Fprintf(w, "  - same value as %s\n", strings.Join(entry.ReusedWith, ", "))
+		}
+		if entry.Old {
+			fmt.Fprintf(w, "  - not changed for %d days\n", entry.AgeDays)
+		}
+		if entry.PolicyViolation != "" {
+			fmt.Fprintf(w, "  - %s\n", entry.PolicyViolation)
+		}
+		if entry.Breaches > 0 {
+			fmt.Fprintf(w, "  - found %d times in known data breaches\n", entry.Breaches)
+		}
+	}
+}
+
+// warnAboutValue prints a warning if value scores below the audit.min_score setting,
+// or appears in the breach database configured with the audit.breach_db setting
+func warnAboutValue(value string) {
+	if minScore, err := cfg.Int("audit.min_score"); err == nil {
+		estimate := strength.Estimate(value)
+		if estimate.Score < minScore {
+			fmt.Fprintf(os.Stderr, "Warning: this value is %s (score %d/4).", strength.ScoreName(estimate.Score), estimate.Score)
+			if estimate.Warning != "" {
+				fmt.Fprintf(os.Stderr, " %s.", estimate.Warning)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	path, err := cfg.String("audit.breach_db")
+	if err != nil || path == "" {
+		return
+	}
+	breaches, err := breach.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check the value against known breaches: %v\n", err)
+		return
+	}
+	defer breaches.Close()
+	count, err := breaches.Count(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check the value against known breaches: %v\n", err)
+		return
+	}
+	if count > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: this value appears %d times in known data breaches. Choose another one.\n", count)
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vault-cli/breach"
+	db "vault-cli/database"
+)
+
+func TestAuditEntries(t *testing.T) {
+	store := db.NewMemoryStore("audit.db")
+	if err := store.SetPolicy(&db.PasswordPolicy{Service: "bank", MaxLength: 16}); err != nil {
+		t.Fatalf("SetPolicy failed: %v", err)
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []db.SensitiveData{
+		{Service: "mail", Identifier: "me", Value: "password"},
+		{Service: "forum", Identifier: "me", Value: "Xk#9vQ2mL7pR4wZe"},
+		{Service: "shop", Identifier: "me", Value: "Xk#9vQ2mL7pR4wZe"},
+		{Service: "bank", Identifier: "me", Value: "Tq8!fW3nZc6yHs1uJd"},
+		{Service: "legacy", Identifier: "me", Value: "Hb5$kN8pXq2wRt7m"},
+	}
+	for i := range entries {
+		entries[i].ValueUpdatedAt = now.Add(-24 * time.Hour)
+	}
+	entries[4].ValueUpdatedAt = now.AddDate(-2, 0, 0)
+
+	// SHA-1 of "password"
+	breachPath := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(breachPath, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n"), 0600); err != nil {
+		t.Fatalf("Failed to write breach database: %v", err)
+	}
+	breaches, err := breach.Open(breachPath)
+	if err != nil {
+		t.Fatalf("Failed to open breach database: %v", err)
+	}
+	defer breaches.Close()
+
+	report, err := auditEntries(store, entries, auditOptions{MinScore: 3, MaxAge: 365 * 24 * time.Hour, Breaches: breaches, Now: now})
+	if err != nil {
+		t.Fatalf("auditEntries failed: %v", err)
+	}
+
+	results := make(map[string]auditEntry)
+	for _, entry := range report.Entries {
+		results[entry.Service] = entry
+	}
+	if !results["mail"].Weak || results["mail"].Breaches != 9545824 {
+		t.Errorf("Expected the common password to be weak and breached, got %+v", results["mail"])
+	}
+	if reused := results["forum"].ReusedWith; len(reused) != 1 || reused[0] != "shop/me" {
+		t.Errorf("Expected forum to share its value with shop, got %v", reused)
+	}
+	if results["bank"].PolicyViolation == "" {
+		t.Error("Expected the bank value to violate its policy")
+	}
+	if !results["legacy"].Old || results["legacy"].AgeDays < 700 {
+		t.Errorf("Expected the legacy value to be old, got %+v", results["legacy"])
+	}
+
+	want := auditSummary{Entries: 5, Healthy: 0, Weak: 1, Reused: 2, Old: 1, PolicyViolations: 1, Breached: 1, BreachChecked: true, Score: 0}
+	if report.Summary != want {
+		t.Errorf("Expected summary %+v, got %+v", want, report.Summary)
+	}
+}
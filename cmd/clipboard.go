@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"vault-cli/clipboard"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// clipboardClearCmd clears the clipboard after a delay. It is started by --clip and is not meant to be run by hand.
+var clipboardClearCmd = &cobra.Command{
+	Use:    "clipboard-clear",
+	Short:  "Clear the clipboard after a delay (started by --clip)",
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backendName, _ := cmd.Flags().GetString("backend")
+		delay, _ := cmd.Flags().GetDuration("after")
+
+		// The digest of the copied value is handed over on stdin
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read digest: %v", err)
+		}
+		digest, err := hex.DecodeString(strings.TrimSpace(line))
+		if err != nil {
+			return fmt.Errorf("failed to decode digest: %v", err)
+		}
+
+		backend, err := clipboard.ForName(backendName, clipboardTTY())
+		if err != nil {
+			return err
+		}
+		_, err = clipboard.ClearAfter(backend, digest, delay)
+		return err
+	},
+}
+
+// clipboardTTY returns the terminal used for OSC 52, or nil if stderr is not a terminal
+func clipboardTTY() io.Writer {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// copyToClipboard copies value with the configured backend and schedules clearing it after
+// clipboard.timeout. The value itself is never printed; description names it in the message.
+func copyToClipboard(cmd *cobra.Command, value, description string) error {
+	backendName, err := cfg.String("clipboard.backend")
+	if err != nil {
+		return err
+	}
+	timeout, err := cfg.Duration("clipboard.timeout")
+	if err != nil {
+		return err
+	}
+
+	backend, err := clipboard.ForName(backendName, clipboardTTY())
+	if err != nil {
+		return err
+	}
+	if err := backend.Copy(value); err != nil {
+		return fmt.Errorf("error copying to clipboard: %w", err)
+	}
+
+	if timeout <= 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Copied %s to the clipboard.\n", description)
+		return nil
+	}
+	if err := clipboard.ScheduleClear(backend, value, timeout); err != nil {
+		return fmt.Errorf("copied %s to the clipboard, but could not schedule clearing it: %w", description, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Copied %s to the clipboard. It will be cleared in %s.\n", description, timeout)
+	return nil
+}
+
+func init() {
+	clipboardClearCmd.Flags().String("backend", clipboard.BackendAuto, "Clipboard backend to clear")
+	clipboardClearCmd.Flags().Duration("after", 0, "Delay before clearing")
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"vault-cli/config"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd groups the commands that manage the config file
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage vault-cli settings",
+	Long: `Manage vault-cli settings stored in the config file.
+
+Settings are resolved in order of precedence: command-line flags, environment
+variables, the config file, and built-in defaults.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, _, err := cfg.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Store a setting in the config file",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := cfg.Set(args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("%s set to %q in %s\n", args[0], args[1], cfg.Path())
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all settings with their values and sources",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fmt.Printf("%-22s | %-8s | %s\n", "Key", "Source", "Value")
+		for _, key := range config.Keys() {
+			value, source, err := cfg.Get(key)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-22s | %-8s | %s\n", key, source, value)
+		}
+		return nil
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(cfg.Path())
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configPathCmd)
+}
+
+// isConfigCommand reports whether cmd is config or one of its subcommands
+func isConfigCommand(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd == configCmd {
+			return true
+		}
+	}
+	return false
+}
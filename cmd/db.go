@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+
+	db "vault-cli/database"
+
+	"github.com/spf13/cobra"
+)
+
+// dbCmd groups the commands that maintain the vault database
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Maintain the vault database",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations to the vault",
+	Long: `Apply pending schema migrations to the vault. Migrations also run automatically
+when a command opens the vault; the vault file is backed up before any migration runs.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showStatus, _ := cmd.Flags().GetBool("status")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if showStatus || dryRun {
+			status, err := db.SchemaInfo(vaultPath)
+			if err != nil {
+				return err
+			}
+			if showStatus {
+				printSchemaStatus(status)
+				return nil
+			}
+			if len(status.Pending) == 0 {
+				fmt.Println("The vault schema is up to date.")
+				return nil
+			}
+			fmt.Printf("Would migrate %s from schema version %d to %d:\n", vaultPath, status.Current, status.Latest)
+			for _, migration := range status.Pending {
+				fmt.Printf("  %04d_%s\n", migration.Version, migration.Name)
+			}
+			if status.Existing {
+				fmt.Println("The vault would be backed up first.")
+			}
+			return nil
+		}
+
+		before, err := db.SchemaInfo(vaultPath)
+		if err != nil {
+			return err
+		}
+		status, backupPath, err := db.MigrateVault(vaultPath)
+		if err != nil {
+			if backupPath != "" {
+				return fmt.Errorf("%w (a backup was saved to %s)", err, backupPath)
+			}
+			return err
+		}
+		if len(before.Pending) == 0 {
+			fmt.Println("The vault schema is up to date.")
+			return nil
+		}
+		if backupPath != "" {
+			fmt.Printf("Backed up the vault to %s\n", backupPath)
+		}
+		fmt.Printf("Migrated the vault from schema version %d to %d.\n", before.Current, status.Current)
+		return nil
+	},
+}
+
+// printSchemaStatus lists every known migration and whether it has been applied
+func printSchemaStatus(status db.SchemaStatus) {
+	fmt.Printf("Vault:          %s\n", vaultPath)
+	fmt.Printf("Schema version: %d (latest %d)\n", status.Current, status.Latest)
+	if status.Legacy {
+		fmt.Println("The vault was created before schema versioning and will be upgraded when it is next opened.")
+	}
+
+	for _, migration := range status.Applied {
+		fmt.Printf("  applied  %04d_%s (%s)\n", migration.Version, migration.Name, migration.AppliedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	for _, migration := range status.Pending {
+		fmt.Printf("  pending  %04d_%s\n", migration.Version, migration.Name)
+	}
+}
+
+func init() {
+	dbMigrateCmd.Flags().Bool("status", false, "Show the schema version and which migrations have been applied")
+	dbMigrateCmd.Flags().Bool("dry-run", false, "List the migrations that would be applied without changing the vault")
+	dbMigrateCmd.MarkFlagsMutuallyExclusive("status", "dry-run")
+
+	dbCmd.AddCommand(dbMigrateCmd)
+}
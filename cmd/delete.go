@@ -2,6 +2,7 @@ package cmd
 
 import (
 	db "vault-cli/database"
+	"vault-cli/vault"
 	"fmt"
 	"github.com/spf13/cobra"
 )
@@ -9,43 +10,58 @@ import (
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
-	Short: "Delete a stored entry from the vault",
-	Long:  `Delete a stored entry from the vault using the specified service and identifier.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Short: "Move a stored entry to the trash",
+	Long: `Move a stored entry to the trash using the specified service and identifier. Entries in the trash
+can be brought back with "trash restore" until they are purged after the trash.retention setting.
+Use --permanent to delete the entry and its history immediately instead.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
 		service, _ := cmd.Flags().GetString("service")
 		identifier, _ := cmd.Flags().GetString("identifier")
 
-		// Check if the vault is locked
-		isLocked, err := db.GetVaultState()
-		if err != nil {
-			fmt.Println("Error getting vault state:", err)
-			return
-		}
-		if isLocked {
-			fmt.Println("Vault is locked. Please unlock it first.")
-			return
+		if _, err := vault.Keyring(store); err != nil {
+			return err
 		}
 
 		if service == "" || identifier == "" {
-			fmt.Println("Error: Both service and identifier are required.")
-			return
+			return usageErrorf("both service and identifier are required")
 		}
 
-		// Attempt to delete the entry
-		err = db.DeleteSensitiveData(service, identifier)
-		if err != nil {
-			fmt.Println("Error deleting entry:", err)
-			return
+		permanent, _ := cmd.Flags().GetBool("permanent")
+		if !permanent {
+			// Attempt to move the entry to the trash
+			if err := db.DeleteSensitiveData(store, service, identifier); err != nil {
+				return fmt.Errorf("error deleting entry: %w", err)
+			}
+			fmt.Println("Entry moved to the trash. Use 'trash restore' to bring it back.")
+			return nil
 		}
 
-		fmt.Println("Entry deleted successfully.")
+		// Make sure the entry exists before asking for confirmation
+		if _, err := store.Get(service, identifier); err != nil {
+			return fmt.Errorf("error deleting entry: %w", err)
+		}
+		yes, _ := cmd.Flags().GetBool("yes")
+		if !yes && !confirm(fmt.Sprintf("Permanently delete %s/%s and its history? This cannot be undone.", service, identifier)) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+		if err := db.PurgeSensitiveData(store, service, identifier); err != nil {
+			return fmt.Errorf("error deleting entry: %w", err)
+		}
+		fmt.Println("Entry permanently deleted.")
+		return nil
 	},
 }
 
 func init() {
+	deleteCmd.Annotations = requiresDatabase
+
 	// Add flags for service and identifier
 	deleteCmd.Flags().StringP("service", "s", "", "Service name (required)")
 	deleteCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	deleteCmd.Flags().Bool("permanent", false, "Delete the entry and its history instead of moving it to the trash")
+	deleteCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation of a permanent delete")
 	deleteCmd.MarkFlagRequired("service")
 	deleteCmd.MarkFlagRequired("identifier")
 
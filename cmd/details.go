@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	db "vault-cli/database"
+
+	"github.com/spf13/cobra"
+)
+
+// maskedValue is shown instead of the value of a secret field unless it is revealed
+const maskedValue = "********"
+
+// addDetailFlags registers the flags setting an entry's notes, URLs, custom fields, tags and one-time password seed
+func addDetailFlags(cmd *cobra.Command) {
+	cmd.Flags().String("notes", "", "Notes stored encrypted with the entry")
+	cmd.Flags().StringArray("url", nil, "URL of the service (repeatable)")
+	cmd.Flags().StringArray("field", nil, "Custom field as name=value (repeatable)")
+	cmd.Flags().StringArray("secret-field", nil, "Custom field as name=value, hidden unless revealed (repeatable)")
+	cmd.Flags().StringSlice("tag", nil, "Tag used to filter entries (repeatable or comma-separated)")
+	cmd.Flags().String("otp", "", `One-time password seed as an otpauth:// URI or base32 secret ("-" to prompt, "" to remove)`)
+}
+
+// detailsFromFlags applies the detail flags given on the command line to the current details and tags.
+// Each kind of detail given replaces the current ones; --field and --secret-field together replace all fields.
+func detailsFromFlags(cmd *cobra.Command, details db.EntryDetails, tags []string) (db.EntryDetails, []string, error) {
+	flags := cmd.Flags()
+	if flags.Changed("notes") {
+		details.Notes, _ = flags.GetString("notes")
+	}
+	if flags.Changed("url") {
+		details.URLs, _ = flags.GetStringArray("url")
+	}
+	if flags.Changed("field") || flags.Changed("secret-field") {
+		plain, _ := flags.GetStringArray("field")
+		secret, _ := flags.GetStringArray("secret-field")
+		fields, err := parseFields(plain, secret)
+		if err != nil {
+			return db.EntryDetails{}, nil, err
+		}
+		details.Fields = fields
+	}
+	if flags.Changed("otp") {
+		value, _ := flags.GetString("otp")
+		uri, err := parseOTPFlag(value)
+		if err != nil {
+			return db.EntryDetails{}, nil, err
+		}
+		details.OTP = uri
+	}
+	if flags.Changed("tag") {
+		tags, _ = flags.GetStringSlice("tag")
+		if _, err := db.NormalizeTags(tags); err != nil {
+			return db.EntryDetails{}, nil, usageErrorf("%v", err)
+		}
+	}
+	return details, tags, nil
+}
+
+// detailFlagsChanged reports whether any of the detail flags was given
+func detailFlagsChanged(cmd *cobra.Command) bool {
+	for _, name := range []string{"notes", "url", "field", "secret-field", "tag", "otp"} {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFields parses name=value pairs into custom fields, rejecting empty and repeated names
+func parseFields(plain, secret []string) ([]db.Field, error) {
+	var fields []db.Field
+	seen := make(map[string]bool)
+	for i, pair := range slices.Concat(plain, secret) {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, usageErrorf("invalid field %q: expected name=value", pair)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, usageErrorf("field %q is given more than once", name)
+		}
+		seen[strings.ToLower(name)] = true
+		fields = append(fields, db.Field{Name: name, Value: value, Secret: i >= len(plain)})
+	}
+	return fields, nil
+}
+
+// printDetails prints the URLs, tags, custom fields and notes of an entry, masking secret fields unless reveal is set.
+// Fields from the schema of the entry's kind are shown with their label.
+func printDetails(w io.Writer, entry db.SensitiveData, reveal bool) {
+	for _, url := range entry.Details.URLs {
+		fmt.Fprintf(w, "URL: %s\n", url)
+	}
+	if tags := entry.TagList(); len(tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(tags, ", "))
+	}
+	kind := kindRegistry.Resolve(entry.Kind)
+	for _, field := range entry.Details.Fields {
+		label := field.Name
+		if schemaField, ok := kind.Field(field.Name); ok {
+			label = schemaField.Label
+		}
+		value := field.Value
+		if field.Secret && !reveal {
+			value = maskedValue
+		}
+		printLabeled(w, label, value)
+	}
+	if entry.Details.Notes != "" {
+		fmt.Fprintln(w, "Notes:")
+		for _, line := range strings.Split(entry.Details.Notes, "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
+
+// printLabeled prints a labeled value, indenting values that span several lines below the label
+func printLabeled(w io.Writer, label, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(w, "%s: %s\n", label, value)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", label)
+	for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
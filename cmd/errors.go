@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	db "vault-cli/database"
+)
+
+// Exit codes returned by the CLI. Scripts can rely on these values.
+const (
+	ExitOK               = 0 // Success
+	ExitError            = 1 // Any error not listed below
+	ExitUsage            = 2 // Invalid command, arguments or flags
+	ExitNotFound         = 3 // The requested entry does not exist
+	ExitLocked           = 4 // The vault is locked
+	ExitDuplicate        = 5 // The entry already exists
+	ExitNoMasterPassword = 6 // No master password has been set
+	ExitBadPassword      = 7 // The master password is wrong
+	ExitIntegrity        = 8 // Stored data failed its integrity check
+)
+
+// exitCodes maps the database sentinel errors to exit codes
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{db.ErrNotFound, ExitNotFound},
+	{db.ErrLocked, ExitLocked},
+	{db.ErrDuplicate, ExitDuplicate},
+	{db.ErrNoMasterPassword, ExitNoMasterPassword},
+	{db.ErrBadPassword, ExitBadPassword},
+	{db.ErrIntegrity, ExitIntegrity},
+}
+
+// usageError reports a command invoked with missing or invalid input
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// usageErrorf returns an error that makes the CLI exit with ExitUsage
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// childExitError passes on the exit code of a command run by exec. It is not printed.
+type childExitError struct {
+	code int
+}
+
+func (e *childExitError) Error() string {
+	return fmt.Sprintf("command exited with code %d", e.code)
+}
+
+// exitCode returns the exit code for err
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var child *childExitError
+	if errors.As(err, &child) {
+		return child.code
+	}
+	var usage *usageError
+	if errors.As(err, &usage) {
+		return ExitUsage
+	}
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return ExitError
+}
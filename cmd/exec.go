@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	db "vault-cli/database"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// envNamePattern matches the variable names exec accepts
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envSecret is a variable exec adds to the environment of the command it runs
+type envSecret struct {
+	name       string
+	service    string
+	identifier string
+	value      string
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec --env NAME=service/identifier... -- command [args...]",
+	Short: "Run a command with secrets from the vault in its environment",
+	Long: `Run a command with the values of vault entries added to its environment, so they never
+appear in shell history or in "export" lines. Each --env maps a variable name to an entry
+written as service/identifier; the service ends at the first "/".
+
+Signals received by vault-cli are forwarded to the command, and vault-cli exits with the
+command's exit code. With --mask, any of the values printed by the command on stdout or
+stderr is replaced with ` + maskedValue + `.`,
+	Example: `  vault-cli exec --env DB_PASS=prod-db/admin --env API_KEY=stripe/live -- ./deploy.sh`,
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		refs, _ := cmd.Flags().GetStringArray("env")
+		mask, _ := cmd.Flags().GetBool("mask")
+
+		secrets, err := parseEnvRefs(refs)
+		if err != nil {
+			return err
+		}
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+
+		// Resolve every reference before starting the command, so that it never runs with some missing
+		for i := range secrets {
+			entry, err := db.GetSensitiveData(store, keyring, secrets[i].service, secrets[i].identifier)
+			if err != nil {
+				return fmt.Errorf("error retrieving %s/%s for %s: %w", secrets[i].service, secrets[i].identifier, secrets[i].name, err)
+			}
+			secrets[i].value = entry.Value
+		}
+
+		return runWithSecrets(args, secrets, mask)
+	},
+}
+
+func init() {
+	execCmd.Annotations = requiresDatabase
+
+	// Flags after the command name belong to the command
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().StringArrayP("env", "e", nil, "Variable to set, as NAME=service/identifier (repeatable)")
+	execCmd.Flags().Bool("mask", false, "Replace the secret values in the command's output with "+maskedValue)
+	execCmd.MarkFlagRequired("env")
+}
+
+// parseEnvRefs parses NAME=service/identifier references, rejecting invalid and repeated names
+func parseEnvRefs(refs []string) ([]envSecret, error) {
+	var secrets []envSecret
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		name, entry, ok := strings.Cut(ref, "=")
+		if !ok || !envNamePattern.MatchString(name) {
+			return nil, usageErrorf("invalid --env %q: expected NAME=service/identifier", ref)
+		}
+		service, identifier, ok := strings.Cut(entry, "/")
+		if !ok || service == "" || identifier == "" {
+			return nil, usageErrorf("invalid --env %q: expected NAME=service/identifier", ref)
+		}
+		if seen[name] {
+			return nil, usageErrorf("variable %s is given more than once", name)
+		}
+		seen[name] = true
+		secrets = append(secrets, envSecret{name: name, service: service, identifier: identifier})
+	}
+	return secrets, nil
+}
+
+// runWithSecrets runs args with the secrets added to the current environment, forwarding signals to it.
+// It returns a childExitError unless the command exits successfully.
+func runWithSecrets(args []string, secrets []envSecret, mask bool) error {
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = os.Environ()
+	values := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		child.Env = append(child.Env, secret.name+"="+secret.value)
+		values = append(values, secret.value)
+	}
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+	if mask {
+		stdout, stderr := newMaskingWriter(os.Stdout, values), newMaskingWriter(os.Stderr, values)
+		defer stdout.Flush()
+		defer stderr.Flush()
+		child.Stdout, child.Stderr = stdout, stderr
+	}
+
+	// Catch signals before starting, so none can stop vault-cli and leave the command running alone
+	signals := make(chan os.Signal, 8)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("error starting %s: %w", args[0], err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				child.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := child.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &childExitError{code: childExitCode(exitErr.ProcessState)}
+	}
+	if err != nil {
+		return fmt.Errorf("error running %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// maskingWriter replaces secret values in what is written through it. Output that could be the
+// start of a value is held back until the next write shows whether it is one.
+type maskingWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	secrets  []string // Longest first, so that a value containing another is masked whole
+	replacer *strings.Replacer
+	pending  []byte
+}
+
+// newMaskingWriter returns a writer masking the non-empty secrets before writing to w
+func newMaskingWriter(w io.Writer, secrets []string) *maskingWriter {
+	var sorted []string
+	for _, secret := range secrets {
+		if secret != "" && !slices.Contains(sorted, secret) {
+			sorted = append(sorted, secret)
+		}
+	}
+	slices.SortFunc(sorted, func(a, b string) int { return len(b) - len(a) })
+
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, secret := range sorted {
+		pairs = append(pairs, secret, maskedValue)
+	}
+	return &maskingWriter{w: w, secrets: sorted, replacer: strings.NewReplacer(pairs...)}
+}
+
+func (m *maskingWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	masked := m.mask(append(m.pending, p...))
+	hold := m.partialSecret(masked)
+	m.pending = []byte(masked[len(masked)-hold:])
+	if _, err := io.WriteString(m.w, masked[:len(masked)-hold]); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the output held back, once nothing more will be written
+func (m *maskingWriter) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := io.WriteString(m.w, m.mask(m.pending))
+	m.pending = nil
+	return err
+}
+
+// mask replaces the secrets in data
+func (m *maskingWriter) mask(data []byte) string {
+	if len(m.secrets) == 0 {
+		return string(data)
+	}
+	return m.replacer.Replace(string(data))
+}
+
+// partialSecret returns the length of the longest end of s that is the start of a secret
+func (m *maskingWriter) partialSecret(s string) int {
+	longest := 0
+	for _, secret := range m.secrets {
+		for n := min(len(secret)-1, len(s)); n > longest; n-- {
+			if strings.HasSuffix(s, secret[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// forwardedSignals are passed on to the command run by exec
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// childExitCode returns the exit code of a command
+func childExitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseEnvRefs(t *testing.T) {
+	secrets, err := parseEnvRefs([]string{"DB_PASS=prod-db/admin", "API_KEY=stripe/live/2024"})
+	if err != nil {
+		t.Fatalf("parseEnvRefs failed: %v", err)
+	}
+	if len(secrets) != 2 || secrets[0] != (envSecret{name: "DB_PASS", service: "prod-db", identifier: "admin"}) ||
+		secrets[1].service != "stripe" || secrets[1].identifier != "live/2024" {
+		t.Errorf("unexpected references %+v", secrets)
+	}
+
+	for _, invalid := range [][]string{{"DB_PASS"}, {"1PASS=a/b"}, {"PASS=ab"}, {"PASS=/b"}, {"PASS=a/"}, {"A=a/b", "A=c/d"}} {
+		var usage *usageError
+		if _, err := parseEnvRefs(invalid); !errors.As(err, &usage) {
+			t.Errorf("parseEnvRefs(%q) = %v, want a usage error", invalid, err)
+		}
+	}
+}
+
+func TestMaskingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newMaskingWriter(&buf, []string{"hunter2", "", "hunter22", "tok"})
+
+	// Secrets split across writes are still masked, and output that cannot be a secret is not held back
+	for _, chunk := range []string{"pass=hun", "ter2 ", "next=hunter22\nto", "k", " h"} {
+		w.Write([]byte(chunk))
+	}
+	if buf.String() != "pass=******** next=********\n******** " {
+		t.Errorf("unexpected output before flush %q", buf.String())
+	}
+	w.Flush()
+	if expected := "pass=******** next=********\n******** h"; buf.String() != expected {
+		t.Errorf("masked output = %q, want %q", buf.String(), expected)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	db "vault-cli/database"
+	"vault-cli/vault"
 
 	"github.com/spf13/cobra"
 )
@@ -17,30 +18,23 @@ var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "Export all sensitive data entries to a file (CSV or JSON)",
 	Long:  `Export all stored sensitive data entries to a specified file in either CSV or JSON format.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
 		fileName, _ := cmd.Flags().GetString("file")
 		format, _ := cmd.Flags().GetString("format")
 
-		// Check if the vault is locked
-		isLocked, err := db.GetVaultState()
+		keyring, err := vault.Keyring(store)
 		if err != nil {
-			fmt.Println("Error retrieving vault state:", err)
-			return
-		}
-
-		if isLocked {
-			fmt.Println("Error: Vault is locked. Please unlock the vault using `unlock`.")
-			return
+			return err
 		}
 
 		// Automatically append the appropriate file extension
 		filePath := appendFileExtension(fileName, format)
 
 		// Retrieve all sensitive data from the vault
-		entries, err := db.GetAllSensitiveData("")
+		entries, err := db.GetAllSensitiveData(store, keyring, "")
 		if err != nil {
-			fmt.Printf("Error retrieving sensitive data: %v\n", err)
-			return
+			return fmt.Errorf("error retrieving sensitive data: %w", err)
 		}
 
 		// Export based on format
@@ -52,15 +46,17 @@ var exportCmd = &cobra.Command{
 		}
 
 		if err != nil {
-			fmt.Printf("Error exporting data: %v\n", err)
-			return
+			return fmt.Errorf("error exporting data: %w", err)
 		}
 
 		fmt.Println("Sensitive data exported successfully.")
+		return nil
 	},
 }
 
 func init() {
+	exportCmd.Annotations = requiresDatabase
+
 	exportCmd.Flags().StringP("file", "f", "", "File path to export data (required)")
 	exportCmd.Flags().StringP("format", "t", "json", "Export format (json or csv)")
 	exportCmd.MarkFlagRequired("file")
@@ -105,6 +101,10 @@ func exportToJSON(filePath string, entries []db.SensitiveData) error {
 	return nil
 }
 
+// csvHeaders are the columns of a CSV export. URLs are written one per line, tags separated by commas
+// and custom fields as a JSON array, so that the export keeps everything the JSON export does.
+var csvHeaders = []string{"Service", "Identifier", "Kind", "Value", "Notes", "URLs", "Tags", "OTP", "Fields"}
+
 // exportToCSV exports sensitive data to a CSV file
 func exportToCSV(filePath string, entries []db.SensitiveData) error {
 	file, err := os.Create(filePath)
@@ -117,15 +117,23 @@ func exportToCSV(filePath string, entries []db.SensitiveData) error {
 	defer writer.Flush()
 
 	// Write CSV headers
-	headers := []string{"Service", "Identifier", "Identifier Type", "Value"}
-	err = writer.Write(headers)
+	err = writer.Write(csvHeaders)
 	if err != nil {
 		return fmt.Errorf("failed to write CSV headers: %v", err)
 	}
 
 	// Write CSV rows for each entry
 	for _, entry := range entries {
-		row := []string{entry.Service, entry.Identifier, string(entry.IdentifierType), entry.Value}
+		fields := ""
+		if len(entry.Details.Fields) > 0 {
+			encoded, err := json.Marshal(entry.Details.Fields)
+			if err != nil {
+				return fmt.Errorf("failed to encode the fields of %s: %v", entry.Service, err)
+			}
+			fields = string(encoded)
+		}
+		row := []string{entry.Service, entry.Identifier, entry.Kind, entry.Value, entry.Details.Notes,
+			strings.Join(entry.Details.URLs, "\n"), strings.Join(entry.TagList(), ","), entry.Details.OTP, fields}
 		err = writer.Write(row)
 		if err != nil {
 			return fmt.Errorf("failed to write CSV row: %v", err)
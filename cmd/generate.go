@@ -1,31 +1,101 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"vault-cli/generator"
+
 	"github.com/spf13/cobra"
 )
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
-	Short: "Generate a random password",
-	Long:  `Generate a secure random password of the specified length.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		length, _ := cmd.Flags().GetInt("length")
-		if length <= 0 {
-			fmt.Println("Error: Length must be a positive integer.")
-			return
-		}
+	Short: "Generate a random password or passphrase",
+	Long: `Generate a secure random password of the specified length, or a passphrase of random words with --words.
 
-		password, err := generateRandomPassword(length)
+Passwords are drawn from the generator.charset setting unless --charset is given. Use --require to make sure
+the password contains at least one upper case letter, lower case letter, digit or symbol, and --exclude or
+--no-ambiguous to leave characters out. The estimated entropy of the result is printed alongside it.`,
+	Example: `  vault-cli generate --length 20 --require upper,lower,digit,symbol --no-ambiguous
+  vault-cli generate --charset 0123456789 --length 6
+  vault-cli generate --words 6 --separator -`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var result generator.Result
+		var err error
+		kind := "Password"
+		if cmd.Flags().Changed("words") {
+			kind = "Passphrase"
+			words, _ := cmd.Flags().GetInt("words")
+			separator, _ := cmd.Flags().GetString("separator")
+			result, err = generator.Passphrase(words, separator)
+		} else {
+			if cmd.Flags().Changed("separator") {
+				return usageErrorf("--separator can only be used with --words")
+			}
+			var opts generator.Options
+			if opts, err = generatorOptions(cmd); err != nil {
+				return err
+			}
+			result, err = generator.Password(opts)
+		}
+		if errors.Is(err, generator.ErrInvalidOptions) {
+			return usageErrorf("%v", err)
+		}
 		if err != nil {
-			fmt.Println("Error generating password:", err)
-			return
+			return fmt.Errorf("error generating password: %w", err)
 		}
 
-		fmt.Println("Generated Password:", password)
+		if clip, _ := cmd.Flags().GetBool("clip"); clip {
+			if err := copyToClipboard(cmd, result.Value, "the generated "+strings.ToLower(kind)); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("Generated %s: %s\n", kind, result.Value)
+		}
+		fmt.Printf("Estimated entropy: %.1f bits\n", result.Entropy)
+		return nil
 	},
 }
 
 func init() {
-	generateCmd.Flags().IntP("length", "l", 12, "Length of the password (default is 12)")
+	generateCmd.Flags().IntP("length", "l", 0, "Length of the password (default from the generator.length setting, 12)")
+	generateCmd.Flags().String("charset", "", "Characters to draw from (default from the generator.charset setting)")
+	generateCmd.Flags().Bool("no-ambiguous", false, "Leave out characters that are easily confused ("+generator.Ambiguous+")")
+	generateCmd.Flags().StringSlice("require", nil, "Character classes the password must contain: upper, lower, digit, symbol")
+	generateCmd.Flags().String("exclude", "", "Characters to leave out")
+	generateCmd.Flags().Int("words", 0, "Generate a passphrase of this many words instead of a password")
+	generateCmd.Flags().String("separator", "-", "Separator between passphrase words")
+	generateCmd.Flags().BoolP("clip", "c", false, "Copy the password to the clipboard instead of printing it")
+
+	for _, flag := range []string{"length", "charset", "no-ambiguous", "require", "exclude"} {
+		generateCmd.MarkFlagsMutuallyExclusive("words", flag)
+	}
+}
+
+// generatorOptions returns the password options given by the flags of cmd, falling back to the generator settings
+func generatorOptions(cmd *cobra.Command) (generator.Options, error) {
+	length, err := intSetting(cmd, "length", "generator.length")
+	if err != nil {
+		return generator.Options{}, err
+	}
+	charset, err := stringSetting(cmd, "charset", "generator.charset")
+	if err != nil {
+		return generator.Options{}, err
+	}
+	noAmbiguous, _ := cmd.Flags().GetBool("no-ambiguous")
+	exclude, _ := cmd.Flags().GetString("exclude")
+	required, _ := cmd.Flags().GetStringSlice("require")
+	classes, err := generator.ParseClasses(required)
+	if err != nil {
+		return generator.Options{}, usageErrorf("%v", err)
+	}
+
+	return generator.Options{
+		Length:      length,
+		Charset:     charset,
+		NoAmbiguous: noAmbiguous,
+		Exclude:     exclude,
+		Require:     classes,
+	}, nil
 }
@@ -2,11 +2,15 @@ package cmd
 
 import (
 	db "vault-cli/database"
+	"vault-cli/otp"
+	"vault-cli/vault"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 // getCmd represents the get command
@@ -14,44 +18,125 @@ var getCmd = &cobra.Command{
 	Use:   "get",
 	Short: "Retrieve a sensitive data entry in the vault",
 	Long:  `Retrieve a specific service and identifier from the vault.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
 		service, _ := cmd.Flags().GetString("service")
 		identifier, _ := cmd.Flags().GetString("identifier")
+		field, _ := cmd.Flags().GetString("field")
+		clip, _ := cmd.Flags().GetBool("clip")
+		reveal, _ := cmd.Flags().GetBool("reveal")
 
-		// Check if the vault is locked
-		isLocked, err := db.GetVaultState()
+		keyring, err := vault.Keyring(store)
 		if err != nil {
-			fmt.Println("Error retrieving vault state:", err)
-			return
-		}
-
-		if isLocked {
-			fmt.Println("Error: Vault is locked. Please unlock the vault using `unlock`.")
-			return
+			return err
 		}
 
 		if service == "" || identifier == "" {
-			fmt.Println("Error: Both service and identifier are required.")
-			return
+			return usageErrorf("both service and identifier are required")
 		}
 
 		// Retrieve the sensitive data based on service and identifier
-		entry, err := db.GetSensitiveData(service, identifier)
+		entry, err := db.GetSensitiveData(store, keyring, service, identifier)
+		if err != nil {
+			return fmt.Errorf("error retrieving data: %w", err)
+		}
+
+		if clip {
+			return copyToClipboard(cmd, entry.Value, fmt.Sprintf("the value of %s/%s", entry.Service, entry.Identifier))
+		}
+		if field != "" {
+			return printField(cmd.OutOrStdout(), entry, field)
+		}
+
+		// Show the current one-time code with the password; HOTP codes are left to the otp command
+		// since generating one uses up a counter
+		view := newEntryView(entry, true, reveal)
+		key, hasOTP, err := entryOTPKey(entry)
 		if err != nil {
-			fmt.Println("Error retrieving data:", err)
-			return
+			return err
+		}
+		var otpLine string
+		if hasOTP && key.Type == otp.HOTP {
+			otpLine = fmt.Sprintf("counter-based, run \"vault-cli otp -s %s -i %s\" for the next code", entry.Service, entry.Identifier)
+		} else if hasOTP {
+			now := time.Now()
+			if view.OTPCode, err = key.Code(now); err != nil {
+				return err
+			}
+			otpLine = describeOTPCode(otpView{Code: view.OTPCode, ExpiresIn: int(key.Remaining(now).Seconds())})
 		}
 
 		// Print the retrieved value
-		fmt.Printf("Service: %s\n", entry.Service)
-		fmt.Printf("%s: %s\n", cases.Title(language.Und).String(string(entry.IdentifierType)), entry.Identifier)
-		fmt.Printf("Password: %s\n", entry.Value)
+		return render(cmd, view, func(w io.Writer, color bool) error {
+			fmt.Fprintf(w, "Service: %s\n", entry.Service)
+			kind := kindRegistry.Resolve(entry.Kind)
+			fmt.Fprintf(w, "Kind: %s\n", kind.Name)
+			fmt.Fprintf(w, "%s: %s\n", kind.Identifier.Label, entry.Identifier)
+			printLabeled(w, kind.Value.Label, entry.Value)
+			if otpLine != "" {
+				fmt.Fprintf(w, "One-time code: %s\n", otpLine)
+			}
+			printDetails(w, entry, reveal)
+			return nil
+		})
 	},
 }
 
+// printField prints a single field of entry with no decoration, for piping into other tools.
+// A trailing newline is only added when writing to a terminal.
+func printField(w io.Writer, entry db.SensitiveData, field string) error {
+	value, err := entryField(entry, field)
+	if err != nil {
+		return err
+	}
+	if isTerminal(w) {
+		value += "\n"
+	}
+	_, err = io.WriteString(w, value)
+	return err
+}
+
+// entryField returns a field of entry by name: one of the entry's own fields or a custom field
+func entryField(entry db.SensitiveData, field string) (string, error) {
+	switch field {
+	case "service":
+		return entry.Service, nil
+	case "identifier":
+		return entry.Identifier, nil
+	case "kind":
+		return entry.Kind, nil
+	case "value":
+		return entry.Value, nil
+	case "notes":
+		return entry.Details.Notes, nil
+	case "url":
+		if len(entry.Details.URLs) == 0 {
+			return "", nil
+		}
+		return entry.Details.URLs[0], nil
+	case "urls":
+		return strings.Join(entry.Details.URLs, "\n"), nil
+	case "tags":
+		return strings.Join(entry.TagList(), ","), nil
+	}
+
+	// Custom fields are returned even if they are secret, since they were asked for by name
+	index := slices.IndexFunc(entry.Details.Fields, func(f db.Field) bool { return strings.EqualFold(f.Name, field) })
+	if index < 0 {
+		return "", usageErrorf("unknown field %q: expected one of service, identifier, kind, value, notes, url, urls, tags or a custom field", field)
+	}
+	return entry.Details.Fields[index].Value, nil
+}
+
 func init() {
+	getCmd.Annotations = requiresDatabase
+
 	getCmd.Flags().StringP("service", "s", "", "Service name (required)")
 	getCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	getCmd.Flags().String("field", "", "Print only this field (service, identifier, kind, value, notes, url, urls, tags or a custom field name) without any formatting")
+	getCmd.Flags().BoolP("clip", "c", false, "Copy the value to the clipboard instead of printing it")
+	getCmd.Flags().Bool("reveal", false, "Show the values of secret custom fields")
+	getCmd.MarkFlagsMutuallyExclusive("clip", "field")
 	getCmd.MarkFlagRequired("service")
 	getCmd.MarkFlagRequired("identifier")
 }
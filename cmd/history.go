@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	db "vault-cli/database"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// historyView is the stable schema of a previous value in machine-readable output.
+// Value is omitted unless --reveal is given.
+type historyView struct {
+	Version    int       `json:"version"`
+	Identifier string    `json:"identifier"`
+	Value      string    `json:"value,omitempty"`
+	SetAt      time.Time `json:"set_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the previous values of an entry",
+	Long: `List the values an entry had before it was updated, rotated or restored, oldest first.
+Up to the history.retention setting of previous values are kept per entry. Use restore with
+one of the listed versions to roll the entry back.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		service, _ := cmd.Flags().GetString("service")
+		identifier, _ := cmd.Flags().GetString("identifier")
+		reveal, _ := cmd.Flags().GetBool("reveal")
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+
+		records, err := db.GetSensitiveDataHistory(store, keyring, service, identifier)
+		if err != nil {
+			return fmt.Errorf("error retrieving history: %w", err)
+		}
+
+		views := make([]historyView, 0, len(records))
+		for _, record := range records {
+			view := historyView{
+				Version:    record.Version,
+				Identifier: record.Identifier,
+				SetAt:      record.ValueSetAt.UTC().Truncate(time.Second),
+				ReplacedAt: record.CreatedAt.UTC().Truncate(time.Second),
+			}
+			if reveal {
+				view.Value = record.Value
+			}
+			views = append(views, view)
+		}
+		return render(cmd, views, func(w io.Writer, color bool) error {
+			printHistoryTable(w, views, reveal)
+			return nil
+		})
+	},
+}
+
+// printHistoryTable prints one row per previous value, with the value only if reveal is set
+func printHistoryTable(w io.Writer, views []historyView, reveal bool) {
+	if len(views) == 0 {
+		fmt.Fprintln(w, "No previous values recorded.")
+		return
+	}
+	if reveal {
+		fmt.Fprintf(w, "%-7s | %-25s | %-19s | %-19s | %s\n", "Version", "Identifier", "Set", "Replaced", "Value")
+	} else {
+		fmt.Fprintf(w, "%-7s | %-25s | %-19s | %s\n", "Version", "Identifier", "Set", "Replaced")
+	}
+	for _, view := range views {
+		fmt.Fprintf(w, "%-7d | %-25s | %s | %s", view.Version, view.Identifier,
+			view.SetAt.Local().Format(time.DateTime), view.ReplacedAt.Local().Format(time.DateTime))
+		if reveal {
+			fmt.Fprintf(w, " | %s", view.Value)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func init() {
+	historyCmd.Annotations = requiresDatabase
+
+	historyCmd.Flags().StringP("service", "s", "", "Service name (required)")
+	historyCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	historyCmd.Flags().Bool("reveal", false, "Include the previous values in the output")
+
+	historyCmd.MarkFlagRequired("service")
+	historyCmd.MarkFlagRequired("identifier")
+}
@@ -1,162 +1,253 @@
 package cmd
 
 import (
-	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	db "vault-cli/database"
+	"vault-cli/importer"
+	"vault-cli/kinds"
+	"vault-cli/vault"
 
 	"github.com/spf13/cobra"
 )
 
+// Policies for items whose service and identifier match an entry already in the vault
+const (
+	conflictSkip      = "skip"      // Keep the vault's entry
+	conflictOverwrite = "overwrite" // Replace the vault's entry with the item
+	conflictRename    = "rename"    // Add the item under a numbered identifier
+	conflictNewer     = "newer"     // Replace the vault's entry if the item was changed after it
+)
+
+var conflictPolicies = []string{conflictSkip, conflictOverwrite, conflictRename, conflictNewer}
+
+// Actions an import takes for an item
+const (
+	importAdd       = "add"
+	importOverwrite = "overwrite"
+	importRename    = "rename"
+	importSkip      = "skip"
+)
+
+// importStep is what an import does with an item read from the file
+type importStep struct {
+	action     string
+	item       importer.Item
+	kind       string // Resolved kind of the item
+	identifier string // Identifier the item is stored under, which differs from the item's when renamed
+	reason     string // Why the item is skipped
+}
+
 var importCmd = &cobra.Command{
 	Use:   "import",
-	Short: "Import password entries from a file (CSV or JSON)",
-	Long:  `Import password entries from a specified file in either CSV or JSON format.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// Get the filename from the flags
+	Short: "Import entries from vault-cli or another password manager",
+	Long: fmt.Sprintf(`Import entries from a file exported by vault-cli or another password manager. The format is
+detected from the file unless it is given with --from (one of %s).
+
+Items matching an entry already in the vault are handled as set by --on-conflict: skip keeps the
+vault's entry, overwrite replaces it, rename adds the item under a numbered identifier, and
+newer replaces it only if the export says the item was changed after the entry.
+
+The import runs in a single transaction, so nothing is imported if any entry fails. --dry-run
+lists what would be added, changed and skipped without changing the vault.`, strings.Join(importer.Names(), ", ")),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		// Get the filename, format and conflict policy from the flags
 		fileName, _ := cmd.Flags().GetString("file")
+		from, _ := cmd.Flags().GetString("from")
+		onConflict, _ := cmd.Flags().GetString("on-conflict")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-		// Check if the vault is locked
-		isLocked, err := db.GetVaultState()
+		if !slices.Contains(conflictPolicies, onConflict) {
+			return usageErrorf("invalid --on-conflict %q: expected one of %s", onConflict, strings.Join(conflictPolicies, ", "))
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		var format importer.Format
+		if from != "" {
+			format, err = importer.Lookup(from)
+		} else {
+			format, err = importer.Detect(fileName, data)
+		}
+		if err != nil {
+			return usageErrorf("%v", err)
+		}
+		result, err := format.Parse(data)
 		if err != nil {
-			fmt.Println("Error retrieving vault state:", err)
-			return
+			return fmt.Errorf("error importing data: %w", err)
 		}
 
-		if isLocked {
-			fmt.Println("Error: Vault is locked. Please unlock the vault using `unlock`.")
-			return
+		// Conflicts are found up front from the entries' names, which are not encrypted
+		existing, err := store.List("")
+		if err != nil {
+			return fmt.Errorf("error listing entries: %w", err)
 		}
+		steps := planImport(existing, result.Items, onConflict)
 
-		// Validate file extension (only .json or .csv are accepted)
-		ext := strings.ToLower(filepath.Ext(fileName))
-		if ext != ".json" && ext != ".csv" {
-			fmt.Println("Error: Invalid file type. Only .json or .csv files are accepted.")
-			return
+		if dryRun {
+			for _, step := range steps {
+				fmt.Println(describeImportStep(step))
+			}
+			printSkippedItems(result.Skipped)
+			fmt.Printf("Dry run: would %s. The vault was not changed.\n", summarizeImport(steps, len(result.Skipped), false))
+			return nil
 		}
 
-		// Import based on file type
-		if ext == ".json" {
-			err = importFromJSON(fileName)
-		} else if ext == ".csv" {
-			err = importFromCSV(fileName)
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
 		}
 
+		// Import every entry or none of them
+		err = store.Transaction(func(tx db.Store) error {
+			for _, step := range steps {
+				if err := applyImportStep(tx, keyring, step); err != nil {
+					return fmt.Errorf("%s/%s: %w", step.item.Service, step.identifier, err)
+				}
+			}
+			return nil
+		})
 		if err != nil {
-			fmt.Printf("Error importing data: %v\n", err)
-			return
+			return fmt.Errorf("error importing data, nothing was imported: %w", err)
 		}
 
-		fmt.Println("Data imported successfully.")
+		for _, step := range steps {
+			if step.action == importSkip || step.action == importRename {
+				fmt.Println(describeImportStep(step))
+			}
+		}
+		printSkippedItems(result.Skipped)
+		fmt.Printf("Imported from the %s: %s.\n", format.Description, summarizeImport(steps, len(result.Skipped), true))
+		return nil
 	},
 }
 
 // init initializes the import command flags
 func init() {
+	importCmd.Annotations = requiresDatabase
+
 	importCmd.Flags().StringP("file", "f", "", "Filename to import data from (required)")
+	importCmd.Flags().String("from", "", "Format of the file: "+strings.Join(importer.Names(), ", ")+" (detected if not given)")
+	importCmd.Flags().String("on-conflict", conflictSkip, "What to do with entries already in the vault: "+strings.Join(conflictPolicies, ", "))
+	importCmd.Flags().Bool("dry-run", false, "Show what would be imported without changing the vault")
 	importCmd.MarkFlagRequired("file")
 }
 
-// importFromJSON reads and parses a JSON file, then adds the entries to the vault
-func importFromJSON(fileName string) error {
-	// Open the file
-	file, err := os.Open(fileName)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	// Decode JSON data
-	var entries []db.SensitiveData
-	err = json.NewDecoder(file).Decode(&entries)
-	if err != nil {
-		return fmt.Errorf("failed to decode JSON: %v", err)
-	}
-
-	// Add each entry to the vault
-	for _, entry := range entries {
-		err = db.AddSensitiveData(entry.Service, entry.Identifier, entry.Value, string(entry.IdentifierType))
-		if err != nil {
-			return fmt.Errorf("failed to add entry for service %s: %v", entry.Service, err)
-		}
-	}
-
-	return nil
+// entryKey identifies an entry the way the store does, ignoring case
+func entryKey(service, identifier string) string {
+	return strings.ToLower(service) + "\x00" + strings.ToLower(identifier)
 }
 
-// importFromCSV reads and parses a CSV file, then adds the entries to the vault
-func importFromCSV(fileName string) error {
-	// Open the file
-	file, err := os.Open(fileName)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	// Create a new CSV reader
-	reader := csv.NewReader(file)
-
-	// Read the CSV headers (should match the format used in export)
-	headers, err := reader.Read()
-	if err != nil {
-		return fmt.Errorf("failed to read CSV headers: %v", err)
+// planImport decides what to do with each item, given the entries in the vault and the conflict policy.
+// Items are also checked against the items before them, so that a file can hold the same entry twice.
+func planImport(existing []db.SensitiveData, items []importer.Item, onConflict string) []importStep {
+	// When each entry, in the vault or planned, was last changed
+	changed := make(map[string]time.Time, len(existing))
+	for _, entry := range existing {
+		changed[entryKey(entry.Service, entry.Identifier)] = entry.UpdatedAt
 	}
 
-	// Ensure headers match the expected format
-	expectedHeaders := []string{"Service", "Identifier", "Identifier Type", "Value"}
-	if !compareHeaders(headers, expectedHeaders) {
-		return fmt.Errorf("CSV headers do not match the expected format")
-	}
+	steps := make([]importStep, 0, len(items))
+	for _, item := range items {
+		step := importStep{item: item, identifier: item.Identifier}
 
-	// Read each row and add the entry to the vault
-	for {
-		row, err := reader.Read()
+		// Legacy identifier types are accepted as aliases of kinds
+		kind, err := kindRegistry.Lookup(item.Kind)
 		if err != nil {
-			if err.Error() == "EOF" {
-				break
-			}
-			return fmt.Errorf("failed to read CSV row: %v", err)
+			step.action, step.reason = importSkip, err.Error()
+			steps = append(steps, step)
+			continue
 		}
-
-		// Convert the identifier type (row[2]) from string to db.IdentifierType
-		identifierType, err := db.ParseIdentifierType(row[2])
-		if err != nil {
-			return fmt.Errorf("invalid identifier type: %v", err)
+		step.kind = kind.Name
+		if kind.Name == kinds.TOTP {
+			if step.item.Value, step.item.Details, err = totpSeed(item.Service, item.Identifier, item.Value, item.Details); err != nil {
+				step.action, step.reason = importSkip, err.Error()
+				steps = append(steps, step)
+				continue
+			}
 		}
 
-		// Create a new entry and add to the vault
-		entry := db.SensitiveData{
-			Service:        row[0],
-			Identifier:     row[1],
-			IdentifierType: identifierType,
-			Value:          row[3],
+		key := entryKey(item.Service, item.Identifier)
+		updatedAt, exists := changed[key]
+		switch {
+		case !exists:
+			step.action = importAdd
+		case onConflict == conflictOverwrite:
+			step.action = importOverwrite
+		case onConflict == conflictRename:
+			step.action = importRename
+			for n := 2; exists; n++ {
+				step.identifier = fmt.Sprintf("%s (%d)", item.Identifier, n)
+				_, exists = changed[entryKey(item.Service, step.identifier)]
+			}
+			key = entryKey(item.Service, step.identifier)
+		case onConflict == conflictNewer && item.UpdatedAt.IsZero():
+			step.action, step.reason = importSkip, "already exists, and the export does not say when the item was changed"
+		case onConflict == conflictNewer && item.UpdatedAt.After(updatedAt):
+			step.action = importOverwrite
+		case onConflict == conflictNewer:
+			step.action, step.reason = importSkip, "the entry in the vault is as recent or newer"
+		default:
+			step.action, step.reason = importSkip, "already exists"
 		}
-
-		err = db.AddSensitiveData(entry.Service, entry.Identifier, entry.Value, string(entry.IdentifierType))
-		if err != nil {
-			return fmt.Errorf("failed to add entry for service %s: %v", entry.Service, err)
+		if step.action != importSkip {
+			changed[key] = item.UpdatedAt
 		}
+		steps = append(steps, step)
 	}
+	return steps
+}
 
+// applyImportStep adds or overwrites the entry for an item
+func applyImportStep(tx db.Store, keyring db.Keyring, step importStep) error {
+	item := step.item
+	switch step.action {
+	case importAdd, importRename:
+		return db.AddSensitiveDataWithDetails(tx, keyring, item.Service, step.identifier, item.Value, step.kind, item.Details, item.Tags)
+	case importOverwrite:
+		return db.ReplaceSensitiveData(tx, keyring, item.Service, step.identifier, item.Value, step.kind, item.Details, item.Tags)
+	}
 	return nil
 }
 
-// compareHeaders checks if the CSV headers match the expected format
-func compareHeaders(headers, expected []string) bool {
-	if len(headers) != len(expected) {
-		return false
+// describeImportStep returns a line saying what the import does with an item
+func describeImportStep(step importStep) string {
+	name := step.item.Service + "/" + step.item.Identifier
+	switch step.action {
+	case importRename:
+		return fmt.Sprintf("%-9s  %s -> %s/%s", step.action, name, step.item.Service, step.identifier)
+	case importSkip:
+		return fmt.Sprintf("%-9s  %s: %s", step.action, name, step.reason)
+	default:
+		return fmt.Sprintf("%-9s  %s", step.action, name)
 	}
+}
 
-	for i, header := range headers {
-		if strings.TrimSpace(header) != strings.TrimSpace(expected[i]) {
-			return false
-		}
+// printSkippedItems lists the items the export could not provide
+func printSkippedItems(skipped []importer.Skipped) {
+	for _, item := range skipped {
+		fmt.Printf("%-9s  %s: %s\n", importSkip, item.Name, item.Reason)
 	}
+}
 
-	return true
+// summarizeImport counts the steps of an import, such as "add 3, overwrite 1, rename 0 and skip 2 entries",
+// or in the past tense once done. unreadable is the number of items the export could not provide.
+func summarizeImport(steps []importStep, unreadable int, done bool) string {
+	counts := map[string]int{importSkip: unreadable}
+	for _, step := range steps {
+		counts[step.action]++
+	}
+	verbs := map[string]string{importAdd: "add", importOverwrite: "overwrite", importRename: "rename", importSkip: "skip"}
+	if done {
+		verbs = map[string]string{importAdd: "added", importOverwrite: "overwrote", importRename: "renamed", importSkip: "skipped"}
+	}
+	return fmt.Sprintf("%s %d, %s %d, %s %d and %s %d entries",
+		verbs[importAdd], counts[importAdd], verbs[importOverwrite], counts[importOverwrite],
+		verbs[importRename], counts[importRename], verbs[importSkip], counts[importSkip])
 }
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	db "vault-cli/database"
+	"vault-cli/importer"
+)
+
+func TestPlanImport(t *testing.T) {
+	vaultTime := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	existing := []db.SensitiveData{
+		{Service: "github", Identifier: "me"},
+		{Service: "github", Identifier: "me (2)"},
+	}
+	existing[0].UpdatedAt = vaultTime
+
+	items := []importer.Item{
+		{Service: "GitHub", Identifier: "ME", Kind: "login", Value: "a", UpdatedAt: vaultTime.Add(time.Hour)},
+		{Service: "gitlab", Identifier: "me", Kind: "username", Value: "b"},
+		{Service: "gitlab", Identifier: "me", Kind: "login", Value: "c", UpdatedAt: vaultTime},
+		{Service: "x", Identifier: "y", Kind: "unknown_kind", Value: "d"},
+	}
+
+	tests := []struct {
+		onConflict string
+		want       []string // Action of each item
+	}{
+		{conflictSkip, []string{importSkip, importAdd, importSkip, importSkip}},
+		{conflictOverwrite, []string{importOverwrite, importAdd, importOverwrite, importSkip}},
+		{conflictRename, []string{importRename, importAdd, importRename, importSkip}},
+		{conflictNewer, []string{importOverwrite, importAdd, importOverwrite, importSkip}},
+	}
+	for _, test := range tests {
+		steps := planImport(existing, items, test.onConflict)
+		for i, step := range steps {
+			if step.action != test.want[i] {
+				t.Errorf("%s: item %d is planned as %s (%s), want %s", test.onConflict, i, step.action, step.reason, test.want[i])
+			}
+		}
+		if steps[1].kind != "login" {
+			t.Errorf("%s: expected the legacy identifier type to resolve to login, got %q", test.onConflict, steps[1].kind)
+		}
+		if test.onConflict == conflictRename && (steps[0].identifier != "ME (3)" || steps[2].identifier != "me (2)") {
+			t.Errorf("unexpected renamed identifiers %q and %q", steps[0].identifier, steps[2].identifier)
+		}
+	}
+
+	// An item older than the vault's entry, or without a time, is skipped
+	steps := planImport(existing, []importer.Item{
+		{Service: "github", Identifier: "me", Kind: "login", Value: "a", UpdatedAt: vaultTime.Add(-time.Hour)},
+		{Service: "github", Identifier: "me (2)", Kind: "login", Value: "a"},
+	}, conflictNewer)
+	if steps[0].action != importSkip || steps[1].action != importSkip {
+		t.Errorf("expected both items to be skipped, got %+v", steps)
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	db "vault-cli/database"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+var injectCmd = &cobra.Command{
+	Use:   "inject",
+	Short: "Render a template with secrets from the vault",
+	Long: `Render a Go text/template with values from the vault, for config files that need secrets at deploy time.
+Templates call {{ secret "service" "identifier" }} for the value of an entry and
+{{ field "service" "identifier" "name" }} for another field: service, identifier, kind, notes,
+url (the first URL), urls, tags or the name of a custom field.
+
+Nothing is written if any reference cannot be resolved, including references to empty fields;
+every unresolved reference is reported. The output file is created with 0600 permissions and
+replaced atomically, so readers never see a partial file. --check resolves the references
+without writing anything.`,
+	Example: `  vault-cli inject -i app.yaml.tmpl -o app.yaml
+  vault-cli inject -i .npmrc.tmpl --check`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		in, _ := cmd.Flags().GetString("in")
+		out, _ := cmd.Flags().GetString("out")
+		check, _ := cmd.Flags().GetBool("check")
+
+		name, text, err := readTemplate(in)
+		if err != nil {
+			return err
+		}
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+		return injectTemplate(cmd, store, keyring, name, text, out, check)
+	},
+}
+
+func init() {
+	injectCmd.Annotations = requiresDatabase
+
+	injectCmd.Flags().StringP("in", "i", "-", `Template file ("-" for standard input)`)
+	injectCmd.Flags().StringP("out", "o", "-", `Output file ("-" for standard output)`)
+	injectCmd.Flags().Bool("check", false, "Check that every reference resolves without writing the output")
+	injectCmd.MarkFlagsMutuallyExclusive("check", "out")
+}
+
+// injectTemplate renders the template text with values from the vault and writes it to out, or
+// with check only reports whether every reference resolves
+func injectTemplate(cmd *cobra.Command, s db.Store, keyring db.Keyring, name, text, out string, check bool) error {
+	resolver := &templateResolver{store: s, keyring: keyring, entries: make(map[[2]string]db.SensitiveData)}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(resolver.funcs()).Parse(text)
+	if err != nil {
+		return usageErrorf("invalid template: %v", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, nil); err != nil {
+		return fmt.Errorf("error rendering %s: %w", name, err)
+	}
+	if len(resolver.problems) > 0 {
+		return fmt.Errorf("unresolved references in %s: %s", name, strings.Join(resolver.problems, "; "))
+	}
+
+	if check {
+		fmt.Fprintf(cmd.ErrOrStderr(), "All %d references in %s resolve.\n", resolver.references, name)
+		return nil
+	}
+	if out == "-" {
+		_, err := cmd.OutOrStdout().Write(rendered.Bytes())
+		return err
+	}
+	if err := writeFileAtomic(out, rendered.Bytes()); err != nil {
+		return fmt.Errorf("error writing %s: %w", out, err)
+	}
+	return nil
+}
+
+// readTemplate returns the name used in messages and the text of the template at path, or of standard input for "-"
+func readTemplate(path string) (string, string, error) {
+	if path == "-" {
+		text, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("error reading the template: %w", err)
+		}
+		return "standard input", string(text), nil
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading the template: %w", err)
+	}
+	return path, string(text), nil
+}
+
+// templateResolver looks up the entries referenced by a template. Unresolved references are
+// recorded instead of stopping the template, so that all of them can be reported at once.
+type templateResolver struct {
+	store      db.Store
+	keyring    db.Keyring
+	entries    map[[2]string]db.SensitiveData // Entries already read, by service and identifier
+	references int
+	problems   []string
+}
+
+// funcs returns the template functions reading from the vault
+func (r *templateResolver) funcs() template.FuncMap {
+	return template.FuncMap{
+		"secret": func(service, identifier string) string {
+			return r.field(service, identifier, "value")
+		},
+		"field": r.field,
+	}
+}
+
+// field returns a field of an entry, or records why it could not be read. Empty fields are unresolved
+// too, so that a template never silently renders a missing URL or note.
+func (r *templateResolver) field(service, identifier, name string) string {
+	r.references++
+	key := [2]string{service, identifier}
+	entry, ok := r.entries[key]
+	if !ok {
+		var err error
+		if entry, err = db.GetSensitiveData(r.store, r.keyring, service, identifier); err != nil {
+			r.problems = append(r.problems, fmt.Sprintf("%s/%s: %v", service, identifier, err))
+			return ""
+		}
+		r.entries[key] = entry
+	}
+	value, err := entryField(entry, name)
+	if err == nil && value == "" {
+		err = fmt.Errorf("field %q is empty", name)
+	}
+	if err != nil {
+		r.problems = append(r.problems, fmt.Sprintf("%s/%s: %v", service, identifier, err))
+		return ""
+	}
+	return value
+}
+
+// writeFileAtomic replaces path with data, readable only by the user. The data is written to a
+// temporary file in the same directory and renamed over path once it is complete.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once the file has been renamed
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	db "vault-cli/database"
+	"vault-cli/kinds"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+// kindRegistry holds the built-in entry kinds and the custom kinds defined in the config file
+var kindRegistry = kinds.NewRegistry()
+
+// kindView is the stable schema of an entry kind in machine-readable output
+type kindView struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Custom      bool            `json:"custom"`
+	Generate    bool            `json:"generate"`
+	Fields      []kindFieldView `json:"fields"` // The identifier and value first, then the extra fields
+}
+
+// kindFieldView is a field of an entry kind's schema
+type kindFieldView struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Default  string   `json:"default,omitempty"`
+	Choices  []string `json:"choices,omitempty"`
+}
+
+// newKindView converts a kind and its schema
+func newKindView(kind kinds.Kind) kindView {
+	view := kindView{Name: kind.Name, Description: kind.Description, Custom: kind.Custom, Generate: kind.Generate}
+	for _, field := range slices.Concat([]kinds.Field{kind.Identifier, kind.Value}, kind.Fields) {
+		view.Fields = append(view.Fields, kindFieldView{
+			Name:     field.Name,
+			Label:    field.Label,
+			Type:     string(field.Type),
+			Required: field.Required || field.Name == "identifier",
+			Default:  field.Default,
+			Choices:  field.Choices,
+		})
+	}
+	return view
+}
+
+var kindsCmd = &cobra.Command{
+	Use:   "kinds [name]",
+	Short: "List the kinds of entries and their fields",
+	Long: `List the kinds of entries the vault can hold, or show the fields of one kind.
+
+Custom kinds are defined in the config file with a [kinds.<name>] table, for example:
+
+  [kinds.wifi]
+  description = "Wi-Fi network"
+  identifier = "Network name"
+  value = "Password"
+  fields = "security:choice(WPA2|WPA3)!, router:url, admin_pin:secret"
+
+Fields are written as name[:type][!], where ! marks a required field. The types are
+text, secret, multiline, number, url, email, card_number, expiry, base32 and choice(a|b).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			kind, err := kindRegistry.Lookup(args[0])
+			if err != nil {
+				return usageErrorf("%v", err)
+			}
+			view := newKindView(kind)
+			return render(cmd, view, func(w io.Writer, color bool) error {
+				printKindFields(w, view)
+				return nil
+			})
+		}
+
+		var views []kindView
+		for _, kind := range kindRegistry.Kinds() {
+			views = append(views, newKindView(kind))
+		}
+		return render(cmd, views, func(w io.Writer, color bool) error {
+			fmt.Fprintf(w, "%-12s | %-8s | %s\n", "Kind", "Source", "Description")
+			for _, view := range views {
+				source := "built-in"
+				if view.Custom {
+					source = "config"
+				}
+				fmt.Fprintf(w, "%-12s | %-8s | %s\n", view.Name, source, view.Description)
+			}
+			return nil
+		})
+	},
+}
+
+// printKindFields prints the schema of a kind as a table
+func printKindFields(w io.Writer, view kindView) {
+	fmt.Fprintf(w, "%s: %s\n", view.Name, view.Description)
+	if view.Generate {
+		fmt.Fprintln(w, "Values are generated when none is entered.")
+	}
+	fmt.Fprintf(w, "%-12s | %-11s | %-8s | %s\n", "Field", "Type", "Required", "Label")
+	for _, field := range view.Fields {
+		required := "no"
+		if field.Required {
+			required = "yes"
+		}
+		label := field.Label
+		if len(field.Choices) > 0 {
+			label += " (" + strings.Join(field.Choices, "|") + ")"
+		}
+		fmt.Fprintf(w, "%-12s | %-11s | %-8s | %s\n", field.Name, field.Type, required, label)
+	}
+}
+
+// registerCustomKinds rebuilds kindRegistry with the custom kinds defined in the config file
+func registerCustomKinds() error {
+	kindRegistry = kinds.NewRegistry()
+	definitions := cfg.Kinds()
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		kind, err := customKind(name, definitions[name])
+		if err == nil {
+			err = kindRegistry.Register(kind)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid kind %q in %s: %v", name, cfg.Path(), err)
+		}
+	}
+	return nil
+}
+
+// customKind builds a kind from its attributes in the config file
+func customKind(name string, attributes map[string]string) (kinds.Kind, error) {
+	fields, err := kinds.ParseFields(attributes["fields"])
+	if err != nil {
+		return kinds.Kind{}, err
+	}
+	return kinds.Kind{
+		Name:        name,
+		Description: attributes["description"],
+		Identifier:  kinds.Field{Name: "identifier", Label: cmp.Or(attributes["identifier"], "Identifier"), Type: kinds.Text},
+		Value:       kinds.Field{Name: "value", Label: cmp.Or(attributes["value"], "Value"), Type: kinds.FieldType(cmp.Or(attributes["value_type"], string(kinds.Secret))), Required: true},
+		Fields:      fields,
+		Generate:    attributes["generate"] == "true",
+		Custom:      true,
+	}, nil
+}
+
+// promptKind prompts the user to select the kind of a new entry using arrow keys
+func promptKind() (kinds.Kind, error) {
+	all := kindRegistry.Kinds()
+	prompt := promptui.Select{
+		Label: "Select the kind of entry",
+		Items: all,
+		Size:  10,
+		Templates: &promptui.SelectTemplates{
+			Active:   "▸ {{ .Name | cyan }}  {{ .Description | faint }}",
+			Inactive: "  {{ .Name }}  {{ .Description | faint }}",
+			Selected: "Kind: {{ .Name }}",
+		},
+	}
+
+	index, _, err := prompt.Run()
+	if err != nil {
+		return kinds.Kind{}, fmt.Errorf("prompt failed: %w", err)
+	}
+	return all[index], nil
+}
+
+// fieldPrompt returns the prompt for a field, noting whether it is optional and its default
+func fieldPrompt(field kinds.Field) string {
+	prompt := field.Label
+	if len(field.Choices) > 0 {
+		prompt += " (" + strings.Join(field.Choices, "/") + ")"
+	}
+	if !field.Required && field.Default == "" {
+		prompt += " (optional)"
+	}
+	if field.Default != "" {
+		prompt += " [" + field.Default + "]"
+	}
+	return prompt
+}
+
+// promptField prompts for the value of a field. Secret fields are read without echo, multiline
+// fields until an empty line. The field's default is returned when nothing is entered.
+func promptField(field kinds.Field, prompt string, hidden bool) (string, error) {
+	var value string
+	switch {
+	case field.Type == kinds.Multiline:
+		fmt.Printf("%s, ending with an empty line:\n", prompt)
+		value = readLines(os.Stdin)
+	case hidden:
+		var err error
+		if value, err = promptPassword(prompt + ": "); err != nil {
+			return "", err
+		}
+	default:
+		fmt.Print(prompt + ": ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimSpace(line)
+	}
+	if value == "" {
+		value = field.Default
+	}
+	return field.Normalize(value), nil
+}
+
+// readLines reads lines from r until an empty line or the end of input
+func readLines(r io.Reader) string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// promptSchemaFields prompts for the fields of kind that are not among fields and returns all fields,
+// with the kind's fields normalized, marked secret as the schema says and left out when empty
+func promptSchemaFields(kind kinds.Kind, fields []db.Field) ([]db.Field, error) {
+	for _, schemaField := range kind.Fields {
+		if slices.ContainsFunc(fields, func(f db.Field) bool { return strings.EqualFold(f.Name, schemaField.Name) }) {
+			continue
+		}
+		value, err := promptField(schemaField, fieldPrompt(schemaField), schemaField.IsSecret())
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, db.Field{Name: schemaField.Name, Value: value})
+	}
+	return applySchema(kind, fields), nil
+}
+
+// applySchema normalizes the fields of an entry that belong to kind's schema, naming them and
+// marking them secret as the schema does, and drops those left empty
+func applySchema(kind kinds.Kind, fields []db.Field) []db.Field {
+	var applied []db.Field
+	for _, field := range fields {
+		if schemaField, ok := kind.Field(field.Name); ok {
+			field.Name = schemaField.Name
+			field.Value = schemaField.Normalize(field.Value)
+			field.Secret = schemaField.IsSecret()
+			if field.Value == "" {
+				continue
+			}
+		}
+		applied = append(applied, field)
+	}
+	return applied
+}
+
+// validateEntry checks an entry's identifier, value and fields against the schema of kind
+func validateEntry(kind kinds.Kind, identifier, value string, fields []db.Field) error {
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		values[field.Name] = field.Value
+	}
+	return kind.Validate(identifier, value, values)
+}
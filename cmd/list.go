@@ -2,8 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"slices"
+	"sort"
 	"strings"
 	db "vault-cli/database"
+	"vault-cli/vault"
 
 	"github.com/spf13/cobra"
 )
@@ -11,65 +15,93 @@ import (
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all stored services and identifiers",
-	Long:  `List all services stored in the vault and their associated identifiers. You can filter by identifier type (e.g., username, email, api_key).`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// Get the id_type flag from the command
-		idType, _ := cmd.Flags().GetString("id-type")
-
-		// Check if the vault is locked
-		isLocked, err := db.GetVaultState()
-		if err != nil {
-			fmt.Println("Error retrieving vault state:", err)
-			return
+	Long:  `List all services stored in the vault and their associated identifiers. You can filter by kind (e.g., login, api_key, ssh_key) and by tag.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		// Get the kind flag from the command; --id-type is its old name
+		kindName, _ := cmd.Flags().GetString("kind")
+		if !cmd.Flags().Changed("kind") {
+			kindName, _ = cmd.Flags().GetString("id-type")
 		}
-
-		if isLocked {
-			fmt.Println("Error: Vault is locked. Please unlock the vault using `unlock`.")
-			return
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		if kindName != "" {
+			kind, err := kindRegistry.Lookup(kindName)
+			if err != nil {
+				return usageErrorf("%v", err)
+			}
+			kindName = kind.Name
 		}
 
-		// Fetch all sensitive data from the database, potentially filtering by id_type
-		entries, err := db.GetAllSensitiveData(idType)
+		keyring, err := vault.Keyring(store)
 		if err != nil {
-			fmt.Println("Error fetching sensitive data:", err)
-			return
+			return err
 		}
 
-		if len(entries) == 0 {
-			fmt.Println("No data found in the vault.")
-			return
-		}
-		fmt.Println("Stored Services and Identifiers:")
-		fmt.Println("--------------------------------")
-	
-		serviceMap := make(map[string][]db.SensitiveData)
-	
-		// Group entries by service
-		for _, entry := range entries {
-			serviceMap[entry.Service] = append(serviceMap[entry.Service], entry)
+		// Fetch all sensitive data from the database, potentially filtering by kind
+		entries, err := db.GetAllSensitiveData(store, keyring, kindName)
+		if err != nil {
+			return fmt.Errorf("error fetching sensitive data: %w", err)
 		}
-	
-		// Header with color
-		fmt.Printf("\033[1;37m%-20s | %-10s | %-30s\033[0m\n", "Service", "Type", "Identifier")
-		fmt.Println(strings.Repeat("-", 65))
-	
-		// Display grouped services with alternating colors
-		alternate := false
-		for service, entries := range serviceMap {
-			for _, entry := range entries {
-				// Switch row colors: Light Gray for odd rows, Normal for even rows
-				if alternate {
-					fmt.Printf("\033[0;37m%-20s | %-10s | %-30s\033[0m\n", service, entry.IdentifierType, entry.Identifier)
-				} else {
-					fmt.Printf("%-20s | %-10s | %-30s\n", service, entry.IdentifierType, entry.Identifier)
-				}
-				alternate = !alternate
+
+		// Keep only the entries carrying every requested tag
+		entries = slices.DeleteFunc(entries, func(entry db.SensitiveData) bool { return !entry.HasTags(tags) })
+
+		// Sort so every output format lists entries in a stable order
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Service != entries[j].Service {
+				return entries[i].Service < entries[j].Service
 			}
+			return entries[i].Identifier < entries[j].Identifier
+		})
+
+		views := make([]entryView, 0, len(entries))
+		for _, entry := range entries {
+			views = append(views, newEntryView(entry, false, false))
 		}
+		return render(cmd, views, func(w io.Writer, color bool) error {
+			printEntryTable(w, entries, color)
+			return nil
+		})
 	},
 }
 
+// printEntryTable prints entries grouped by service, alternating row colors when color is set
+func printEntryTable(w io.Writer, entries []db.SensitiveData, color bool) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No data found in the vault.")
+		return
+	}
+	fmt.Fprintln(w, "Stored Services and Identifiers:")
+	fmt.Fprintln(w, "--------------------------------")
+
+	// Header with color
+	if color {
+		fmt.Fprintf(w, "\033[1;37m%-20s | %-12s | %-30s | %s\033[0m\n", "Service", "Kind", "Identifier", "Tags")
+	} else {
+		fmt.Fprintf(w, "%-20s | %-12s | %-30s | %s\n", "Service", "Kind", "Identifier", "Tags")
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 74))
+
+	// Display services with alternating colors; entries are sorted, so each service is grouped
+	alternate := false
+	for _, entry := range entries {
+		tags := strings.Join(entry.TagList(), ", ")
+		// Switch row colors: Light Gray for odd rows, Normal for even rows
+		if alternate && color {
+			fmt.Fprintf(w, "\033[0;37m%-20s | %-12s | %-30s | %s\033[0m\n", entry.Service, entry.Kind, entry.Identifier, tags)
+		} else {
+			fmt.Fprintf(w, "%-20s | %-12s | %-30s | %s\n", entry.Service, entry.Kind, entry.Identifier, tags)
+		}
+		alternate = !alternate
+	}
+}
+
 func init() {
-	// Add the id-type flag to filter by identifier type
-	listCmd.Flags().StringP("id-type", "t", "", "Filter by identifier type (e.g., username, email, api_key)")
+	listCmd.Annotations = requiresDatabase
+
+	// Add the kind flag to filter by kind, keeping --id-type for scripts written before kinds existed
+	listCmd.Flags().StringP("kind", "k", "", "Filter by kind (e.g., login, api_key, ssh_key)")
+	listCmd.Flags().StringP("id-type", "t", "", "Filter by kind")
+	listCmd.Flags().MarkDeprecated("id-type", "use --kind instead")
+	listCmd.Flags().StringSlice("tag", nil, "Only list entries with this tag (repeatable; entries must have all given tags)")
 }
@@ -10,17 +10,20 @@ var lockCmd = &cobra.Command{
 	Use:   "lock",
 	Short: "Lock the vault",
 	Long:  `Lock the vault, preventing access to sensitive data until it is unlocked again.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
 		// Lock the vault
-		err := vault.LockVault()
+		err := vault.LockVault(store)
 		if err != nil {
-			fmt.Println("Error locking the vault:", err)
-			return
+			return fmt.Errorf("error locking the vault: %w", err)
 		}
+		return nil
 	},
 }
 
 func init() {
+	lockCmd.Annotations = usesExistingDatabase
+
 	// For now, locking the vault does not require any flags
 	// lockCmd.Flags().StringP("some-flag", "s", "", "Some description")
 	// lockCmd.MarkFlagRequired("some-flag") // Uncomment if needed
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	db "vault-cli/database"
+	"vault-cli/kinds"
+	"vault-cli/otp"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// otpView is the stable schema of a one-time code in machine-readable output
+type otpView struct {
+	Code      string  `json:"code"`
+	Type      string  `json:"type"`
+	ExpiresIn int     `json:"expires_in,omitempty"` // Seconds the TOTP code stays valid
+	Counter   *uint64 `json:"counter,omitempty"`    // Counter the HOTP code was generated from
+}
+
+var otpCmd = &cobra.Command{
+	Use:   "otp",
+	Short: "Print the current one-time code of an entry",
+	Long: `Print the current two-factor code of an entry with its seconds remaining. The seed is stored
+with --otp on add or update or imported with "otp import". The seed of a totp entry is its value.
+Counter-based (HOTP) seeds move to the next counter every time a code is printed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		service, _ := cmd.Flags().GetString("service")
+		identifier, _ := cmd.Flags().GetString("identifier")
+		clip, _ := cmd.Flags().GetBool("clip")
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+
+		// HOTP codes are only shown once the next counter is stored. The entry is read and written in
+		// one transaction, so that concurrent commands cannot both use the same counter.
+		var entry db.SensitiveData
+		var key otp.Key
+		err = store.Transaction(func(tx db.Store) error {
+			var err error
+			if entry, err = db.GetSensitiveData(tx, keyring, service, identifier); err != nil {
+				return fmt.Errorf("error retrieving data: %w", err)
+			}
+			var ok bool
+			if key, ok, err = entryOTPKey(entry); err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%s/%s has no one-time password seed", entry.Service, entry.Identifier)
+			}
+			if key.Type != otp.HOTP {
+				return nil
+			}
+			return advanceHOTPCounter(tx, keyring, entry, key)
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		code, err := key.Code(now)
+		if err != nil {
+			return err
+		}
+		view := otpView{Code: code, Type: string(key.Type)}
+		if key.Type == otp.HOTP {
+			counter := key.Counter
+			view.Counter = &counter
+		} else {
+			view.ExpiresIn = int(key.Remaining(now).Seconds())
+		}
+
+		if clip {
+			return copyToClipboard(cmd, code, fmt.Sprintf("the one-time code of %s/%s", entry.Service, entry.Identifier))
+		}
+		return render(cmd, view, func(w io.Writer, color bool) error {
+			fmt.Fprintln(w, describeOTPCode(view))
+			return nil
+		})
+	},
+}
+
+var otpImportCmd = &cobra.Command{
+	Use:   "import <uri>...",
+	Short: "Import one-time password seeds from otpauth:// or otpauth-migration:// URIs",
+	Long: `Import one-time password seeds from otpauth:// URIs or the otpauth-migration:// URIs exported
+by authenticator apps as QR codes. Pass "-" to read URIs from standard input, one per line.
+
+Each seed is attached to the entry whose service and identifier match its issuer and account name.
+Seeds without such an entry are stored as new totp entries. Entries that already have a seed are skipped.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		uris, err := otpURIs(args, os.Stdin)
+		if err != nil {
+			return err
+		}
+		var keys []otp.Key
+		for _, uri := range uris {
+			var parsed []otp.Key
+			if strings.HasPrefix(uri, "otpauth-migration:") {
+				parsed, err = otp.ParseMigrationURI(uri)
+			} else {
+				var key otp.Key
+				key, err = otp.ParseURI(uri)
+				parsed = []otp.Key{key}
+			}
+			if err != nil {
+				return usageErrorf("%v", err)
+			}
+			keys = append(keys, parsed...)
+		}
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+
+		// Import every seed or none of them
+		var report []string
+		err = store.Transaction(func(tx db.Store) error {
+			report = report[:0]
+			for _, key := range keys {
+				line, err := importOTPKey(tx, keyring, key)
+				if err != nil {
+					return err
+				}
+				report = append(report, line)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error importing one-time password seeds: %w", err)
+		}
+		for _, line := range report {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+func init() {
+	otpCmd.Annotations = requiresDatabase
+	otpImportCmd.Annotations = requiresDatabase
+	otpCmd.AddCommand(otpImportCmd)
+
+	otpCmd.Flags().StringP("service", "s", "", "Service name (required)")
+	otpCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	otpCmd.Flags().BoolP("clip", "c", false, "Copy the code to the clipboard instead of printing it")
+	otpCmd.MarkFlagRequired("service")
+	otpCmd.MarkFlagRequired("identifier")
+}
+
+// describeOTPCode formats a code with how long it is valid for, or the counter it was generated from
+func describeOTPCode(view otpView) string {
+	if view.Counter != nil {
+		return fmt.Sprintf("%s (counter %d)", view.Code, *view.Counter)
+	}
+	return fmt.Sprintf("%s (expires in %ds)", view.Code, view.ExpiresIn)
+}
+
+// entryOTPKey returns the one-time password key of a decrypted entry, stored in its details.
+// ok is false if the entry has none.
+func entryOTPKey(entry db.SensitiveData) (key otp.Key, ok bool, err error) {
+	if entry.Details.OTP == "" {
+		return otp.Key{}, false, nil
+	}
+	key, err = otp.ParseURI(entry.Details.OTP)
+	return key, true, err
+}
+
+// advanceHOTPCounter stores the counter following key's in the entry the key was read from
+func advanceHOTPCounter(tx db.Store, keyring db.Keyring, entry db.SensitiveData, key otp.Key) error {
+	details := entry.Details
+	key.Counter++
+	details.OTP = key.URI()
+	if err := db.SetEntryDetails(tx, keyring, entry.Service, entry.Identifier, details, entry.TagList()); err != nil {
+		return fmt.Errorf("error saving the HOTP counter: %w", err)
+	}
+	return nil
+}
+
+// totpSeed keeps the seed of a totp entry in its details, as for any other entry, and returns the value
+// of the entry: the base32 secret of the seed. A value other than that secret replaces the secret of
+// the seed, or becomes a TOTP seed with the default settings; an empty value keeps the seed as it is.
+func totpSeed(service, identifier, value string, details db.EntryDetails) (string, db.EntryDetails, error) {
+	var key otp.Key
+	if details.OTP != "" {
+		var err error
+		if key, err = otp.ParseURI(details.OTP); err != nil {
+			return "", details, err
+		}
+	}
+	switch {
+	case value == "" && details.OTP == "":
+		return "", details, fmt.Errorf("%s entries need a one-time password seed", kinds.TOTP)
+	case value == "" || otp.NormalizeSecret(value) == key.Secret:
+		return key.Secret, details, nil
+	case details.OTP == "":
+		var err error
+		if key, err = otp.NewKey(value); err != nil {
+			return "", details, err
+		}
+		key.Issuer, key.Account = service, identifier
+	default:
+		key.Secret = otp.NormalizeSecret(value)
+		if err := key.Validate(); err != nil {
+			return "", details, err
+		}
+	}
+	details.OTP = key.URI()
+	return key.Secret, details, nil
+}
+
+// parseOTPFlag reads the value of --otp: an otpauth:// URI or a base32 seed for TOTP codes with the
+// default settings, "-" to prompt for either without echo, or "" to remove the seed.
+// The URI of the key is returned.
+func parseOTPFlag(value string) (string, error) {
+	if value == "-" {
+		var err error
+		if value, err = promptPassword("One-time password seed or otpauth:// URI: "); err != nil {
+			return "", err
+		}
+	}
+	if value == "" {
+		return "", nil
+	}
+
+	var key otp.Key
+	var err error
+	if strings.HasPrefix(value, "otpauth:") {
+		key, err = otp.ParseURI(value)
+	} else {
+		key, err = otp.NewKey(value)
+	}
+	if err != nil {
+		return "", usageErrorf("invalid --otp: %v", err)
+	}
+	return key.URI(), nil
+}
+
+// otpURIs returns the URIs given as arguments, reading them from r for an argument of "-"
+func otpURIs(args []string, r io.Reader) ([]string, error) {
+	var uris []string
+	for _, arg := range args {
+		if arg != "-" {
+			uris = append(uris, strings.TrimSpace(arg))
+			continue
+		}
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024) // Migration URIs of large batches are long
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				uris = append(uris, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading URIs: %w", err)
+		}
+	}
+	return uris, nil
+}
+
+// importOTPKey attaches key to the entry matching its issuer and account, or adds it as a totp entry.
+// It returns a line describing what was done.
+func importOTPKey(s db.Store, keyring db.Keyring, key otp.Key) (string, error) {
+	service, identifier := key.Issuer, key.Account
+	if service == "" {
+		service = key.Account
+	}
+	if identifier == "" {
+		return "", fmt.Errorf("the seed issued by %q has no account name", key.Issuer)
+	}
+
+	entry, err := db.GetSensitiveData(s, keyring, service, identifier)
+	if errors.Is(err, db.ErrNotFound) {
+		details := db.EntryDetails{OTP: key.URI()}
+		if err := db.AddSensitiveDataWithDetails(s, keyring, service, identifier, key.Secret, kinds.TOTP, details, nil); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added %s/%s as a %s entry.", service, identifier, kinds.TOTP), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok, _ := entryOTPKey(entry); ok {
+		return fmt.Sprintf("Skipped %s/%s: it already has a one-time password seed.", entry.Service, entry.Identifier), nil
+	}
+	entry.Details.OTP = key.URI()
+	if err := db.SetEntryDetails(s, keyring, entry.Service, entry.Identifier, entry.Details, entry.TagList()); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Attached the seed to %s/%s.", entry.Service, entry.Identifier), nil
+}
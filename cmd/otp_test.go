@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	db "vault-cli/database"
+	"vault-cli/otp"
+)
+
+func TestTOTPSeed(t *testing.T) {
+	// A value alone becomes a TOTP seed with the default settings
+	value, details, err := totpSeed("github", "me", "jbsw y3dp ehpk 3pxp", db.EntryDetails{})
+	if err != nil {
+		t.Fatalf("totpSeed failed: %v", err)
+	}
+	key, err := otp.ParseURI(details.OTP)
+	if err != nil || value != "JBSWY3DPEHPK3PXP" || key.Secret != value || key.Issuer != "github" || key.Account != "me" {
+		t.Fatalf("expected a seed for the value, got %q, %+v, %v", value, key, err)
+	}
+
+	// An empty value keeps the seed, and a new value replaces its secret but not its settings
+	hotp := db.EntryDetails{OTP: "otpauth://hotp/GitHub:me?secret=JBSWY3DPEHPK3PXP&counter=5&digits=8"}
+	if value, details, err = totpSeed("github", "me", "", hotp); err != nil || value != "JBSWY3DPEHPK3PXP" || details.OTP != hotp.OTP {
+		t.Errorf("expected the seed to be kept, got %q, %q, %v", value, details.OTP, err)
+	}
+	if value, details, err = totpSeed("github", "me", "GEZDGNBVGY3TQOJQ", hotp); err != nil {
+		t.Fatalf("totpSeed failed: %v", err)
+	}
+	if key, err = otp.ParseURI(details.OTP); err != nil || key.Secret != value || key.Type != otp.HOTP || key.Counter != 5 || key.Digits != 8 {
+		t.Errorf("expected the secret to be replaced, got %+v, %v", key, err)
+	}
+
+	if _, _, err := totpSeed("github", "me", "", db.EntryDetails{}); err == nil {
+		t.Error("expected an error for a totp entry without a seed")
+	}
+}
+
+func TestAdvanceHOTPCounter(t *testing.T) {
+	store := db.NewMemoryStore("otp.db")
+	keyring := db.NewLocalKeyring(bytes.Repeat([]byte{7}, 32))
+	details := db.EntryDetails{OTP: "otpauth://hotp/GitHub:me?secret=JBSWY3DPEHPK3PXP&counter=5"}
+	if err := db.AddSensitiveDataWithDetails(store, keyring, "github", "me", "JBSWY3DPEHPK3PXP", "totp", details, nil); err != nil {
+		t.Fatalf("failed to add entry: %v", err)
+	}
+
+	entry, err := db.GetSensitiveData(store, keyring, "github", "me")
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	key, ok, err := entryOTPKey(entry)
+	if err != nil || !ok {
+		t.Fatalf("expected a seed, got %v, %v", ok, err)
+	}
+	if err := advanceHOTPCounter(store, keyring, entry, key); err != nil {
+		t.Fatalf("advanceHOTPCounter failed: %v", err)
+	}
+
+	entry, err = db.GetSensitiveData(store, keyring, "github", "me")
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	if key, _, err = entryOTPKey(entry); err != nil || key.Counter != 6 {
+		t.Errorf("expected the counter to be 6, got %d, %v", key.Counter, err)
+	}
+}
@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"vault-cli/config"
+	db "vault-cli/database"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Output formats accepted by --output and the output.format setting
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatEnv   = "env"
+)
+
+// entryView is the stable schema of an entry in machine-readable output.
+// Value and details are omitted by commands that do not reveal secrets, such as list.
+type entryView struct {
+	Service    string      `json:"service"`
+	Identifier string      `json:"identifier"`
+	Kind       string      `json:"kind"`
+	Value      string      `json:"value,omitempty"`
+	URLs       []string    `json:"urls,omitempty"`
+	Fields     []fieldView `json:"fields,omitempty"`
+	Notes      string      `json:"notes,omitempty"`
+	Tags       []string    `json:"tags,omitempty"`
+	OTPCode    string      `json:"otp_code,omitempty"` // Current TOTP code, set by get
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// fieldView is a custom field of an entry. The value of a secret field is masked unless revealed.
+type fieldView struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+// newEntryView converts a decrypted entry, leaving out its value and details unless withValue is set.
+// Secret fields are masked unless reveal is set.
+func newEntryView(entry db.SensitiveData, withValue, reveal bool) entryView {
+	view := entryView{
+		Service:    entry.Service,
+		Identifier: entry.Identifier,
+		Kind:       entry.Kind,
+		Tags:       entry.TagList(),
+		CreatedAt:  entry.CreatedAt.UTC().Truncate(time.Second),
+		UpdatedAt:  entry.UpdatedAt.UTC().Truncate(time.Second),
+	}
+	if withValue {
+		view.Value = entry.Value
+		view.URLs = entry.Details.URLs
+		view.Notes = entry.Details.Notes
+		for _, field := range entry.Details.Fields {
+			value := field.Value
+			if field.Secret && !reveal {
+				value = maskedValue
+			}
+			view.Fields = append(view.Fields, fieldView{Name: field.Name, Value: value, Secret: field.Secret})
+		}
+	}
+	return view
+}
+
+// outputFormat returns the format selected with --output or the output.format setting
+func outputFormat(cmd *cobra.Command) (string, error) {
+	format, err := stringSetting(cmd, "output", "output.format")
+	if err != nil {
+		return "", err
+	}
+	setting, err := config.Lookup("output.format")
+	if err != nil {
+		return "", err
+	}
+	if err := setting.Validate(format); err != nil {
+		return "", usageErrorf("invalid output format %q: expected one of %v", format, setting.Allowed)
+	}
+	return format, nil
+}
+
+// isTerminal reports whether w is a terminal, so tables can use colors
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// render writes v to the command's output in the selected format. The table format is
+// delegated to table, which is told whether the output is a terminal.
+func render(cmd *cobra.Command, v any, table func(w io.Writer, color bool) error) error {
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case formatYAML:
+		return writeYAML(w, v)
+	case formatEnv:
+		return writeEnv(w, v)
+	default:
+		return table(w, isTerminal(w))
+	}
+}
+
+// writeYAML writes v as a YAML document. Structs use their json field names and order.
+func writeYAML(w io.Writer, v any) error {
+	var b strings.Builder
+	value := reflect.ValueOf(v)
+	if isScalar(value) {
+		b.WriteString(yamlScalar(value))
+		b.WriteString("\n")
+	} else if isEmpty(value) {
+		b.WriteString(emptyCollection(value))
+		b.WriteString("\n")
+	} else {
+		writeYAMLValue(&b, value, 0)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeYAMLValue writes a mapping or sequence at the given indentation
+func writeYAMLValue(b *strings.Builder, v reflect.Value, indent int) {
+	v = indirect(v)
+	prefix := strings.Repeat("  ", indent)
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			item := indirect(v.Index(i))
+			if isScalar(item) || isEmpty(item) {
+				fmt.Fprintf(b, "%s- %s\n", prefix, yamlInline(item))
+				continue
+			}
+			// Write the first field on the "- " line and the rest indented under it
+			var nested strings.Builder
+			writeYAMLValue(&nested, item, indent+1)
+			lines := strings.SplitAfter(nested.String(), "\n")
+			b.WriteString(prefix + "- " + strings.TrimPrefix(lines[0], prefix+"  "))
+			for _, line := range lines[1:] {
+				b.WriteString(line)
+			}
+		}
+		return
+	}
+
+	for _, field := range fieldsOf(v) {
+		if isScalar(field.value) || isEmpty(field.value) {
+			fmt.Fprintf(b, "%s%s: %s\n", prefix, field.name, yamlInline(field.value))
+			continue
+		}
+		fmt.Fprintf(b, "%s%s:\n", prefix, field.name)
+		writeYAMLValue(b, field.value, indent+1)
+	}
+}
+
+// yamlInline renders a scalar or an empty collection on a single line
+func yamlInline(v reflect.Value) string {
+	if isScalar(v) {
+		return yamlScalar(v)
+	}
+	return emptyCollection(v)
+}
+
+// yamlScalar renders a scalar. Strings are always double-quoted, which YAML parses like JSON strings.
+func yamlScalar(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return "null"
+	}
+	s, _ := scalarString(v)
+	if v.Kind() != reflect.String && !isTime(v) {
+		return s
+	}
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// emptyCollection renders an empty sequence or mapping
+func emptyCollection(v reflect.Value) string {
+	v = indirect(v)
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return "[]"
+	}
+	return "{}"
+}
+
+// writeEnv writes v as KEY=value lines. Nested names are joined with "_" and sequences are
+// numbered from 0 with a _COUNT line; a top-level sequence uses the ITEM prefix, e.g.
+// ITEM_COUNT and ITEM_0_SERVICE. Values are single-quoted for POSIX shells.
+func writeEnv(w io.Writer, v any) error {
+	var lines []string
+	prefix := ""
+	if kind := indirect(reflect.ValueOf(v)).Kind(); kind == reflect.Slice || kind == reflect.Array {
+		prefix = "ITEM"
+	}
+	collectEnv(&lines, prefix, reflect.ValueOf(v))
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectEnv appends the KEY=value lines for v under prefix
+func collectEnv(lines *[]string, prefix string, v reflect.Value) {
+	v = indirect(v)
+	if isScalar(v) {
+		s, _ := scalarString(v)
+		*lines = append(*lines, prefix+"="+shellQuote(s))
+		return
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		*lines = append(*lines, envName(prefix, "count")+"="+strconv.Itoa(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			collectEnv(lines, envName(prefix, strconv.Itoa(i)), v.Index(i))
+		}
+		return
+	}
+	for _, field := range fieldsOf(v) {
+		collectEnv(lines, envName(prefix, field.name), field.value)
+	}
+}
+
+// envName joins prefix and name into an upper-case variable name
+func envName(prefix, name string) string {
+	name = strings.ToUpper(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name))
+	if prefix == "" {
+		return name
+	}
+	return prefix + "_" + name
+}
+
+// shellQuote single-quotes s so it can be evaluated by a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// namedValue is a field of a struct or an entry of a map
+type namedValue struct {
+	name  string
+	value reflect.Value
+}
+
+// fieldsOf lists the fields of a struct by json name, or the entries of a map by sorted key
+func fieldsOf(v reflect.Value) []namedValue {
+	var fields []namedValue
+	switch v.Kind() {
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			fields = append(fields, namedValue{name: key.String(), value: v.MapIndex(key)})
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			value := v.Field(i)
+			if strings.Contains(opts, "omitempty") && value.IsZero() {
+				continue
+			}
+			fields = append(fields, namedValue{name: name, value: value})
+		}
+	}
+	return fields
+}
+
+// indirect follows pointers and interfaces
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isTime reports whether v holds a time.Time
+func isTime(v reflect.Value) bool {
+	return v.IsValid() && v.Type() == reflect.TypeOf(time.Time{})
+}
+
+// isScalar reports whether v is rendered as a single value
+func isScalar(v reflect.Value) bool {
+	v = indirect(v)
+	if !v.IsValid() || isTime(v) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+// isEmpty reports whether v is a collection without elements
+func isEmpty(v reflect.Value) bool {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len() == 0
+	case reflect.Struct:
+		return !isTime(v) && len(fieldsOf(v)) == 0
+	}
+	return false
+}
+
+// scalarString formats a scalar value
+func scalarString(v reflect.Value) (string, bool) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return "", true
+	}
+	if isTime(v) {
+		return v.Interface().(time.Time).Format(time.RFC3339), true
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	}
+	return fmt.Sprint(v.Interface()), false
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testViews() []entryView {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []entryView{
+		{Service: "example.com", Identifier: "user@example.com", Kind: "login", CreatedAt: created, UpdatedAt: created},
+		{Service: "api", Identifier: "it's", Kind: "api_key", Value: "line\nbreak", CreatedAt: created, UpdatedAt: created},
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, testViews()); err != nil {
+		t.Fatalf("writeYAML failed: %v", err)
+	}
+
+	expected := `- service: "example.com"
+  identifier: "user@example.com"
+  kind: "login"
+  created_at: "2024-01-02T03:04:05Z"
+  updated_at: "2024-01-02T03:04:05Z"
+- service: "api"
+  identifier: "it's"
+  kind: "api_key"
+  value: "line\nbreak"
+  created_at: "2024-01-02T03:04:05Z"
+  updated_at: "2024-01-02T03:04:05Z"
+`
+	if buf.String() != expected {
+		t.Errorf("writeYAML() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := writeYAML(&buf, []entryView{}); err != nil {
+		t.Fatalf("writeYAML failed: %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("writeYAML() of an empty list = %q, want %q", buf.String(), "[]\n")
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeEnv(&buf, testViews()[1]); err != nil {
+		t.Fatalf("writeEnv failed: %v", err)
+	}
+
+	expected := `SERVICE='api'
+IDENTIFIER='it'\''s'
+KIND='api_key'
+VALUE='line
+break'
+CREATED_AT='2024-01-02T03:04:05Z'
+UPDATED_AT='2024-01-02T03:04:05Z'
+`
+	if buf.String() != expected {
+		t.Errorf("writeEnv() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	if err := writeEnv(&buf, testViews()); err != nil {
+		t.Fatalf("writeEnv failed: %v", err)
+	}
+	if lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")); string(lines[0]) != "ITEM_COUNT=2" || string(lines[1]) != "ITEM_0_SERVICE='example.com'" {
+		t.Errorf("Unexpected env output for a list:\n%s", buf.String())
+	}
+}
+
+func TestEntryViewJSONSchema(t *testing.T) {
+	data, err := json.Marshal(testViews()[0])
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	expected := `{"service":"example.com","identifier":"user@example.com","kind":"login","created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-02T03:04:05Z"}`
+	if string(data) != expected {
+		t.Errorf("json.Marshal() = %s, want %s", data, expected)
+	}
+}
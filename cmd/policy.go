@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	db "vault-cli/database"
+	"vault-cli/generator"
+
+	"github.com/spf13/cobra"
+)
+
+// policyCmd groups the commands that manage password policies
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage per-service password policies",
+	Long: `Manage the password policies stored in the vault.
+
+A policy describes the values a service accepts. add, update and rotate use it to generate
+values for that service, and values typed in for the service are checked against it.`,
+}
+
+var policySetCmd = &cobra.Command{
+	Use:   "set <service>",
+	Short: "Create or replace the password policy for a service",
+	Example: `  vault-cli policy set github --length 32 --symbols
+  vault-cli policy set mybank --length 16 --max-length 16 --no-symbols --require upper,lower,digit`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		length, _ := cmd.Flags().GetInt("length")
+		minLength, _ := cmd.Flags().GetInt("min-length")
+		maxLength, _ := cmd.Flags().GetInt("max-length")
+		charset, _ := cmd.Flags().GetString("charset")
+		requireNames, _ := cmd.Flags().GetStringSlice("require")
+		forbidNames, _ := cmd.Flags().GetStringSlice("forbid")
+		if symbols, _ := cmd.Flags().GetBool("symbols"); symbols {
+			requireNames = append(requireNames, string(generator.Symbol))
+		}
+		if noSymbols, _ := cmd.Flags().GetBool("no-symbols"); noSymbols {
+			forbidNames = append(forbidNames, string(generator.Symbol))
+		}
+
+		require, err := generator.ParseClasses(requireNames)
+		if err != nil {
+			return usageErrorf("%v", err)
+		}
+		forbid, err := generator.ParseClasses(forbidNames)
+		if err != nil {
+			return usageErrorf("%v", err)
+		}
+		policy := generator.Policy{
+			Length:    length,
+			MinLength: minLength,
+			MaxLength: maxLength,
+			Charset:   charset,
+			Require:   require,
+			Forbid:    forbid,
+		}
+		if err := policy.Validate(); err != nil {
+			return usageErrorf("%v", err)
+		}
+
+		// Make sure values can actually be generated under the policy
+		defaultLength, err := cfg.Int("generator.length")
+		if err != nil {
+			return err
+		}
+		defaultCharset, err := cfg.String("generator.charset")
+		if err != nil {
+			return err
+		}
+		if _, err := generator.Password(policy.Options(defaultLength, defaultCharset)); err != nil {
+			return usageErrorf("%v", err)
+		}
+
+		record := db.PasswordPolicy{
+			Service:   args[0],
+			Length:    length,
+			MinLength: minLength,
+			MaxLength: maxLength,
+			Charset:   charset,
+			Require:   joinClasses(require),
+			Forbid:    joinClasses(forbid),
+		}
+		if err := store.SetPolicy(&record); err != nil {
+			return fmt.Errorf("error saving password policy: %w", err)
+		}
+		fmt.Printf("Password policy for %s saved.\n", args[0])
+		return nil
+	},
+}
+
+var policyGetCmd = &cobra.Command{
+	Use:   "get <service>",
+	Short: "Show the password policy for a service",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		record, err := store.Policy(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Service:    %s\n", record.Service)
+		fmt.Printf("Length:     %s\n", describeLength(record.Length, "generator.length setting"))
+		fmt.Printf("Min length: %s\n", describeLength(record.MinLength, "none"))
+		fmt.Printf("Max length: %s\n", describeLength(record.MaxLength, "none"))
+		fmt.Printf("Charset:    %s\n", describeString(record.Charset, "generator.charset setting"))
+		fmt.Printf("Require:    %s\n", describeString(record.Require, "none"))
+		fmt.Printf("Forbid:     %s\n", describeString(record.Forbid, "none"))
+		return nil
+	},
+}
+
+var policyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all password policies",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		records, err := store.Policies()
+		if err != nil {
+			return fmt.Errorf("error fetching password policies: %w", err)
+		}
+		if len(records) == 0 {
+			fmt.Println("No password policies found in the vault.")
+			return nil
+		}
+
+		fmt.Printf("%-20s | %-6s | %-6s | %-6s | %-25s | %s\n", "Service", "Length", "Min", "Max", "Require", "Forbid")
+		fmt.Println(strings.Repeat("-", 85))
+		for _, record := range records {
+			fmt.Printf("%-20s | %-6s | %-6s | %-6s | %-25s | %s\n", record.Service,
+				describeLength(record.Length, "-"), describeLength(record.MinLength, "-"), describeLength(record.MaxLength, "-"),
+				describeString(record.Require, "-"), describeString(record.Forbid, "-"))
+		}
+		return nil
+	},
+}
+
+var policyDeleteCmd = &cobra.Command{
+	Use:   "delete <service>",
+	Short: "Delete the password policy for a service",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		if err := store.DeletePolicy(args[0]); err != nil {
+			return fmt.Errorf("error deleting password policy: %w", err)
+		}
+		fmt.Printf("Password policy for %s deleted.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	policyCmd.AddCommand(policySetCmd)
+	policyCmd.AddCommand(policyGetCmd)
+	policyCmd.AddCommand(policyListCmd)
+	policyCmd.AddCommand(policyDeleteCmd)
+	for _, sub := range policyCmd.Commands() {
+		sub.Annotations = requiresDatabase
+	}
+
+	policySetCmd.Flags().IntP("length", "l", 0, "Length of generated values (default from the generator.length setting)")
+	policySetCmd.Flags().Int("min-length", 0, "Shortest value the service accepts")
+	policySetCmd.Flags().Int("max-length", 0, "Longest value the service accepts")
+	policySetCmd.Flags().String("charset", "", "Characters generated values are drawn from (default from the generator.charset setting)")
+	policySetCmd.Flags().StringSlice("require", nil, "Character classes values must contain: upper, lower, digit, symbol")
+	policySetCmd.Flags().StringSlice("forbid", nil, "Character classes values must not contain: upper, lower, digit, symbol")
+	policySetCmd.Flags().Bool("symbols", false, "Require a symbol (same as --require symbol)")
+	policySetCmd.Flags().Bool("no-symbols", false, "Forbid symbols (same as --forbid symbol)")
+	policySetCmd.MarkFlagsMutuallyExclusive("symbols", "no-symbols")
+}
+
+// servicePolicy returns the password policy stored for service, or an empty policy if there is none
+func servicePolicy(store db.Store, service string) (generator.Policy, error) {
+	record, err := store.Policy(service)
+	if errors.Is(err, db.ErrNotFound) {
+		return generator.Policy{}, nil
+	}
+	if err != nil {
+		return generator.Policy{}, err
+	}
+
+	require, err := generator.ParseClasses(splitClasses(record.Require))
+	if err != nil {
+		return generator.Policy{}, fmt.Errorf("invalid password policy for %s: %w", service, err)
+	}
+	forbid, err := generator.ParseClasses(splitClasses(record.Forbid))
+	if err != nil {
+		return generator.Policy{}, fmt.Errorf("invalid password policy for %s: %w", service, err)
+	}
+	return generator.Policy{
+		Length:    record.Length,
+		MinLength: record.MinLength,
+		MaxLength: record.MaxLength,
+		Charset:   record.Charset,
+		Require:   require,
+		Forbid:    forbid,
+	}, nil
+}
+
+// generateForService generates a value following the password policy of service and the generator settings
+func generateForService(store db.Store, service string) (string, error) {
+	length, err := cfg.Int("generator.length")
+	if err != nil {
+		return "", err
+	}
+	charset, err := cfg.String("generator.charset")
+	if err != nil {
+		return "", err
+	}
+	policy, err := servicePolicy(store, service)
+	if err != nil {
+		return "", err
+	}
+	result, err := generator.Password(policy.Options(length, charset))
+	if err != nil {
+		return "", err
+	}
+	return result.Value, nil
+}
+
+// checkServicePolicy returns an error if value violates the password policy of service
+func checkServicePolicy(store db.Store, service, value string) error {
+	policy, err := servicePolicy(store, service)
+	if err != nil {
+		return err
+	}
+	if err := policy.Check(value); err != nil {
+		return fmt.Errorf("%s: %w", service, err)
+	}
+	return nil
+}
+
+func joinClasses(classes []generator.Class) string {
+	names := make([]string, len(classes))
+	for i, class := range classes {
+		names[i] = string(class)
+	}
+	return strings.Join(names, ",")
+}
+
+func splitClasses(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func describeLength(n int, unset string) string {
+	if n == 0 {
+		return unset
+	}
+	return fmt.Sprint(n)
+}
+
+func describeString(s, unset string) string {
+	if s == "" {
+		return unset
+	}
+	return s
+}
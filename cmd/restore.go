@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	db "vault-cli/database"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Roll an entry back to a previous value",
+	Long: `Replace the value of an entry with a version listed by history. The identifier is kept.
+The value being replaced is added to the history, so a restore can itself be undone.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		service, _ := cmd.Flags().GetString("service")
+		identifier, _ := cmd.Flags().GetString("identifier")
+		version, _ := cmd.Flags().GetInt("version")
+		if version < 1 {
+			return usageErrorf("--version must be a version listed by history")
+		}
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+
+		if err := db.RestoreSensitiveData(store, keyring, service, identifier, version); err != nil {
+			return fmt.Errorf("error restoring sensitive data: %w", err)
+		}
+		fmt.Printf("Restored version %d of %s/%s.\n", version, service, identifier)
+		return nil
+	},
+}
+
+func init() {
+	restoreCmd.Annotations = requiresDatabase
+
+	restoreCmd.Flags().StringP("service", "s", "", "Service name (required)")
+	restoreCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	restoreCmd.Flags().Int("version", 0, "Version to restore, as listed by history (required)")
+
+	restoreCmd.MarkFlagRequired("service")
+	restoreCmd.MarkFlagRequired("identifier")
+	restoreCmd.MarkFlagRequired("version")
+}
@@ -1,15 +1,131 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+
+	"vault-cli/config"
+	db "vault-cli/database"
+	"vault-cli/vault"
+
 	"github.com/spf13/cobra"
 )
 
+// cfg holds the settings loaded by the root command before any subcommand runs
+var cfg *config.Config
+
+// vaultPath is the vault file selected with --vault, $VAULT_CLI_PATH, the config file or the default vault
+var vaultPath string
+
+// storeKey is the context key of the vault opened by the root command
+type storeKey struct{}
+
+// commandStore returns the vault the root command opened for cmd. It is set for commands annotated
+// with requiresDatabase or usesExistingDatabase, and is nil for all others.
+func commandStore(cmd *cobra.Command) db.Store {
+	if cmd == nil || cmd.Context() == nil {
+		return nil
+	}
+	store, _ := cmd.Context().Value(storeKey{}).(db.Store)
+	return store
+}
+
+// withStore makes store the vault returned by commandStore for cmd
+func withStore(cmd *cobra.Command, store db.Store) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd.SetContext(context.WithValue(ctx, storeKey{}, store))
+}
+
+// requiresDatabase is set as the Annotations of commands that open the vault database,
+// creating it if needed. Other commands (generate, help, ...) never create a database file.
+var requiresDatabase = map[string]string{"database": "required"}
+
+// usesExistingDatabase is set as the Annotations of commands that only need the vault if it
+// exists, such as lock. An empty in-memory store stands in for a vault that was never created.
+var usesExistingDatabase = map[string]string{"database": "existing"}
+
+// commandStarted is set once cobra has parsed the command line and started running a command.
+// Errors returned before that point are usage errors (unknown command, bad arguments, ...).
+var commandStarted bool
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "vault",                           // The name of your command
 	Short: "A secure sensitive data manager", // Short description
 	Long:  `Vault is a secure sensitive data manager for storing and retrieving your sensitive data from the terminal.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Cobra checks required flags and flag groups only after this hook, so check them here to report a usage error
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return err
+		}
+		if err := cmd.ValidateFlagGroups(); err != nil {
+			return err
+		}
+		commandStarted = true
+
+		// Load the config file
+		configPath, _ := cmd.Flags().GetString("config")
+		if configPath == "" {
+			var err error
+			if configPath, err = config.DefaultPath(); err != nil {
+				return err
+			}
+		}
+		var err error
+		if cfg, err = config.Load(configPath); err != nil {
+			return err
+		}
+		// The config commands skip the settings they could be run to fix, so that a bad config file can be repaired
+		if !isConfigCommand(cmd) {
+			if err := applyKDFSettings(); err != nil {
+				return err
+			}
+			if err := applyHistorySettings(); err != nil {
+				return err
+			}
+			if err := registerCustomKinds(); err != nil {
+				return err
+			}
+		}
+
+		// Determine the vault file from --vault, $VAULT_CLI_PATH, the config file or the default vault
+		vaultName, _ := cmd.Flags().GetString("vault")
+		if vaultName == "" {
+			if vaultName, err = cfg.String("vault"); err != nil {
+				return err
+			}
+		}
+		if vaultPath, err = vault.Resolve(vaultName); err != nil {
+			return err
+		}
+
+		switch cmd.Annotations["database"] {
+		case "existing":
+			if _, err := os.Stat(vaultPath); errors.Is(err, os.ErrNotExist) {
+				withStore(cmd, db.NewMemoryStore(vaultPath))
+				return nil
+			}
+		case "required":
+			if err := vault.EnsureDir(vaultPath); err != nil {
+				return fmt.Errorf("could not create vault directory: %v", err)
+			}
+		default:
+			return nil
+		}
+
+		// Open the database
+		store, err := db.OpenSQLiteStore(vaultPath)
+		if err != nil {
+			return fmt.Errorf("could not initialize the database: %v", err)
+		}
+		withStore(cmd, store)
+		return purgeExpiredTrash(store)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Default action when no subcommands are provided
 		cmd.Help() // Show help if no subcommand is given
@@ -17,28 +133,71 @@ var rootCmd = &cobra.Command{
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// It prints any error to stderr and exits with the matching exit code.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		// Exit the program or handle error appropriately
+	cmd, err := rootCmd.ExecuteC()
+	if store := commandStore(cmd); store != nil {
+		store.Close()
+	}
+	if err == nil {
+		return
 	}
+
+	var child *childExitError
+	if errors.As(err, &child) {
+		os.Exit(child.code)
+	}
+	if !commandStarted {
+		err = &usageError{msg: err.Error()}
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+
+	var usage *usageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	case errors.Is(err, db.ErrIntegrity):
+		fmt.Fprintln(os.Stderr, "The stored entry could not be authenticated. Restore the vault from a backup if you did not expect this.")
+	}
+	os.Exit(exitCode(err))
 }
 
 func init() {
+	// Errors are printed by Execute, which also picks the exit code
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
 	// Here you can define flags and configuration settings.
-	// For example, adding a global flag:
-	// rootCmd.PersistentFlags().String("config", "", "config file (default is $HOME/.vault.yaml)")
+	rootCmd.PersistentFlags().String("config", "", "Config file (default $"+config.EnvConfigPath+" or ~/.config/vault-cli/config.toml)")
+	rootCmd.PersistentFlags().String("vault", "", "Vault name or database path (default $VAULT_CLI_PATH or the \"vault\" setting)")
+	rootCmd.PersistentFlags().String("output", "", "Output format: table, json, yaml or env (default from the output.format setting)")
 
 	// Add subcommands to rootCmd
 	rootCmd.AddCommand(setMasterCmd)
 	rootCmd.AddCommand(unlockCmd)
 	rootCmd.AddCommand(lockCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(deleteCmd)
+	rootCmd.AddCommand(trashCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(getCmd)
 	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(otpCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(injectCmd)
+	rootCmd.AddCommand(rotateCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(restoreCmd)
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(auditCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(vaultCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(policyCmd)
+	rootCmd.AddCommand(kindsCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(clipboardClearCmd)
 }
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	db "vault-cli/database"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandStore(t *testing.T) {
+	if store := commandStore(&cobra.Command{}); store != nil {
+		t.Errorf("expected no store for a command without one, got %v", store)
+	}
+
+	// Commands run against the store in their context, so a test can hand them a memory store
+	store := db.NewMemoryStore("root.db")
+	if err := store.SetPolicy(&db.PasswordPolicy{Service: "bank", MaxLength: 16}); err != nil {
+		t.Fatalf("SetPolicy failed: %v", err)
+	}
+	cmd := &cobra.Command{}
+	withStore(cmd, store)
+	if commandStore(cmd) != store {
+		t.Fatal("commandStore did not return the store set with withStore")
+	}
+	if err := policyDeleteCmd.RunE(cmd, []string{"bank"}); err != nil {
+		t.Fatalf("policy delete failed: %v", err)
+	}
+	if _, err := store.Policy("bank"); !errors.Is(err, db.ErrNotFound) {
+		t.Errorf("expected the policy to be deleted, got %v", err)
+	}
+}
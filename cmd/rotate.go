@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	db "vault-cli/database"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace the value of an entry with a newly generated password",
+	Long:  `Replace the value of an entry with a password generated according to the service's password policy and the generator settings.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		service, _ := cmd.Flags().GetString("service")
+		identifier, _ := cmd.Flags().GetString("identifier")
+		clip, _ := cmd.Flags().GetBool("clip")
+
+		keyring, err := vault.Keyring(store)
+		if err != nil {
+			return err
+		}
+
+		entry, err := store.Get(service, identifier)
+		if err != nil {
+			return fmt.Errorf("error rotating password: %w", err)
+		}
+		if kind := kindRegistry.Resolve(entry.Kind); !kind.Generate {
+			return fmt.Errorf("values of %s entries cannot be generated", kind.Name)
+		}
+
+		value, err := generateForService(store, entry.Service)
+		if err != nil {
+			return fmt.Errorf("error generating password: %w", err)
+		}
+		// The identifier is kept as stored, whatever its case in the flag
+		if err := db.UpdateSensitiveData(store, keyring, entry.Service, entry.Identifier, value, ""); err != nil {
+			return fmt.Errorf("error rotating password: %w", err)
+		}
+
+		fmt.Printf("Password for %s/%s rotated.\n", entry.Service, entry.Identifier)
+		return showGenerated(cmd, clip, value, entry.Service, entry.Identifier)
+	},
+}
+
+func init() {
+	rotateCmd.Annotations = requiresDatabase
+
+	rotateCmd.Flags().StringP("service", "s", "", "Service name (required)")
+	rotateCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	rotateCmd.Flags().BoolP("clip", "c", false, "Copy the new password to the clipboard instead of printing it")
+
+	rotateCmd.MarkFlagRequired("service")
+	rotateCmd.MarkFlagRequired("identifier")
+}
+
+// showGenerated prints a generated value, or copies it to the clipboard if clip is set
+func showGenerated(cmd *cobra.Command, clip bool, value, service, identifier string) error {
+	if clip {
+		return copyToClipboard(cmd, value, fmt.Sprintf("the new value of %s/%s", service, identifier))
+	}
+	fmt.Printf("New password for %s/%s: %s\n", service, identifier, value)
+	return nil
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	db "vault-cli/database"
+	"vault-cli/vault"
 	"fmt"
 	"github.com/spf13/cobra"
 )
@@ -11,40 +13,54 @@ var setMasterCmd = &cobra.Command{
 	Use:   "set-master",
 	Short: "Set or update the master password",
 	Long:  `Set or update the master password for accessing the password vault.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
 		masterPassword, _ := cmd.Flags().GetString("password")
-		isMasterPasswordSet := false
 
-		if err := db.CheckMasterPasswordSet(); err == nil {
-			oldMasterPassword, _ := cmd.Flags().GetString("old-password")
-			if oldMasterPassword == "" {
-				fmt.Println("Error: Master password already set. Old master password is required to change the master password. Use --old-password")
-				return
+		err := db.CheckMasterPasswordSet(store)
+		if errors.Is(err, db.ErrNoMasterPassword) {
+			// First-time setup
+			if err := db.SetMasterPassword(store, masterPassword); err != nil {
+				return fmt.Errorf("error setting master password: %w", err)
 			}
-			valid, err := db.VerifyMasterPassword(oldMasterPassword)
-			if err != nil {
-				fmt.Println("Error verifying old master password:", err)
-				return
-			}
-			if !valid {
-				fmt.Println("Invalid old master password. Please try again.")
-				return
+			fmt.Println("Master password set successfully.")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		oldMasterPassword, _ := cmd.Flags().GetString("old-password")
+		if oldMasterPassword == "" {
+			return usageErrorf("master password already set. Old master password is required to change the master password. Use --old-password")
+		}
+
+		oldKey, err := db.UnlockMasterKey(store, oldMasterPassword)
+		if err != nil {
+			return fmt.Errorf("error changing master password (no changes were made to the vault): %w", err)
+		}
+		// A running agent holds the old key. It is locked before re-encrypting, so that other
+		// commands cannot write values encrypted with the old key while the entries are changed.
+		if isLocked, err := vault.GetVaultState(store); err != nil || !isLocked {
+			if err := vault.LockVault(store); err != nil {
+				return fmt.Errorf("error locking the vault: %w", err)
 			}
-			isMasterPasswordSet = true
 		}
 
-		// Handle the logic to set the master password
-		err := db.SetMasterPassword(masterPassword, isMasterPasswordSet)
+		// Change the master password, re-encrypting every entry with the new key
+		err = db.ChangeMasterKey(store, oldKey, masterPassword, printProgress("Re-encrypting entries"))
 		if err != nil {
-			fmt.Println("Error setting master password:", err)
-			return
+			return fmt.Errorf("error changing master password (no changes were made to the vault): %w", err)
 		}
 
 		fmt.Println("Master password set successfully.")
+		return nil
 	},
 }
 
 func init() {
+	setMasterCmd.Annotations = requiresDatabase
+
 	setMasterCmd.Flags().StringP("password", "p", "", "New master password (required)")
 	setMasterCmd.Flags().StringP("old-password", "o", "", "Old master password (required if changing)")
 	setMasterCmd.MarkFlagRequired("password") // Make new password flag required
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the vault is unlocked and when it will lock",
+	Long:  `Show whether an unlock session is running and how much time remains before it locks automatically.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		status, ok, err := vault.Status(store)
+		if err != nil {
+			return fmt.Errorf("error retrieving vault state: %w", err)
+		}
+		if !ok {
+			fmt.Println("Vault is locked.")
+			return nil
+		}
+
+		now := time.Now()
+		fmt.Println("Vault is unlocked.")
+		fmt.Printf("Unlocked at:  %s\n", status.UnlockedAt.Format(time.DateTime))
+		fmt.Printf("Last access:  %s\n", status.LastAccess.Format(time.DateTime))
+		fmt.Printf("Idle timeout: %s\n", formatLimit(status.IdleTimeout))
+		fmt.Printf("Max lifetime: %s\n", formatLimit(status.MaxLifetime))
+
+		if remaining := status.Remaining(now); remaining >= 0 {
+			fmt.Printf("Locks in:     %s\n", remaining.Round(time.Second))
+		} else {
+			fmt.Println("Locks in:     never (run `lock` to lock the vault)")
+		}
+		return nil
+	},
+}
+
+// formatLimit renders a session limit, where zero means disabled
+func formatLimit(d time.Duration) string {
+	if d <= 0 {
+		return "disabled"
+	}
+	return d.String()
+}
+
+func init() {
+	statusCmd.Annotations = usesExistingDatabase
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// trashView is the stable schema of an entry in the trash in machine-readable output
+type trashView struct {
+	Service    string     `json:"service"`
+	Identifier string     `json:"identifier"`
+	Kind       string     `json:"kind"`
+	DeletedAt  time.Time  `json:"deleted_at"`
+	PurgeAt    *time.Time `json:"purge_at,omitempty"` // Unset if the trash is never purged automatically
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore or purge deleted entries",
+	Long: `Entries removed with delete are kept in the trash until they have been there for longer than the
+trash.retention setting, after which they are purged automatically.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the entries in the trash",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		if _, err := vault.Keyring(store); err != nil {
+			return err
+		}
+		retention, err := cfg.Duration("trash.retention")
+		if err != nil {
+			return err
+		}
+
+		entries, err := store.Trash()
+		if err != nil {
+			return err
+		}
+		views := make([]trashView, 0, len(entries))
+		for _, entry := range entries {
+			view := trashView{
+				Service:    entry.Service,
+				Identifier: entry.Identifier,
+				Kind:       entry.Kind,
+				DeletedAt:  entry.DeletedAt.Time.UTC().Truncate(time.Second),
+			}
+			if retention > 0 {
+				purgeAt := view.DeletedAt.Add(retention)
+				view.PurgeAt = &purgeAt
+			}
+			views = append(views, view)
+		}
+
+		return render(cmd, views, func(w io.Writer, color bool) error {
+			if len(views) == 0 {
+				fmt.Fprintln(w, "The trash is empty.")
+				return nil
+			}
+			fmt.Fprintf(w, "%-20s | %-30s | %-19s | %s\n", "Service", "Identifier", "Deleted", "Purged")
+			for _, view := range views {
+				purge := "never"
+				if view.PurgeAt != nil {
+					purge = view.PurgeAt.Local().Format(time.DateTime)
+				}
+				fmt.Fprintf(w, "%-20s | %-30s | %s | %s\n", view.Service, view.Identifier, view.DeletedAt.Local().Format(time.DateTime), purge)
+			}
+			return nil
+		})
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Move an entry out of the trash",
+	Long: `Move the most recently deleted entry for a service and identifier out of the trash, with its
+value and history. This fails if another entry has since been added under the same name.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		service, _ := cmd.Flags().GetString("service")
+		identifier, _ := cmd.Flags().GetString("identifier")
+		if _, err := vault.Keyring(store); err != nil {
+			return err
+		}
+
+		entry, err := store.RestoreTrashed(service, identifier)
+		if err != nil {
+			return fmt.Errorf("error restoring entry: %w", err)
+		}
+		fmt.Printf("Restored %s/%s from the trash.\n", entry.Service, entry.Identifier)
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete every entry in the trash",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		if _, err := vault.Keyring(store); err != nil {
+			return err
+		}
+
+		state, err := store.State()
+		if err != nil {
+			return err
+		}
+		if state.Trashed == 0 {
+			fmt.Println("The trash is empty.")
+			return nil
+		}
+		yes, _ := cmd.Flags().GetBool("yes")
+		if !yes && !confirm(fmt.Sprintf("Permanently delete %s in the trash? This cannot be undone.", countEntries(state.Trashed))) {
+			fmt.Println("Aborted.")
+			return nil
+		}
+
+		purged, err := store.PurgeTrash(time.Now())
+		if err != nil {
+			return fmt.Errorf("error emptying the trash: %w", err)
+		}
+		fmt.Printf("Permanently deleted %s.\n", countEntries(purged))
+		return nil
+	},
+}
+
+// countEntries returns "1 entry" or "n entries"
+func countEntries(n int) string {
+	if n == 1 {
+		return "1 entry"
+	}
+	return fmt.Sprintf("%d entries", n)
+}
+
+func init() {
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	for _, sub := range trashCmd.Commands() {
+		sub.Annotations = requiresDatabase
+	}
+
+	trashRestoreCmd.Flags().StringP("service", "s", "", "Service name (required)")
+	trashRestoreCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	trashRestoreCmd.MarkFlagRequired("service")
+	trashRestoreCmd.MarkFlagRequired("identifier")
+
+	trashEmptyCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+}
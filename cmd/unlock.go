@@ -2,9 +2,9 @@ package cmd
 
 import (
 	db "vault-cli/database" 
+	"vault-cli/agent"
 	"vault-cli/vault"
 	"fmt"
-	"log"
 	"golang.org/x/term" // This package allows for hidden password input
 	"syscall"
 	"github.com/spf13/cobra"
@@ -14,11 +14,20 @@ var unlockCmd = &cobra.Command{
 	Use:   "unlock",
 	Short: "Unlock the vault",
 	Long:  `Unlock the vault by providing the master password.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
+		idleTimeout, err := durationSetting(cmd, "timeout", "session.idle_timeout")
+		if err != nil {
+			return err
+		}
+		maxLifetime, err := durationSetting(cmd, "max-lifetime", "session.max_lifetime")
+		if err != nil {
+			return err
+		}
+
 		// Check if the master password is set
-		if err := db.CheckMasterPasswordSet(); err != nil {
-			fmt.Println("Error:", err)
-			return
+		if err := db.CheckMasterPasswordSet(store); err != nil {
+			return err
 		}
 
 		// Prompt for the password and hide input
@@ -27,33 +36,30 @@ var unlockCmd = &cobra.Command{
 		fmt.Println() // To move to the next line after password input
 
 		if err != nil {
-			log.Fatal("Error reading password:", err)
+			return fmt.Errorf("error reading password: %w", err)
 		}
 
 		password := string(passwordBytes)
 
-		// Verify the master password
-		valid, err := db.VerifyMasterPassword(password)
+		// Verify the master password, migrating legacy vaults to the derived key on first unlock
+		key, err := db.UnlockMasterKey(store, password)
 		if err != nil {
-			fmt.Println("Error verifying master password:", err)
-			return
-		}
-		if !valid {
-			fmt.Println("Invalid master password. Please try again.")
-			return
+			return fmt.Errorf("error verifying master password: %w", err)
 		}
 
-		// Unlock the vault
-		err = vault.UnlockVault()
+		// Unlock the vault by handing the key to the agent
+		err = vault.UnlockVault(store, key, agent.Options{IdleTimeout: idleTimeout, MaxLifetime: maxLifetime})
 		if err != nil {
-			fmt.Println("Error unlocking the vault:", err)
-			return
+			return fmt.Errorf("error unlocking the vault: %w", err)
 		}
-
-		fmt.Println("Vault unlocked successfully!")
+		return nil
 	},
 }
 
 func init() {
-	// No need for a password flag anymore since we're prompting interactively
+	unlockCmd.Annotations = requiresDatabase
+
+	// The password is prompted interactively; only the session limits are flags
+	unlockCmd.Flags().Duration("timeout", agent.DefaultIdleTimeout, "Lock automatically after this long without accessing secrets (0 disables; default from the session.idle_timeout setting)")
+	unlockCmd.Flags().Duration("max-lifetime", agent.DefaultMaxLifetime, "Lock automatically this long after unlocking (0 disables; default from the session.max_lifetime setting)")
 }
@@ -7,59 +7,125 @@ import (
 	"strings"
 
 	db "vault-cli/database"
+	"vault-cli/kinds"
+	"vault-cli/vault"
 	"github.com/spf13/cobra"
 )
 
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update a sensitive data entry in the vault",
-	Long:  `Update the value or identifier for a specific service in the vault.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Update the value or identifier for a specific service in the vault.
+The notes, URLs, custom fields and tags given with the flags below replace the current ones. When only
+those flags are given, the identifier and value are kept without prompting.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := commandStore(cmd)
 		service, _ := cmd.Flags().GetString("service")
 		identifier, _ := cmd.Flags().GetString("identifier")
+		generate, _ := cmd.Flags().GetBool("generate")
+		clip, _ := cmd.Flags().GetBool("clip")
+		if clip && !generate {
+			return usageErrorf("--clip can only be used with --generate")
+		}
+		detailsOnly := detailFlagsChanged(cmd) && !generate
 
-		// Check if the vault is locked
-		isLocked, err := db.GetVaultState()
+		keyring, err := vault.Keyring(store)
 		if err != nil {
-			fmt.Println("Error retrieving vault state:", err)
-			return
+			return err
 		}
 
-		if isLocked {
-			fmt.Println("Error: Vault is locked. Please unlock the vault using `unlock`.")
-			return
+		// Retrieve the existing sensitive data entry for this service and identifier
+		existingEntry, err := db.GetSensitiveData(store, keyring, service, identifier)
+		if err != nil {
+			return fmt.Errorf("error retrieving sensitive data: %w", err)
 		}
 
-		// Retrieve the existing sensitive data entry for this service and identifier
-		existingEntry, err := db.GetSensitiveData(service, identifier)
+		details, tags, err := detailsFromFlags(cmd, existingEntry.Details, existingEntry.TagList())
 		if err != nil {
-			fmt.Printf("Error retrieving sensitive data: %v\n", err)
-			return
+			return err
+		}
+		kind := kindRegistry.Resolve(existingEntry.Kind)
+		if generate && !kind.Generate {
+			return fmt.Errorf("values of %s entries cannot be generated", kind.Name)
 		}
+		details.Fields = applySchema(kind, details.Fields)
 
-		// Prompt for new identifier (if any)
-		newIdentifier := promptForInput(fmt.Sprintf("Enter new %s (leave empty to keep the current one): ", existingEntry.IdentifierType), existingEntry.Identifier)
+		// Prompt for new identifier and value (if any), unless only the details are being changed
+		newIdentifier, newValue := existingEntry.Identifier, existingEntry.Value
+		if !detailsOnly {
+			newIdentifier = promptForInput(fmt.Sprintf("Enter new %s (leave empty to keep the current one): ", strings.ToLower(kind.Identifier.Label)), existingEntry.Identifier)
 
-		// Prompt for new value (if any)
-		newValue := promptForInput("Enter new value (leave empty to keep the current one): ", existingEntry.Value)
+			// Generate a value following the service's policy if requested
+			if generate {
+				newValue, err = generateForService(store, service)
+				if err != nil {
+					return fmt.Errorf("error generating password: %w", err)
+				}
+			} else {
+				newValue, err = promptField(kind.Value, fmt.Sprintf("Enter new %s (leave empty to keep the current one)", strings.ToLower(kind.Value.Label)), false)
+				if err != nil {
+					return err
+				}
+				if newValue == "" {
+					newValue = existingEntry.Value
+				}
+				if newValue != existingEntry.Value && kind.Generate {
+					if err := checkServicePolicy(store, service, newValue); err != nil {
+						return err
+					}
+					warnAboutValue(newValue)
+				}
+			}
+		}
+		if kind.Name == kinds.TOTP {
+			// An unchanged value keeps the seed, which --otp may have replaced
+			typed := newValue
+			if typed == existingEntry.Value {
+				typed = ""
+			}
+			if newValue, details, err = totpSeed(service, newIdentifier, typed, details); err != nil {
+				return err
+			}
+		}
+		if err := validateEntry(kind, newIdentifier, newValue, details.Fields); err != nil {
+			return err
+		}
 
-		err = db.UpdateSensitiveData(service, identifier, newValue, newIdentifier)
+		// Change the value and the details together or not at all
+		err = store.Transaction(func(tx db.Store) error {
+			if err := db.UpdateSensitiveData(tx, keyring, service, identifier, newValue, newIdentifier); err != nil {
+				return fmt.Errorf("error updating sensitive data: %w", err)
+			}
+			if detailFlagsChanged(cmd) || kind.Name == kinds.TOTP {
+				if err := db.SetEntryDetails(tx, keyring, service, newIdentifier, details, tags); err != nil {
+					return fmt.Errorf("error updating entry details: %w", err)
+				}
+			}
+			return nil
+		})
 		if err != nil {
-			fmt.Printf("Error updating sensitive data: %v\n", err)
-			return
+			return err
 		}
 		fmt.Println("Sensitive data updated successfully.")
+		if generate {
+			return showGenerated(cmd, clip, newValue, service, newIdentifier)
+		}
+		return nil
 	},
 }
 
 func init() {
+	updateCmd.Annotations = requiresDatabase
+
 	// Define flags for the update command
 	updateCmd.Flags().StringP("service", "s", "", "Service name (required)")
 	updateCmd.Flags().StringP("identifier", "i", "", "Identifier (required)")
+	updateCmd.Flags().BoolP("generate", "g", false, "Generate a new value following the service's password policy instead of prompting for one")
+	updateCmd.Flags().BoolP("clip", "c", false, "Copy the generated value to the clipboard instead of printing it")
+	addDetailFlags(updateCmd)
 
 	updateCmd.MarkFlagRequired("service")
 	updateCmd.MarkFlagRequired("identifier")
-
 }
 
 // promptForInput prompts the user for a new value, or keeps the existing one if the input is empty
@@ -1,17 +1,103 @@
 package cmd
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	db "vault-cli/database"
+
+	"github.com/spf13/cobra"
 )
 
-// GenerateRandomPassword generates a secure random password
-func generateRandomPassword(length int) (string, error) {
-	bytes := make([]byte, length)
-	_, err := rand.Read(bytes)
+// printProgress returns a callback that renders a single-line progress counter
+func printProgress(label string) func(done, total int) {
+	return func(done, total int) {
+		fmt.Printf("\r%s: %d/%d", label, done, total)
+		if done == total {
+			fmt.Println()
+		}
+	}
+}
+
+// intSetting returns the value of an int flag if it was given, otherwise the config setting
+func intSetting(cmd *cobra.Command, flag, key string) (int, error) {
+	if cmd.Flags().Changed(flag) {
+		return cmd.Flags().GetInt(flag)
+	}
+	return cfg.Int(key)
+}
+
+// stringSetting returns the value of a string flag if it was given, otherwise the config setting
+func stringSetting(cmd *cobra.Command, flag, key string) (string, error) {
+	if cmd.Flags().Changed(flag) {
+		return cmd.Flags().GetString(flag)
+	}
+	return cfg.String(key)
+}
+
+// durationSetting returns the value of a duration flag if it was given, otherwise the config setting
+func durationSetting(cmd *cobra.Command, flag, key string) (time.Duration, error) {
+	if cmd.Flags().Changed(flag) {
+		return cmd.Flags().GetDuration(flag)
+	}
+	return cfg.Duration(key)
+}
+
+// applyKDFSettings sets the KDF cost used for new master passwords from the config
+func applyKDFSettings() error {
+	kdfTime, err := cfg.Int("kdf.time")
+	if err != nil {
+		return err
+	}
+	kdfMemory, err := cfg.Int("kdf.memory")
 	if err != nil {
-		return "", err
+		return err
 	}
-	// Use base64 encoding to generate a readable password
-	return base64.RawStdEncoding.EncodeToString(bytes)[:length], nil
+	kdfThreads, err := cfg.Int("kdf.threads")
+	if err != nil {
+		return err
+	}
+	// The config settings bound each value, which keeps the conversions below in range
+	params := db.KDFParams{Time: uint32(kdfTime), Memory: uint32(kdfMemory), Threads: uint8(kdfThreads)}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	db.DefaultKDFParams = params
+	return nil
+}
+
+// applyHistorySettings sets how many previous values are kept per entry from the config
+func applyHistorySettings() error {
+	retention, err := cfg.Int("history.retention")
+	if err != nil {
+		return err
+	}
+	db.HistoryRetention = retention
+	return nil
+}
+
+// purgeExpiredTrash permanently deletes entries that have been in the trash for longer than the trash.retention setting
+func purgeExpiredTrash(store db.Store) error {
+	retention, err := cfg.Duration("trash.retention")
+	if err != nil {
+		return err
+	}
+	if retention <= 0 {
+		return nil
+	}
+	if _, err := store.PurgeTrash(time.Now().Add(-retention)); err != nil {
+		return fmt.Errorf("error purging the trash: %w", err)
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on stdin and reports whether it was answered with yes
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
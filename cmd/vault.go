@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	db "vault-cli/database"
+	"vault-cli/vault"
+
+	"github.com/spf13/cobra"
+)
+
+// vaultCmd groups the commands that manage named vaults
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Manage named vaults",
+	Long:  `Create, select and list named vaults. Each vault is a separate database with its own master password and lock state.`,
+}
+
+var vaultCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new named vault",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		path, err := vault.Create(name)
+		if err != nil {
+			return fmt.Errorf("error creating vault: %w", err)
+		}
+		created, err := db.OpenSQLiteStore(path)
+		if err != nil {
+			return fmt.Errorf("error initializing vault: %w", err)
+		}
+		created.Close()
+
+		fmt.Printf("Vault '%s' created at %s.\n", name, path)
+		fmt.Printf("Set its master password with `vault-cli --vault %s set-master`.\n", name)
+		return nil
+	},
+}
+
+var vaultUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select the vault used by default",
+	Long:  `Select the named vault used when neither --vault nor $VAULT_CLI_PATH is set. This sets the "vault" config setting.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := vault.Exists(args[0]); err != nil {
+			return fmt.Errorf("error selecting vault: %w", err)
+		}
+		if err := cfg.Set("vault", args[0]); err != nil {
+			return fmt.Errorf("error selecting vault: %w", err)
+		}
+		fmt.Printf("Now using vault '%s'.\n", args[0])
+		return nil
+	},
+}
+
+var vaultListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List named vaults",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaults, err := vault.List(vaultPath)
+		if err != nil {
+			return fmt.Errorf("error listing vaults: %w", err)
+		}
+		if len(vaults) == 0 {
+			fmt.Println("No named vaults found. Create one with `vault-cli vault create <name>`.")
+			return nil
+		}
+
+		for _, v := range vaults {
+			marker := " "
+			if v.Current {
+				marker = "*"
+			}
+			state := "locked"
+			if vault.IsUnlocked(v.Path) {
+				state = "unlocked"
+			}
+			fmt.Printf("%s %-20s %-8s %s\n", marker, v.Name, state, v.Path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	vaultCmd.AddCommand(vaultCreateCmd)
+	vaultCmd.AddCommand(vaultUseCmd)
+	vaultCmd.AddCommand(vaultListCmd)
+}
@@ -0,0 +1,340 @@
+// Package config loads vault-cli settings from the config file and environment.
+//
+// Settings are resolved in order of precedence: command-line flags (applied by
+// the caller), environment variables, the config file, and built-in defaults.
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvConfigPath overrides the location of the config file
+const EnvConfigPath = "VAULT_CLI_CONFIG"
+
+// Kind is the type of a setting's value
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindInt
+	KindDuration
+)
+
+// Setting describes a configurable value
+type Setting struct {
+	Key         string
+	Env         string
+	Kind        Kind
+	Default     string
+	Description string
+	Allowed     []string // If set, the value must be one of these
+	Min, Max    string   // If set, the bounds of an integer or duration value
+}
+
+// Settings lists every supported setting
+var Settings = []Setting{
+	{Key: "vault", Env: "VAULT_CLI_PATH", Kind: KindString, Description: "Vault name or database path used when --vault is not given"},
+	{Key: "generator.length", Env: "VAULT_CLI_GENERATOR_LENGTH", Kind: KindInt, Default: "12", Description: "Length of generated passwords", Min: "1"},
+	{Key: "generator.charset", Env: "VAULT_CLI_GENERATOR_CHARSET", Kind: KindString, Default: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/", Description: "Characters used in generated passwords"},
+	{Key: "audit.min_score", Env: "VAULT_CLI_AUDIT_MIN_SCORE", Kind: KindInt, Default: "3", Description: "Strength score (0-4) below which values are reported as weak", Allowed: []string{"0", "1", "2", "3", "4"}},
+	{Key: "audit.max_age", Env: "VAULT_CLI_AUDIT_MAX_AGE", Kind: KindDuration, Default: "8760h", Description: "Values not changed for this long are reported as old (0 disables)", Min: "0"},
+	{Key: "audit.breach_db", Env: "VAULT_CLI_BREACH_DB", Kind: KindString, Description: "Local Pwned Passwords file (SHA-1, ordered by hash) checked by audit and add"},
+	{Key: "history.retention", Env: "VAULT_CLI_HISTORY_RETENTION", Kind: KindInt, Default: "10", Description: "Previous values kept per entry for history and restore (0 disables)", Min: "0"},
+	{Key: "trash.retention", Env: "VAULT_CLI_TRASH_RETENTION", Kind: KindDuration, Default: "720h", Description: "Deleted entries are purged after this long in the trash (0 keeps them until emptied)", Min: "0"},
+	{Key: "clipboard.timeout", Env: "VAULT_CLI_CLIPBOARD_TIMEOUT", Kind: KindDuration, Default: "45s", Description: "How long copied secrets stay on the clipboard (0 disables clearing)", Min: "0"},
+	{Key: "clipboard.backend", Env: "VAULT_CLI_CLIPBOARD_BACKEND", Kind: KindString, Default: "auto", Description: "Clipboard used by --clip", Allowed: []string{"auto", "wl-copy", "xclip", "xsel", "pbcopy", "osc52"}},
+	{Key: "output.format", Env: "VAULT_CLI_OUTPUT_FORMAT", Kind: KindString, Default: "table", Description: "Default output format for get, list and audit", Allowed: []string{"table", "json", "yaml", "env"}},
+	{Key: "session.idle_timeout", Env: "VAULT_CLI_SESSION_IDLE_TIMEOUT", Kind: KindDuration, Default: "15m", Description: "Lock after this long without accessing secrets (0 disables)", Min: "0"},
+	{Key: "session.max_lifetime", Env: "VAULT_CLI_SESSION_MAX_LIFETIME", Kind: KindDuration, Default: "8h", Description: "Lock this long after unlocking (0 disables)", Min: "0"},
+	{Key: "kdf.time", Env: "VAULT_CLI_KDF_TIME", Kind: KindInt, Default: "3", Description: "Argon2id passes used when setting a master password", Min: "1", Max: "4294967295"},
+	{Key: "kdf.memory", Env: "VAULT_CLI_KDF_MEMORY", Kind: KindInt, Default: "65536", Description: "Argon2id memory in KiB used when setting a master password", Min: "2048", Max: "4294967295"},
+	{Key: "kdf.threads", Env: "VAULT_CLI_KDF_THREADS", Kind: KindInt, Default: "4", Description: "Argon2id parallelism used when setting a master password", Min: "1", Max: "255"},
+}
+
+// KindsTable is the table holding custom entry kinds, one [kinds.<name>] table per kind
+const KindsTable = "kinds"
+
+// KindAttributes lists the keys a custom entry kind can set
+var KindAttributes = map[string]string{
+	"description": "What entries of the kind hold",
+	"identifier":  "Label of the identifier, such as Username",
+	"value":       "Label of the value, such as Password",
+	"value_type":  "Field type of the value (default secret)",
+	"fields":      "Extra fields as name[:type][!], separated by commas",
+	"generate":    "Whether empty values are generated (true or false)",
+}
+
+// Sources a setting's value can come from
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// Config holds the settings read from the config file
+type Config struct {
+	path   string
+	values map[string]string
+}
+
+// DefaultPath returns $VAULT_CLI_CONFIG, or config.toml under $XDG_CONFIG_HOME/vault-cli
+// (~/.config/vault-cli when XDG_CONFIG_HOME is not set)
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not get user home directory: %v", err)
+		}
+		dir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(dir, "vault-cli", "config.toml"), nil
+}
+
+// Load reads the config file at path. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{path: path, values: make(map[string]string)}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %v", err)
+	}
+	defer file.Close()
+
+	values, err := parseTOML(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	for key, value := range values {
+		if _, err := Lookup(key); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+		cfg.values[key] = value
+	}
+	return cfg, nil
+}
+
+// Path returns the location of the config file
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Lookup returns the definition of the setting named key, including attributes of custom kinds
+func Lookup(key string) (Setting, error) {
+	for _, setting := range Settings {
+		if setting.Key == key {
+			return setting, nil
+		}
+	}
+	if setting, ok := kindSetting(key); ok {
+		return setting, nil
+	}
+	return Setting{}, fmt.Errorf("unknown setting %q", key)
+}
+
+// kindSetting describes key if it is an attribute of a custom kind, such as kinds.wifi.fields
+func kindSetting(key string) (Setting, bool) {
+	rest, ok := strings.CutPrefix(key, KindsTable+".")
+	if !ok {
+		return Setting{}, false
+	}
+	name, attribute, ok := strings.Cut(rest, ".")
+	description, known := KindAttributes[attribute]
+	if !ok || !isBareKey(name) || !known {
+		return Setting{}, false
+	}
+	setting := Setting{Key: key, Kind: KindString, Description: description}
+	if attribute == "generate" {
+		setting.Allowed = []string{"true", "false"}
+	}
+	return setting, true
+}
+
+// Kinds returns the attributes of the custom kinds defined in the config file, keyed by kind name
+func (c *Config) Kinds() map[string]map[string]string {
+	kinds := make(map[string]map[string]string)
+	for key, value := range c.values {
+		if _, ok := kindSetting(key); !ok {
+			continue
+		}
+		name, attribute, _ := strings.Cut(strings.TrimPrefix(key, KindsTable+"."), ".")
+		if kinds[name] == nil {
+			kinds[name] = make(map[string]string)
+		}
+		kinds[name][attribute] = value
+	}
+	return kinds
+}
+
+// Get returns the effective value of key and where it came from
+func (c *Config) Get(key string) (value, source string, err error) {
+	setting, err := Lookup(key)
+	if err != nil {
+		return "", "", err
+	}
+	if value, ok := os.LookupEnv(setting.Env); ok && value != "" {
+		return value, SourceEnv, nil
+	}
+	if value, ok := c.values[key]; ok {
+		return value, SourceFile, nil
+	}
+	return setting.Default, SourceDefault, nil
+}
+
+// String returns the effective value of a string setting
+func (c *Config) String(key string) (string, error) {
+	value, _, err := c.Get(key)
+	if err != nil {
+		return "", err
+	}
+	return value, c.validate(key, value)
+}
+
+// Int returns the effective value of an integer setting
+func (c *Config) Int(key string) (int, error) {
+	value, _, err := c.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.validate(key, value); err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// Duration returns the effective value of a duration setting
+func (c *Config) Duration(key string) (time.Duration, error) {
+	value, _, err := c.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.validate(key, value); err != nil {
+		return 0, err
+	}
+	return time.ParseDuration(value)
+}
+
+// validate checks value against the setting's kind and allowed values
+func (c *Config) validate(key, value string) error {
+	setting, err := Lookup(key)
+	if err != nil {
+		return err
+	}
+	return setting.Validate(value)
+}
+
+// Validate checks that value is acceptable for the setting
+func (s Setting) Validate(value string) error {
+	switch s.Kind {
+	case KindInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s: expected an integer", value, s.Key)
+		}
+		if err := s.checkRange(value, func(bound string) int {
+			b, _ := strconv.Atoi(bound)
+			return cmp.Compare(n, b)
+		}); err != nil {
+			return err
+		}
+	case KindDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for %s: expected a duration such as 30s or 15m", value, s.Key)
+		}
+		if err := s.checkRange(value, func(bound string) int {
+			b, _ := time.ParseDuration(bound)
+			return cmp.Compare(d, b)
+		}); err != nil {
+			return err
+		}
+	}
+	if len(s.Allowed) > 0 {
+		for _, allowed := range s.Allowed {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid value %q for %s: expected one of %v", value, s.Key, s.Allowed)
+	}
+	return nil
+}
+
+// checkRange checks value against the setting's bounds. compare returns -1, 0 or +1 as the
+// value is less than, equal to or greater than a bound.
+func (s Setting) checkRange(value string, compare func(bound string) int) error {
+	tooLow := s.Min != "" && compare(s.Min) < 0
+	tooHigh := s.Max != "" && compare(s.Max) > 0
+	switch {
+	case (tooLow || tooHigh) && s.Min != "" && s.Max != "":
+		return fmt.Errorf("invalid value %q for %s: expected %s to %s", value, s.Key, s.Min, s.Max)
+	case tooLow:
+		return fmt.Errorf("invalid value %q for %s: expected at least %s", value, s.Key, s.Min)
+	case tooHigh:
+		return fmt.Errorf("invalid value %q for %s: expected at most %s", value, s.Key, s.Max)
+	}
+	return nil
+}
+
+// Set validates value, stores it under key and writes the config file
+func (c *Config) Set(key, value string) error {
+	setting, err := Lookup(key)
+	if err != nil {
+		return err
+	}
+	if err := setting.Validate(value); err != nil {
+		return err
+	}
+	c.values[key] = value
+	return c.save()
+}
+
+// Unset removes key from the config file so its default applies again
+func (c *Config) Unset(key string) error {
+	if _, err := Lookup(key); err != nil {
+		return err
+	}
+	delete(c.values, key)
+	return c.save()
+}
+
+// Keys returns all setting keys in sorted order
+func Keys() []string {
+	keys := make([]string, 0, len(Settings))
+	for _, setting := range Settings {
+		keys = append(keys, setting.Key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// save writes the config file with owner-only permissions
+func (c *Config) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	file, err := os.OpenFile(c.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, "# vault-cli configuration. Manage with `vault-cli config set <key> <value>`."); err != nil {
+		return err
+	}
+	return writeTOML(file, c.values)
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTOML(t *testing.T) {
+	input := `# comment
+vault = "work" # trailing comment
+
+[generator]
+length = 20
+charset = "abc#\"def"
+
+[session]
+idle_timeout = "5m"
+`
+	values, err := parseTOML(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	expected := map[string]string{
+		"vault":                "work",
+		"generator.length":     "20",
+		"generator.charset":    `abc#"def`,
+		"session.idle_timeout": "5m",
+	}
+	for key, want := range expected {
+		if got := values[key]; got != want {
+			t.Errorf("values[%q] = %q, want %q", key, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"[generator", "length", "length = abc", "bad key = 1"} {
+		if _, err := parseTOML(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	t.Setenv("VAULT_CLI_GENERATOR_LENGTH", "")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load missing config: %v", err)
+	}
+
+	// Built-in default
+	if length, _ := cfg.Int("generator.length"); length != 12 {
+		t.Errorf("expected default length 12, got %d", length)
+	}
+
+	// Config file
+	if err := cfg.Set("generator.length", "20"); err != nil {
+		t.Fatalf("failed to set: %v", err)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if length, _ := reloaded.Int("generator.length"); length != 20 {
+		t.Errorf("expected length 20 from file, got %d", length)
+	}
+
+	// Environment overrides the file
+	t.Setenv("VAULT_CLI_GENERATOR_LENGTH", "30")
+	value, source, _ := reloaded.Get("generator.length")
+	if value != "30" || source != SourceEnv {
+		t.Errorf("expected 30 from env, got %q from %s", value, source)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat config: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected config permissions 0600, got %o", perm)
+	}
+}
+
+func TestSetValidates(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "config.toml"))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if err := cfg.Set("generator.length", "abc"); err == nil {
+		t.Error("expected error for non-integer length")
+	}
+	if err := cfg.Set("session.idle_timeout", "soon"); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+	if err := cfg.Set("output.format", "xml"); err == nil {
+		t.Error("expected error for unsupported output format")
+	}
+	if err := cfg.Set("no.such.key", "1"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+	for _, setting := range [][2]string{{"kdf.threads", "0"}, {"kdf.threads", "256"}, {"kdf.memory", "1"}, {"kdf.time", "0"}, {"history.retention", "-1"}, {"clipboard.timeout", "-1s"}} {
+		if err := cfg.Set(setting[0], setting[1]); err == nil {
+			t.Errorf("expected error for %s = %s, which is out of range", setting[0], setting[1])
+		}
+	}
+	if err := cfg.Set("kdf.threads", "255"); err != nil {
+		t.Errorf("failed to set the largest parallelism: %v", err)
+	}
+
+	if err := cfg.Set("session.idle_timeout", "30m"); err != nil {
+		t.Fatalf("failed to set duration: %v", err)
+	}
+	if timeout, _ := cfg.Duration("session.idle_timeout"); timeout != 30*time.Minute {
+		t.Errorf("expected 30m, got %v", timeout)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[generator]\nlenght = 20\n"), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for misspelled key")
+	}
+}
+
+func TestCustomKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	input := `[kinds.wifi]
+description = "Wi-Fi network"
+identifier = "Network name"
+fields = "security:choice(WPA2|WPA3)!"
+`
+	if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	wifi := cfg.Kinds()["wifi"]
+	if wifi["identifier"] != "Network name" || wifi["fields"] != "security:choice(WPA2|WPA3)!" {
+		t.Errorf("unexpected kind attributes %v", wifi)
+	}
+
+	// Kinds can be managed with Set and survive a round trip through the file
+	if err := cfg.Set("kinds.wifi.generate", "maybe"); err == nil {
+		t.Error("expected error for a non-boolean generate attribute")
+	}
+	if err := cfg.Set("kinds.wifi.generate", "true"); err != nil {
+		t.Fatalf("failed to set kind attribute: %v", err)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if reloaded.Kinds()["wifi"]["generate"] != "true" || reloaded.Kinds()["wifi"]["description"] != "Wi-Fi network" {
+		t.Errorf("unexpected kind attributes after reload %v", reloaded.Kinds()["wifi"])
+	}
+
+	for _, invalid := range []string{"[kinds.wifi]\nlabel = \"x\"\n", "[kinds]\nwifi = \"x\"\n", "[kinds.]\nvalue = \"x\"\n"} {
+		if err := os.WriteFile(path, []byte(invalid), 0600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected error loading %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads the subset of TOML used by the config file: [tables] and [dotted.tables],
+// bare keys, and string, integer or boolean values. Keys are returned as "table.key".
+func parseTOML(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	table := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineNumber, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			for _, part := range strings.Split(table, ".") {
+				if !isBareKey(strings.TrimSpace(part)) {
+					return nil, fmt.Errorf("line %d: invalid table name %q", lineNumber, table)
+				}
+			}
+			table = strings.ReplaceAll(table, " ", "")
+			continue
+		}
+
+		key, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		if !isBareKey(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineNumber, key)
+		}
+
+		value, err := parseValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+
+		if table != "" {
+			key = table + "." + key
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// stripComment removes a trailing # comment that is not inside a string
+func stripComment(line string) string {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case '#':
+			if !inString {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// parseValue converts a TOML string, integer or boolean literal to its string form
+func parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case raw == "true" || raw == "false":
+		return raw, nil
+	default:
+		if _, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64); err != nil {
+			return "", fmt.Errorf("unsupported value %s", raw)
+		}
+		return strings.ReplaceAll(raw, "_", ""), nil
+	}
+}
+
+// isBareKey reports whether s is a valid unquoted TOML key
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTOML writes values grouped into tables, quoting anything that is not an integer or boolean
+func writeTOML(w io.Writer, values map[string]string) error {
+	tables := make(map[string][]string)
+	for key := range values {
+		table, _ := splitKey(key)
+		tables[table] = append(tables[table], key)
+	}
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for i, name := range names {
+		if name != "" {
+			if i > 0 {
+				fmt.Fprintln(bw)
+			}
+			fmt.Fprintf(bw, "[%s]\n", name)
+		}
+		keys := tables[name]
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, field := splitKey(key)
+			fmt.Fprintf(bw, "%s = %s\n", field, formatValue(values[key]))
+		}
+	}
+	return bw.Flush()
+}
+
+// splitKey splits "table.key" into its table and key parts
+func splitKey(key string) (string, string) {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+// formatValue renders value as a TOML literal
+func formatValue(value string) string {
+	if value == "true" || value == "false" {
+		return value
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return value
+	}
+	return strconv.Quote(value)
+}
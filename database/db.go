@@ -1,302 +1,514 @@
 package database
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-func InitDB(dbName string) error {
-    var err error
-    DB, err = gorm.Open(sqlite.Open(dbName), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-    })
-    if err != nil {
-        return err
-    }
-	// Migrate the schema
-	if err := DB.AutoMigrate(&SensitiveData{}, &MasterPassword{}, &VaultState{}); err != nil {
-		return err
+// HistoryRetention is the number of previous values kept per entry. When it is 0, updates record no
+// history and leave the versions already recorded alone.
+var HistoryRetention = 10
+
+// SetMasterPassword stores the initial master password, deriving a fresh salt and key verifier.
+// Use ChangeMasterPassword to replace an existing one.
+func SetMasterPassword(s Store, password string) error {
+	if err := CheckMasterPasswordSet(s); err == nil {
+		return fmt.Errorf("master password already set; use ChangeMasterPassword to change it: %w", ErrDuplicate)
 	}
 
-	// Initialize the vault state in your database
-	if err := InitializeVaultState(); err != nil {
+	masterPassword, _, err := newMasterPassword(password, DefaultKDFParams)
+	if err != nil {
 		return err
 	}
+	return s.SetMaster(&masterPassword)
+}
 
-	return nil
+// ChangeMasterPassword replaces the master password and re-encrypts every entry, including those in the
+// trash, with the new key in a single transaction. progress, if not nil, is called after each entry is re-encrypted.
+// ErrBadPassword is returned if oldPassword is wrong.
+func ChangeMasterPassword(s Store, oldPassword, newPassword string, progress func(done, total int)) error {
+	oldKey, err := UnlockMasterKey(s, oldPassword)
+	if err != nil {
+		return err
+	}
+	return ChangeMasterKey(s, oldKey, newPassword, progress)
 }
 
-// Initialize the vault state in your database
-func InitializeVaultState() error {
-	// Check if there's an existing vault state
-	var state VaultState
-	err := DB.First(&state).Error
+// ChangeMasterKey is ChangeMasterPassword for a caller that already holds the key derived from the old
+// password with UnlockMasterKey, so that the key derivation is not paid for twice.
+func ChangeMasterKey(s Store, oldKey []byte, newPassword string, progress func(done, total int)) error {
+	masterPassword, newKey, err := newMasterPassword(newPassword, DefaultKDFParams)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// If no record exists, create the initial vault state
-			state = VaultState{IsLocked: true} // Start with the vault locked
-			if err := DB.Create(&state).Error; err != nil {
+		return err
+	}
+
+	return s.Transaction(func(tx Store) error {
+		entries, err := allEntries(tx)
+		if err != nil {
+			return err
+		}
+
+		for i, entry := range entries {
+			aad := entryAAD(entry.Service, entry.Identifier)
+			value, err := decrypt(entry.Value, oldKey, aad)
+			if err != nil {
+				return fmt.Errorf("error decrypting entry for service '%s' and identifier '%s': %w", entry.Service, entry.Identifier, err)
+			}
+			entry.Value, err = encrypt(value, newKey, aad)
+			if err != nil {
+				return fmt.Errorf("error encrypting entry for service '%s': %v", entry.Service, err)
+			}
+			if entry.Metadata != "" {
+				metadataAAD := metadataAAD(entry.Service, entry.Identifier)
+				metadata, err := decrypt(entry.Metadata, oldKey, metadataAAD)
+				if err != nil {
+					return fmt.Errorf("error decrypting details for service '%s' and identifier '%s': %w", entry.Service, entry.Identifier, err)
+				}
+				if entry.Metadata, err = encrypt(metadata, newKey, metadataAAD); err != nil {
+					return fmt.Errorf("error encrypting details for service '%s': %v", entry.Service, err)
+				}
+			}
+			if err := tx.Update(&entry); err != nil {
+				return fmt.Errorf("error updating entry for service '%s': %w", entry.Service, err)
+			}
+			if err := reencryptHistory(tx, entry.ID, oldKey, newKey); err != nil {
 				return err
 			}
-		} else {
-			return err // Return other errors
+			if progress != nil {
+				progress(i+1, len(entries))
+			}
 		}
+
+		// Replace the old master password record
+		return tx.SetMaster(&masterPassword)
+	})
+}
+
+// allEntries returns the entries in the vault followed by those in the trash
+func allEntries(s Store) ([]SensitiveData, error) {
+	entries, err := s.List("")
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	trashed, err := s.Trash()
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, trashed...), nil
 }
 
-// GetVaultState retrieves the current vault state from the database
-func GetVaultState() (bool, error) {
-	var state VaultState
-	// Query the last (or the first) vault state record from the database
-	err := DB.First(&state).Error
+// reencryptHistory re-encrypts the previous values of an entry, each bound to the service and identifier it was stored under
+func reencryptHistory(tx Store, entryID uint, oldKey, newKey []byte) error {
+	records, err := tx.History(entryID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// If no record is found, assume the vault is locked
-			return true, nil // Assume locked if no state is found
+		return err
+	}
+	for _, record := range records {
+		aad := entryAAD(record.Service, record.Identifier)
+		value, err := decrypt(record.Value, oldKey, aad)
+		if err != nil {
+			return fmt.Errorf("error decrypting version %d of service '%s' and identifier '%s': %w", record.Version, record.Service, record.Identifier, err)
+		}
+		record.Value, err = encrypt(value, newKey, aad)
+		if err != nil {
+			return fmt.Errorf("error encrypting version %d of service '%s': %v", record.Version, record.Service, err)
+		}
+		if err := tx.UpdateHistory(&record); err != nil {
+			return err
 		}
-		return false, err // Return other errors
 	}
-	return state.IsLocked, nil // Return the vault state (locked or unlocked)
+	return nil
 }
 
-// SetVaultState updates the vault's locked state in the database
-func SetVaultState(isLocked bool) error {
-	var state VaultState
+func VerifyMasterPassword(s Store, inputPassword string) (bool, error) {
+	masterPassword, err := s.Master()
+	if err != nil {
+		return false, err
+	}
+	if masterPassword.isLegacy() {
+		err = bcrypt.CompareHashAndPassword([]byte(masterPassword.HashedPassword), []byte(inputPassword))
+		return err == nil, nil
+	}
+	_, valid, err := deriveMasterKey(masterPassword, inputPassword)
+	return valid, err
+}
 
-	// Try to find the existing vault state
-	err := DB.First(&state).Error
+// UnlockMasterKey verifies the master password and returns the derived entry encryption key.
+// Vaults still using the legacy bcrypt-derived key are migrated on the first successful unlock.
+func UnlockMasterKey(s Store, password string) ([]byte, error) {
+	masterPassword, err := s.Master()
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// If no record exists, create a new state
-			state = VaultState{IsLocked: isLocked} // Set the state based on the input
-			return DB.Create(&state).Error
-		}
-		return err // Return other errors
+		return nil, err
+	}
+
+	if masterPassword.isLegacy() {
+		return migrateLegacyKey(s, masterPassword, password)
 	}
 
-	// If the record exists, update the state
-	state.IsLocked = isLocked
-	return DB.Save(&state).Error
+	key, valid, err := deriveMasterKey(masterPassword, password)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrBadPassword
+	}
+	return key, nil
 }
 
-func SetMasterPassword(password string, isMasterPasswordSet bool) error {
-	if isMasterPasswordSet {
-		// A master password exists, delete the old one
-		if err := DB.Delete(&MasterPassword{}).Error; err != nil {
-			return fmt.Errorf("failed to delete old master password: %w", err)
-		}
+// migrateLegacyKey re-encrypts every entry with a key derived from the master password
+// and replaces the stored bcrypt hash with KDF parameters and a verifier
+func migrateLegacyKey(s Store, masterPassword MasterPassword, password string) ([]byte, error) {
+	if err := bcrypt.CompareHashAndPassword([]byte(masterPassword.HashedPassword), []byte(password)); err != nil {
+		return nil, ErrBadPassword
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	oldKey := deriveLegacyKey(masterPassword.HashedPassword)
+	migrated, newKey, err := newMasterPassword(password, DefaultKDFParams)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	masterPassword := MasterPassword{HashedPassword: string(hashedPassword)}
-	return DB.Create(&masterPassword).Error
-}
+	err = s.Transaction(func(tx Store) error {
+		entries, err := allEntries(tx)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			value, err := decryptLegacy(entry.Value, oldKey)
+			if err != nil {
+				return fmt.Errorf("error decrypting entry for service '%s': %w", entry.Service, err)
+			}
+			entry.Value, err = encrypt(value, newKey, entryAAD(entry.Service, entry.Identifier))
+			if err != nil {
+				return fmt.Errorf("error encrypting entry for service '%s': %v", entry.Service, err)
+			}
+			if err := tx.Update(&entry); err != nil {
+				return err
+			}
+		}
 
-func VerifyMasterPassword(inputPassword string) (bool, error) {
-	var masterPassword MasterPassword
-	err := DB.First(&masterPassword).Error
+		return tx.SetMaster(&migrated)
+	})
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("failed to migrate vault key: %w", err)
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(masterPassword.HashedPassword), []byte(inputPassword))
-	return err == nil, nil
+
+	return newKey, nil
 }
 
-func CheckMasterPasswordSet() error {
-	var masterPassword MasterPassword
-	if err := DB.First(&masterPassword).Error; err != nil {
-		// If we can't find the master password, it means it hasn't been set
-		return fmt.Errorf("please set the master password first using 'set-master'")
-	}
-	return nil
+func CheckMasterPasswordSet(s Store) error {
+	_, err := s.Master()
+	return err
+}
+
+// AddSensitiveData encrypts value with the keyring and stores it as a new entry
+func AddSensitiveData(s Store, kr Keyring, service, identifier, value, kind string) error {
+	return AddSensitiveDataWithDetails(s, kr, service, identifier, value, kind, EntryDetails{}, nil)
 }
 
-func AddSensitiveData(service, identifier, value, idType string) error {
-	// Use the utility function to validate and convert idType
-	identifierType, err := ParseIdentifierType(idType)
+// AddSensitiveDataWithDetails stores a new entry with encrypted details and the given tags.
+// Checking the entry against the schema of its kind is up to the caller.
+func AddSensitiveDataWithDetails(s Store, kr Keyring, service, identifier, value, kind string, details EntryDetails, tags []string) error {
+	if kind == "" {
+		return fmt.Errorf("an entry kind is required")
+	}
+	tags, err := NormalizeTags(tags)
 	if err != nil {
 		return err
 	}
 
-	// Retrieve the hashed master password from the database
-	var masterPassword MasterPassword
-	if err := DB.First(&masterPassword).Error; err != nil {
-		return fmt.Errorf("could not retrieve master password: %v", err)
-	}
-
-	key := deriveAESKey(masterPassword.HashedPassword)
-
-	// Encrypt the value using the hashed master password
-	encryptedValue, err := encrypt(value, key)
+	// Encrypt the value using the keyring, bound to this service and identifier
+	encryptedValue, err := kr.Encrypt(value, entryAAD(service, identifier))
 	if err != nil {
 		return fmt.Errorf("error encrypting sensitive data: %v", err)
 	}
+	metadata, err := encryptDetails(kr, service, identifier, details)
+	if err != nil {
+		return err
+	}
 
 	sensitiveData := SensitiveData{
-		Service:        service,
-		Identifier:     identifier,
+		Service:    service,
+		Identifier: identifier,
 		Value:          encryptedValue,
-		IdentifierType: identifierType,
+		ValueUpdatedAt: time.Now(),
+		Kind:           strings.ToLower(kind),
+		Metadata:       metadata,
+		Tags:           strings.Join(tags, ","),
 	}
-	return DB.Create(&sensitiveData).Error
+	return s.Add(&sensitiveData)
 }
 
-func GetSensitiveData(service, identifier string) (SensitiveData, error) {
-	var sensitiveData SensitiveData
-
-	// Normalize service and identifier to lowercase for case-insensitive search
-	normalizedService := strings.ToLower(service)
-	normalizedIdentifier := strings.ToLower(identifier)
-
-	// Query database for matching service and identifier
-	err := DB.Where("LOWER(service) = ?", normalizedService).
-		Where("LOWER(identifier) = ?", normalizedIdentifier).
-		First(&sensitiveData).Error
-
+// SetEntryDetails replaces the details and tags of an entry
+func SetEntryDetails(s Store, kr Keyring, service, identifier string, details EntryDetails, tags []string) error {
+	tags, err := NormalizeTags(tags)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return SensitiveData{}, fmt.Errorf("no data found for service '%s' and identifier '%s'", service, identifier)
-		}
-		return SensitiveData{}, fmt.Errorf("error querying sensitive data: %w", err)
+		return err
 	}
-
-	// Retrieve the hashed master password from the database
-	var masterPassword MasterPassword
-	if err := DB.First(&masterPassword).Error; err != nil {
-		return SensitiveData{}, fmt.Errorf("could not retrieve master password: %v", err)
+	entry, err := s.Get(service, identifier)
+	if err != nil {
+		return err
 	}
+	if entry.Metadata, err = encryptDetails(kr, entry.Service, entry.Identifier, details); err != nil {
+		return err
+	}
+	entry.Tags = strings.Join(tags, ",")
+	return s.Update(&entry)
+}
 
-	// Derive the encryption key from the hashed master password
-	key := deriveAESKey(masterPassword.HashedPassword)
-
-	// Decrypt the sensitive data value
-	decryptedValue, err := decrypt(sensitiveData.Value, key)
+// ReplaceSensitiveData replaces the value, kind, details and tags of an entry, as when importing over it.
+// A changed value is recorded in the entry's history, keeping at most HistoryRetention versions.
+func ReplaceSensitiveData(s Store, kr Keyring, service, identifier, value, kind string, details EntryDetails, tags []string) error {
+	if kind == "" {
+		return fmt.Errorf("an entry kind is required")
+	}
+	tags, err := NormalizeTags(tags)
 	if err != nil {
-		return SensitiveData{}, fmt.Errorf("error decrypting sensitive data: %v", err)
+		return err
 	}
+	return s.Transaction(func(tx Store) error {
+		entry, err := tx.Get(service, identifier)
+		if err != nil {
+			return err
+		}
+		previous := entry
 
-	// Replace the encrypted value with the decrypted value
-	sensitiveData.Value = decryptedValue
+		currentValue, err := kr.Decrypt(entry.Value, entryAAD(entry.Service, entry.Identifier))
+		if err != nil {
+			return fmt.Errorf("error decrypting sensitive data: %w", err)
+		}
+		if entry.Value, err = kr.Encrypt(value, entryAAD(entry.Service, entry.Identifier)); err != nil {
+			return fmt.Errorf("error encrypting sensitive data: %v", err)
+		}
+		if entry.Metadata, err = encryptDetails(kr, entry.Service, entry.Identifier, details); err != nil {
+			return err
+		}
+		entry.Kind = strings.ToLower(kind)
+		entry.Tags = strings.Join(tags, ",")
+		if value != currentValue {
+			entry.ValueUpdatedAt = time.Now()
+		}
 
-	return sensitiveData, nil
+		if err := tx.Update(&entry); err != nil {
+			return err
+		}
+		if value == currentValue {
+			return nil // The value is unchanged, so there is nothing to record
+		}
+		return recordHistory(tx, previous)
+	})
 }
 
-func GetAllSensitiveData(idType string) ([]SensitiveData, error) {
-	var entries []SensitiveData
-	query := DB
-
-	// If idType is not empty, validate and filter by it
-	if idType != "" {
-		identifierType, err := ParseIdentifierType(idType)
-		if err != nil {
-			return nil, err // Return nil slice and the error
-		}
-		query = query.Where("identifier_type = ?", identifierType)
+// encryptDetails returns details encrypted for the entry, or "" if there are none
+func encryptDetails(kr Keyring, service, identifier string, details EntryDetails) (string, error) {
+	if details.IsZero() {
+		return "", nil
 	}
+	plaintext, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("error encoding entry details: %v", err)
+	}
+	encrypted, err := kr.Encrypt(string(plaintext), metadataAAD(service, identifier))
+	if err != nil {
+		return "", fmt.Errorf("error encrypting entry details: %v", err)
+	}
+	return encrypted, nil
+}
 
-	err := query.Find(&entries).Error
+// GetSensitiveData retrieves an entry and decrypts its value with the keyring
+func GetSensitiveData(s Store, kr Keyring, service, identifier string) (SensitiveData, error) {
+	sensitiveData, err := s.Get(service, identifier)
 	if err != nil {
-		return nil, err // Return nil slice and the error
+		return SensitiveData{}, err
 	}
 
-	// Retrieve the hashed master password
-	var masterPassword MasterPassword
-	if err := DB.First(&masterPassword).Error; err != nil {
-		return nil, fmt.Errorf("could not retrieve master password: %v", err)
+	// Decrypt the sensitive data value, replacing the encrypted value with the decrypted one
+	if err := decryptEntry(kr, &sensitiveData); err != nil {
+		return SensitiveData{}, err
 	}
 
-	key := deriveAESKey(masterPassword.HashedPassword)
+	return sensitiveData, nil
+}
+
+// GetAllSensitiveData retrieves all entries, optionally filtered by kind, and decrypts them with the keyring
+func GetAllSensitiveData(s Store, kr Keyring, kind string) ([]SensitiveData, error) {
+	entries, err := s.List(strings.ToLower(kind))
+	if err != nil {
+		return nil, err // Return nil slice and the error
+	}
 
 	// Decrypt the sensitive data values
-	for i, entry := range entries {
-		decryptedValue, err := decrypt(entry.Value, key)
-		if err != nil {
-			return nil, fmt.Errorf("error decrypting sensitive data: %v", err)
+	for i := range entries {
+		if err := decryptEntry(kr, &entries[i]); err != nil {
+			return nil, err
 		}
-		entries[i].Value = decryptedValue // Replace the encrypted value with the decrypted one
 	}
 
 	return entries, nil
 }
 
-func DeleteSensitiveData(service, identifier string) error {
-	var entry SensitiveData
-	// Normalize service and identifier to lowercase
-	normalizedService := strings.ToLower(service)
-	normalizedIdentifier := strings.ToLower(identifier)
-
-	// Attempt to find the entry based on service and identifier
-	err := DB.Where("LOWER(service) = ? AND LOWER(identifier) = ?", normalizedService, normalizedIdentifier).First(&entry).Error
+// decryptEntry replaces the entry's encrypted value with its plaintext and decrypts its details
+func decryptEntry(kr Keyring, entry *SensitiveData) error {
+	decryptedValue, err := kr.Decrypt(entry.Value, entryAAD(entry.Service, entry.Identifier))
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("no entry found for service '%s' and identifier '%s'", service, identifier)
-		}
-		return fmt.Errorf("error finding the entry: %w", err)
+		return fmt.Errorf("error decrypting sensitive data for service '%s' and identifier '%s': %w", entry.Service, entry.Identifier, err)
 	}
+	entry.Value = decryptedValue
 
-	// Attempt to delete the entry
-	if err := DB.Unscoped().Delete(&entry).Error; err != nil {
-		return fmt.Errorf("error deleting the entry: %w", err)
+	entry.Details = EntryDetails{}
+	if entry.Metadata != "" {
+		metadata, err := kr.Decrypt(entry.Metadata, metadataAAD(entry.Service, entry.Identifier))
+		if err != nil {
+			return fmt.Errorf("error decrypting details for service '%s' and identifier '%s': %w", entry.Service, entry.Identifier, err)
+		}
+		if err := json.Unmarshal([]byte(metadata), &entry.Details); err != nil {
+			return fmt.Errorf("error decoding details for service '%s' and identifier '%s': %v", entry.Service, entry.Identifier, err)
+		}
 	}
-
 	return nil
 }
 
-func UpdateSensitiveData(service, identifier, newValue, newIdentifier string) error {
-	var entry SensitiveData
-
-	// Normalize service and identifier to lowercase
-	normalizedService := strings.ToLower(service)
-	normalizedIdentifier := strings.ToLower(identifier)
+// DeleteSensitiveData moves an entry to the trash, from which it can be restored until it is purged
+func DeleteSensitiveData(s Store, service, identifier string) error {
+	return s.Delete(service, identifier)
+}
 
-	// Find the existing entry based on the service and identifier
-	err := DB.Where("LOWER(service) = ? AND LOWER(identifier) = ?", normalizedService, normalizedIdentifier).First(&entry).Error
+// PurgeSensitiveData permanently deletes an entry and its history, bypassing the trash
+func PurgeSensitiveData(s Store, service, identifier string) error {
+	entry, err := s.Get(service, identifier)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("no entry found for service '%s' and identifier '%s'", service, identifier)
-		}
-		return fmt.Errorf("error finding the entry: %w", err)
+		return err
 	}
+	return s.Purge(entry.ID)
+}
 
-	// Update the value if a new value is provided
-	if newValue != "" {
-		// Retrieve the hashed master password from the database
-		var masterPassword MasterPassword
-		if err := DB.First(&masterPassword).Error; err != nil {
-			return fmt.Errorf("could not retrieve master password: %v", err)
+// UpdateSensitiveData replaces the value and/or identifier of an entry, encrypting the new value with the keyring.
+// The previous value is recorded in the entry's history, keeping at most HistoryRetention versions.
+func UpdateSensitiveData(s Store, kr Keyring, service, identifier, newValue, newIdentifier string) error {
+	return s.Transaction(func(tx Store) error {
+		// Find the existing entry based on the service and identifier
+		entry, err := tx.Get(service, identifier)
+		if err != nil {
+			return err
 		}
-		// Derive the AES key from the hashed master password
-		key := deriveAESKey(masterPassword.HashedPassword)
-		// Encrypt the new value using the hashed master password
-		encryptedValue, err := encrypt(newValue, key)
+		previous := entry
+
+		currentValue, err := kr.Decrypt(entry.Value, entryAAD(entry.Service, entry.Identifier))
+		if err != nil {
+			return fmt.Errorf("error decrypting sensitive data: %w", err)
+		}
+		if newValue == "" {
+			newValue = currentValue
+		}
+		if newIdentifier != "" {
+			entry.Identifier = newIdentifier
+		}
+		if newValue == currentValue && entry.Identifier == previous.Identifier {
+			return nil // Nothing changes, so there is nothing to record
+		}
+		if newValue != currentValue {
+			entry.ValueUpdatedAt = time.Now()
+		}
+
+		// Encrypt the value using the keyring, bound to the (possibly new) identifier
+		entry.Value, err = kr.Encrypt(newValue, entryAAD(entry.Service, entry.Identifier))
 		if err != nil {
 			return fmt.Errorf("error encrypting sensitive data: %v", err)
 		}
-		entry.Value = encryptedValue // Update the value with the encrypted one
-	}
 
-	// Update the identifier if a new identifier is provided
-	if newIdentifier != "" {
-		entry.Identifier = newIdentifier // Update the identifier
+		// Details are bound to the identifier as well
+		if entry.Metadata != "" && entry.Identifier != previous.Identifier {
+			metadata, err := kr.Decrypt(entry.Metadata, metadataAAD(previous.Service, previous.Identifier))
+			if err != nil {
+				return fmt.Errorf("error decrypting entry details: %w", err)
+			}
+			if entry.Metadata, err = kr.Encrypt(metadata, metadataAAD(entry.Service, entry.Identifier)); err != nil {
+				return fmt.Errorf("error encrypting entry details: %v", err)
+			}
+		}
+
+		// Save the updated entry; renaming onto an existing entry fails with ErrDuplicate
+		if err := tx.Update(&entry); err != nil {
+			return err
+		}
+		return recordHistory(tx, previous)
+	})
+}
+
+// recordHistory adds the previous state of an entry to its history and prunes versions beyond HistoryRetention
+func recordHistory(tx Store, previous SensitiveData) error {
+	if HistoryRetention <= 0 {
+		return nil
+	}
+	record := SensitiveDataHistory{
+		EntryID:    previous.ID,
+		Service:    previous.Service,
+		Identifier: previous.Identifier,
+		Value:      previous.Value, // Still bound to the service and identifier recorded with it
+		ValueSetAt: previous.ValueUpdatedAt,
+	}
+	if err := tx.AddHistory(&record); err != nil {
+		return fmt.Errorf("error recording entry history: %w", err)
 	}
+	return tx.PruneHistory(previous.ID, HistoryRetention)
+}
 
-	// Save the updated entry
-	if err := DB.Save(&entry).Error; err != nil {
-		return fmt.Errorf("error updating the entry: %w", err)
+// GetSensitiveDataHistory returns the previous values of an entry ordered by version, decrypted with the keyring
+func GetSensitiveDataHistory(s Store, kr Keyring, service, identifier string) ([]SensitiveDataHistory, error) {
+	entry, err := s.Get(service, identifier)
+	if err != nil {
+		return nil, err
 	}
+	records, err := s.History(entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		value, err := kr.Decrypt(records[i].Value, entryAAD(records[i].Service, records[i].Identifier))
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting version %d of service '%s' and identifier '%s': %w", records[i].Version, service, identifier, err)
+		}
+		records[i].Value = value
+	}
+	return records, nil
+}
 
-	return nil
+// RestoreSensitiveData replaces the value of an entry with the one recorded as version. The identifier is kept,
+// and the replaced value is added to the history so the restore can itself be undone.
+func RestoreSensitiveData(s Store, kr Keyring, service, identifier string, version int) error {
+	return s.Transaction(func(tx Store) error {
+		entry, err := tx.Get(service, identifier)
+		if err != nil {
+			return err
+		}
+		records, err := tx.History(entry.ID)
+		if err != nil {
+			return err
+		}
+		index := slices.IndexFunc(records, func(record SensitiveDataHistory) bool { return record.Version == version })
+		if index < 0 {
+			return fmt.Errorf("%w: no version %d of service '%s' and identifier '%s'", ErrNotFound, version, service, identifier)
+		}
+		record := records[index]
+
+		value, err := kr.Decrypt(record.Value, entryAAD(record.Service, record.Identifier))
+		if err != nil {
+			return fmt.Errorf("error decrypting version %d: %w", version, err)
+		}
+		previous := entry
+		entry.Value, err = kr.Encrypt(value, entryAAD(entry.Service, entry.Identifier))
+		if err != nil {
+			return fmt.Errorf("error encrypting sensitive data: %v", err)
+		}
+		entry.ValueUpdatedAt = time.Now()
+		if err := tx.Update(&entry); err != nil {
+			return err
+		}
+		return recordHistory(tx, previous)
+	})
 }
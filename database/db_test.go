@@ -1,133 +1,109 @@
 package database
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
-)
+	"time"
 
-// Test Setup and teardown
-func setup(filename string) error {
-	return InitDB(filename)
-}
+	"golang.org/x/crypto/bcrypt"
+)
 
-func teardown(filename string) {
-	_ = os.Remove(filename) // Remove the database file if created
+// TestMain uses cheap KDF parameters so tests stay fast
+func TestMain(m *testing.M) {
+	DefaultKDFParams = KDFParams{Time: 1, Memory: 8 * 1024, Threads: 1}
+	os.Exit(m.Run())
 }
 
-func TestInitDB(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
+// setup opens a fresh SQLite store in a temporary directory. Tests using it run in parallel.
+func setup(t *testing.T) Store {
+	t.Helper()
+	t.Parallel()
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "test_vault.db"))
+	if err != nil {
 		t.Fatalf("Failed to initialize DB: %v", err)
 	}
-	teardown(filename)
+	t.Cleanup(func() { store.Close() })
+	return store
 }
 
-func TestInitializeVaultState(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
+// setupKey sets a master password and returns a keyring holding the derived entry key
+func setupKey(t *testing.T, store Store) *LocalKeyring {
+	t.Helper()
+	if err := SetMasterPassword(store, "mysecretpassword"); err != nil {
+		t.Fatalf("Failed to set master password: %v", err)
 	}
-	defer teardown(filename)
-
-	state, err := GetVaultState()
+	key, err := UnlockMasterKey(store, "mysecretpassword")
 	if err != nil {
-		t.Fatalf("Failed to get vault state: %v", err)
-	}
-	if state != true {
-		t.Errorf("Expected vault state to be locked, got %v", state)
+		t.Fatalf("Failed to unlock master key: %v", err)
 	}
+	return NewLocalKeyring(key)
 }
 
-func TestSetVaultState(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
-
-	if err := SetVaultState(true); err != nil {
-		t.Fatalf("Failed to set vault state to true: %v", err)
-	}
+func TestOpenSQLiteStore(t *testing.T) {
+	store := setup(t)
 
-	state, err := GetVaultState()
+	state, err := store.State()
 	if err != nil {
-		t.Fatalf("Failed to get vault state: %v", err)
-	}
-	if state != true {
-		t.Errorf("Expected vault state to be locked, got %v", state)
-	}
-
-	if err := SetVaultState(false); err != nil {
-		t.Fatalf("Failed to set vault state to false: %v", err)
+		t.Fatalf("Failed to read state: %v", err)
 	}
-
-	state, err = GetVaultState()
-	if err != nil {
-		t.Fatalf("Failed to get vault state: %v", err)
-	}
-	if state != false {
-		t.Errorf("Expected vault state to be unlocked, got %v", state)
+	if state.MasterPasswordSet || state.Entries != 0 {
+		t.Errorf("Expected an empty vault, got %+v", state)
 	}
 }
 
 func TestSetMasterPassword(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
+	store := setup(t)
 
-	err := SetMasterPassword("mysecretpassword", false)
+	err := SetMasterPassword(store, "mysecretpassword")
 	if err != nil {
 		t.Fatalf("Failed to set master password: %v", err)
 	}
 
 	// Check if the password is set correctly
-	if err := CheckMasterPasswordSet(); err != nil {
+	if err := CheckMasterPasswordSet(store); err != nil {
 		t.Fatalf("Master password should be set: %v", err)
 	}
 }
 
 func TestVerifyMasterPassword(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
+	store := setup(t)
 
 	password := "mysecretpassword"
-	if err := SetMasterPassword(password, false); err != nil {
+	if err := SetMasterPassword(store, password); err != nil {
 		t.Fatalf("Failed to set master password: %v", err)
 	}
 
-	valid, err := VerifyMasterPassword(password)
+	valid, err := VerifyMasterPassword(store, password)
 	if err != nil || !valid {
 		t.Errorf("Expected valid master password, got error: %v", err)
 	}
 
-	valid, err = VerifyMasterPassword("wrongpassword")
+	valid, err = VerifyMasterPassword(store, "wrongpassword")
 	if err == nil && valid {
 		t.Error("Expected invalid master password, got valid")
 	}
 }
 
 func TestAddAndGetSensitiveData(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
+	store := setup(t)
 
-	if err := SetMasterPassword("mysecretpassword", false); err != nil {
-		t.Fatalf("Failed to set master password: %v", err)
-	}
+	key := setupKey(t, store)
 
-	if err := AddSensitiveData("example.com", "user@example.com", "mypassword", "email"); err != nil {
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
 		t.Fatalf("Failed to add sensitive data: %v", err)
 	}
 
-	data, err := GetSensitiveData("example.com", "user@example.com")
+	data, err := GetSensitiveData(store, key, "example.com", "user@example.com")
 	if err != nil {
 		t.Fatalf("Failed to get sensitive data: %v", err)
 	}
@@ -137,50 +113,38 @@ func TestAddAndGetSensitiveData(t *testing.T) {
 }
 
 func TestDeleteSensitiveData(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
+	store := setup(t)
 
-	if err := SetMasterPassword("mysecretpassword", false); err != nil {
-		t.Fatalf("Failed to set master password: %v", err)
-	}
+	key := setupKey(t, store)
 
-	if err := AddSensitiveData("example.com", "user@example.com", "mypassword", "email"); err != nil {
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
 		t.Fatalf("Failed to add sensitive data: %v", err)
 	}
 
-	if err := DeleteSensitiveData("example.com", "user@example.com"); err != nil {
+	if err := DeleteSensitiveData(store, "example.com", "user@example.com"); err != nil {
 		t.Fatalf("Failed to delete sensitive data: %v", err)
 	}
 
-	_, err := GetSensitiveData("example.com", "user@example.com")
+	_, err := GetSensitiveData(store, key, "example.com", "user@example.com")
 	if err == nil {
 		t.Error("Expected error getting deleted sensitive data, got none")
 	}
 }
 
 func TestUpdateSensitiveData(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
+	store := setup(t)
 
-	if err := SetMasterPassword("mysecretpassword", false); err != nil {
-		t.Fatalf("Failed to set master password: %v", err)
-	}
+	key := setupKey(t, store)
 
-	if err := AddSensitiveData("example.com", "user@example.com", "mypassword", "email"); err != nil {
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
 		t.Fatalf("Failed to add sensitive data: %v", err)
 	}
 
-	if err := UpdateSensitiveData("example.com", "user@example.com", "newpassword", ""); err != nil {
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "newpassword", ""); err != nil {
 		t.Fatalf("Failed to update sensitive data: %v", err)
 	}
 
-	data, err := GetSensitiveData("example.com", "user@example.com")
+	data, err := GetSensitiveData(store, key, "example.com", "user@example.com")
 	if err != nil {
 		t.Fatalf("Failed to get sensitive data: %v", err)
 	}
@@ -190,21 +154,15 @@ func TestUpdateSensitiveData(t *testing.T) {
 }
 
 func TestGetAllSensitiveData(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
+	store := setup(t)
 
-	if err := SetMasterPassword("mysecretpassword", false); err != nil {
-		t.Fatalf("Failed to set master password: %v", err)
-	}
+	key := setupKey(t, store)
 
-	if err := AddSensitiveData("example.com", "user@example.com", "mypassword", "email"); err != nil {
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
 		t.Fatalf("Failed to add sensitive data: %v", err)
 	}
 
-	allData, err := GetAllSensitiveData("")
+	allData, err := GetAllSensitiveData(store, key, "")
 	if err != nil {
 		t.Fatalf("Failed to get all sensitive data: %v", err)
 	}
@@ -214,60 +172,31 @@ func TestGetAllSensitiveData(t *testing.T) {
 }
 
 func TestCheckMasterPasswordSet(t *testing.T) {
-	filename := "test_vault.db"
-	if err := setup(filename); err != nil {
-		t.Fatalf("Failed to initialize DB: %v", err)
-	}
-	defer teardown(filename)
+	store := setup(t)
 
-	err := CheckMasterPasswordSet()
+	err := CheckMasterPasswordSet(store)
 	if err == nil {
 		t.Error("Expected error for unset master password, got none")
 	}
 
-	if err := SetMasterPassword("mysecretpassword", false); err != nil {
+	if err := SetMasterPassword(store, "mysecretpassword"); err != nil {
 		t.Fatalf("Failed to set master password: %v", err)
 	}
 
-	if err := CheckMasterPasswordSet(); err != nil {
+	if err := CheckMasterPasswordSet(store); err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
 }
 
-// TestParseIdentifierType tests the ParseIdentifierType function
-func TestParseIdentifierType(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected IdentifierType
-		wantErr  bool
-	}{
-		{"username", IdentifierTypeUsername, false},
-		{"email", IdentifierTypeEmail, false},
-		{"api_key", IdentifierTypeAPIKey, false},
-		{"secret_key", IdentifierTypeSecret, false},
-		{"invalid", "", true}, // expect error for invalid input
-	}
-
-	for _, test := range tests {
-		result, err := ParseIdentifierType(test.input)
-		if (err != nil) != test.wantErr {
-			t.Errorf("ParseIdentifierType(%q) error = %v, wantErr %v", test.input, err, test.wantErr)
-		}
-		if !test.wantErr && result != test.expected {
-			t.Errorf("ParseIdentifierType(%q) = %v, want %v", test.input, result, test.expected)
-		}
-	}
-}
-
-// TestDeriveAESKey tests the DeriveAESKey function
-func TestDeriveAESKey(t *testing.T) {
+// TestDeriveLegacyKey tests the legacy key derivation used for migration
+func TestDeriveLegacyKey(t *testing.T) {
 	password := "mysecretpassword"
 	expected := sha256.Sum256([]byte(password))
 
-	result := deriveAESKey(password)
+	result := deriveLegacyKey(password)
 	// Compare the results
 	if !equal(result, expected[:]) {
-		t.Errorf("DeriveAESKey(%q) = %x, want %x", password, result, expected[:])
+		t.Errorf("deriveLegacyKey(%q) = %x, want %x", password, result, expected[:])
 	}
 }
 
@@ -286,17 +215,21 @@ func equal(a, b []byte) bool {
 
 // TestEncryptDecrypt tests the Encrypt and Decrypt functions
 func TestEncryptDecrypt(t *testing.T) {
-	key := deriveAESKey("mysecretpassword") // Deriving the key
+	key := deriveLegacyKey("mysecretpassword") // Deriving the key
 	plaintext := "Hello, World!"
+	aad := entryAAD("example.com", "user@example.com")
 
 	// Encrypt the plaintext
-	ciphertextHex, err := encrypt(plaintext, key)
+	ciphertextHex, err := encrypt(plaintext, key, aad)
 	if err != nil {
 		t.Fatalf("Failed to encrypt: %v", err)
 	}
+	if !strings.HasPrefix(ciphertextHex, ciphertextVersionAESGCM+ciphertextSeparator) {
+		t.Errorf("Expected ciphertext to carry version prefix, got %q", ciphertextHex)
+	}
 
 	// Decrypt the ciphertext
-	decryptedText, err := decrypt(ciphertextHex, key)
+	decryptedText, err := decrypt(ciphertextHex, key, aad)
 	if err != nil {
 		t.Fatalf("Failed to decrypt: %v", err)
 	}
@@ -305,3 +238,616 @@ func TestEncryptDecrypt(t *testing.T) {
 		t.Errorf("Decrypt() = %q, want %q", decryptedText, plaintext)
 	}
 }
+
+// TestDecryptDetectsTampering tests that modified ciphertexts and swapped rows are rejected
+func TestDecryptDetectsTampering(t *testing.T) {
+	key := deriveLegacyKey("mysecretpassword")
+	aad := entryAAD("example.com", "user@example.com")
+
+	ciphertextHex, err := encrypt("Hello, World!", key, aad)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	// Flip the last hex digit
+	last := ciphertextHex[len(ciphertextHex)-1]
+	flipped := byte('0')
+	if last == '0' {
+		flipped = '1'
+	}
+	tampered := ciphertextHex[:len(ciphertextHex)-1] + string(flipped)
+	if _, err := decrypt(tampered, key, aad); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("Expected ErrIntegrity for tampered ciphertext, got %v", err)
+	}
+
+	// A value moved to another entry must not decrypt
+	if _, err := decrypt(ciphertextHex, key, entryAAD("example.com", "admin@example.com")); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("Expected ErrIntegrity for swapped ciphertext, got %v", err)
+	}
+
+	// Names containing NUL must not make two entries share an AAD
+	if bytes.Equal(entryAAD("a\x00b", "c"), entryAAD("a", "b\x00c")) {
+		t.Error("Expected entries with different names to have different AADs")
+	}
+}
+
+// TestDecryptRejectsLegacyFormat tests that values without a version prefix cannot be read in place of authenticated ones
+func TestDecryptRejectsLegacyFormat(t *testing.T) {
+	key := deriveLegacyKey("mysecretpassword")
+
+	legacy, err := encryptLegacy("Hello, World!", key)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if !isLegacyCiphertext(legacy) {
+		t.Fatalf("Expected %q to be detected as legacy ciphertext", legacy)
+	}
+	if _, err := decrypt(legacy, key, entryAAD("example.com", "user@example.com")); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("Expected ErrIntegrity for a value without a version prefix, got %v", err)
+	}
+}
+
+// encryptLegacy encrypts plaintext the way older versions did (AES-CFB, no prefix)
+func encryptLegacy(plaintext string, key []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCFBEncrypter(block, nonce).XORKeyStream(ciphertext, []byte(plaintext))
+	return hex.EncodeToString(nonce) + hex.EncodeToString(ciphertext), nil
+}
+
+func TestUnlockMasterKey(t *testing.T) {
+	store := setup(t)
+
+	setupKey(t, store)
+	key, err := UnlockMasterKey(store, "mysecretpassword")
+	if err != nil {
+		t.Fatalf("Failed to unlock master key: %v", err)
+	}
+	if len(key) != keyLength {
+		t.Errorf("Expected %d-byte key, got %d", keyLength, len(key))
+	}
+
+	// The key must not be derivable from anything stored in the database
+	masterPassword, err := store.Master()
+	if err != nil {
+		t.Fatalf("Failed to load master password: %v", err)
+	}
+	if masterPassword.HashedPassword != "" || masterPassword.Verifier == hex.EncodeToString(key) {
+		t.Error("Expected only a key verifier to be stored")
+	}
+
+	again, err := UnlockMasterKey(store, "mysecretpassword")
+	if err != nil || !equal(key, again) {
+		t.Errorf("Expected the same key on every unlock, got error: %v", err)
+	}
+
+	if _, err := UnlockMasterKey(store, "wrongpassword"); err == nil {
+		t.Error("Expected error unlocking with wrong password, got none")
+	}
+}
+
+func TestMigrateLegacyKey(t *testing.T) {
+	store := setup(t)
+
+	// Recreate a vault as written by older versions
+	hashed, err := bcrypt.GenerateFromPassword([]byte("mysecretpassword"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if err := store.SetMaster(&MasterPassword{HashedPassword: string(hashed)}); err != nil {
+		t.Fatalf("Failed to create legacy master password: %v", err)
+	}
+	legacyValue, err := encryptLegacy("mypassword", deriveLegacyKey(string(hashed)))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	legacyEntry := SensitiveData{Service: "example.com", Identifier: "user@example.com", Value: legacyValue, Kind: "login"}
+	if err := store.Add(&legacyEntry); err != nil {
+		t.Fatalf("Failed to create legacy entry: %v", err)
+	}
+
+	if _, err := UnlockMasterKey(store, "wrongpassword"); err == nil {
+		t.Error("Expected error unlocking legacy vault with wrong password, got none")
+	}
+
+	key, err := UnlockMasterKey(store, "mysecretpassword")
+	if err != nil {
+		t.Fatalf("Failed to migrate legacy vault: %v", err)
+	}
+
+	data, err := GetSensitiveData(store, NewLocalKeyring(key), "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get sensitive data after migration: %v", err)
+	}
+	if data.Value != "mypassword" {
+		t.Errorf("Expected migrated value 'mypassword', got %v", data.Value)
+	}
+
+	masterPassword, err := store.Master()
+	if err != nil {
+		t.Fatalf("Failed to load master password: %v", err)
+	}
+	if masterPassword.isLegacy() || masterPassword.KDF != KDFArgon2id {
+		t.Errorf("Expected master password to be migrated to %s, got %+v", KDFArgon2id, masterPassword)
+	}
+
+	stored, err := store.Get("example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to load entry: %v", err)
+	}
+	if isLegacyCiphertext(stored.Value) {
+		t.Error("Expected entry to be re-encrypted with the current ciphertext format")
+	}
+}
+
+func TestUpdateIdentifierKeepsValue(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "", "admin@example.com"); err != nil {
+		t.Fatalf("Failed to update identifier: %v", err)
+	}
+
+	data, err := GetSensitiveData(store, key, "example.com", "admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get renamed entry: %v", err)
+	}
+	if data.Value != "mypassword" {
+		t.Errorf("Expected value 'mypassword' after rename, got %v", data.Value)
+	}
+}
+
+func TestChangeMasterPassword(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := AddSensitiveData(store, key, "github.com", "octocat", "ghp_token", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+
+	var calls int
+	progress := func(done, total int) {
+		calls++
+		if total != 2 {
+			t.Errorf("Expected total of 2 entries, got %d", total)
+		}
+	}
+	if err := ChangeMasterPassword(store, "mysecretpassword", "newsecretpassword", progress); err != nil {
+		t.Fatalf("Failed to change master password: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected progress to be reported 2 times, got %d", calls)
+	}
+
+	if _, err := UnlockMasterKey(store, "mysecretpassword"); err == nil {
+		t.Error("Expected old master password to be rejected after change")
+	}
+	newKey, err := UnlockMasterKey(store, "newsecretpassword")
+	if err != nil {
+		t.Fatalf("Failed to unlock with new master password: %v", err)
+	}
+
+	data, err := GetSensitiveData(store, NewLocalKeyring(newKey), "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get sensitive data after password change: %v", err)
+	}
+	if data.Value != "mypassword" {
+		t.Errorf("Expected value 'mypassword' after password change, got %v", data.Value)
+	}
+}
+
+func TestChangeMasterPasswordRollsBack(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	// An entry that cannot be decrypted makes the change fail part-way
+	if err := store.Add(&SensitiveData{Service: "zzz.com", Identifier: "broken", Value: "v2:00", Kind: "login"}); err != nil {
+		t.Fatalf("Failed to create broken entry: %v", err)
+	}
+
+	if err := ChangeMasterPassword(store, "mysecretpassword", "newsecretpassword", nil); err == nil {
+		t.Fatal("Expected error changing master password with a corrupt entry, got none")
+	}
+
+	// The old password and values must still work
+	oldKey, err := UnlockMasterKey(store, "mysecretpassword")
+	if err != nil {
+		t.Fatalf("Expected old master password to remain valid: %v", err)
+	}
+	data, err := GetSensitiveData(store, NewLocalKeyring(oldKey), "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get sensitive data after rollback: %v", err)
+	}
+	if data.Value != "mypassword" {
+		t.Errorf("Expected value 'mypassword' after rollback, got %v", data.Value)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	store := setup(t)
+
+	if err := CheckMasterPasswordSet(store); !errors.Is(err, ErrNoMasterPassword) {
+		t.Errorf("Expected ErrNoMasterPassword before setup, got %v", err)
+	}
+	if _, err := UnlockMasterKey(store, "mysecretpassword"); !errors.Is(err, ErrNoMasterPassword) {
+		t.Errorf("Expected ErrNoMasterPassword from UnlockMasterKey, got %v", err)
+	}
+
+	key := setupKey(t, store)
+
+	if err := SetMasterPassword(store, "another"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate when setting the master password twice, got %v", err)
+	}
+	if _, err := UnlockMasterKey(store, "wrongpassword"); !errors.Is(err, ErrBadPassword) {
+		t.Errorf("Expected ErrBadPassword, got %v", err)
+	}
+	if err := ChangeMasterPassword(store, "wrongpassword", "new", nil); !errors.Is(err, ErrBadPassword) {
+		t.Errorf("Expected ErrBadPassword from ChangeMasterPassword, got %v", err)
+	}
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := AddSensitiveData(store, key, "Example.com", "User@example.com", "other", "login"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for an existing entry, got %v", err)
+	}
+	if err := AddSensitiveData(store, key, "example.com", "admin", "other", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := UpdateSensitiveData(store, key, "example.com", "admin", "", "user@example.com"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate when renaming onto an existing entry, got %v", err)
+	}
+
+	if _, err := GetSensitiveData(store, key, "missing.com", "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from GetSensitiveData, got %v", err)
+	}
+	if err := DeleteSensitiveData(store, "missing.com", "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from DeleteSensitiveData, got %v", err)
+	}
+	if err := UpdateSensitiveData(store, key, "missing.com", "nobody", "value", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from UpdateSensitiveData, got %v", err)
+	}
+}
+
+func TestUpdateRecordsHistory(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	// Updates that change nothing are not recorded
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "user@example.com"); err != nil {
+		t.Fatalf("Failed to update sensitive data: %v", err)
+	}
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "newpassword", "admin@example.com"); err != nil {
+		t.Fatalf("Failed to update sensitive data: %v", err)
+	}
+
+	history, err := GetSensitiveDataHistory(store, key, "example.com", "admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("Expected 1 previous value, got %d", len(history))
+	}
+	if history[0].Version != 1 || history[0].Identifier != "user@example.com" || history[0].Value != "mypassword" {
+		t.Errorf("Expected version 1 with the previous identifier and value, got %+v", history[0])
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "value0", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	updates := HistoryRetention + 2
+	for i := 1; i <= updates; i++ {
+		if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", fmt.Sprintf("value%d", i), ""); err != nil {
+			t.Fatalf("Failed to update sensitive data: %v", err)
+		}
+	}
+
+	history, err := GetSensitiveDataHistory(store, key, "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != HistoryRetention {
+		t.Fatalf("Expected %d previous values, got %d", HistoryRetention, len(history))
+	}
+	if last := history[len(history)-1]; last.Version != updates || last.Value != fmt.Sprintf("value%d", updates-1) {
+		t.Errorf("Expected the most recent versions to be kept, got %+v", last)
+	}
+}
+
+func TestHistoryRetentionZero(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "value0", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "value1", ""); err != nil {
+		t.Fatalf("Failed to update sensitive data: %v", err)
+	}
+
+	// With no retention, updates record nothing and keep the versions already recorded
+	defer func(retention int) { HistoryRetention = retention }(HistoryRetention)
+	HistoryRetention = 0
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "value2", ""); err != nil {
+		t.Fatalf("Failed to update sensitive data: %v", err)
+	}
+	history, err := GetSensitiveDataHistory(store, key, "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 || history[0].Value != "value0" {
+		t.Errorf("Expected only the version recorded before, got %+v", history)
+	}
+}
+
+func TestValueUpdatedAt(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "value0", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	added, err := store.Get("example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to load entry: %v", err)
+	}
+	if added.ValueUpdatedAt.IsZero() {
+		t.Fatal("Expected a new entry to record when its value was set")
+	}
+
+	// Changing the details or tags does not change when the value was set
+	time.Sleep(10 * time.Millisecond)
+	if err := SetEntryDetails(store, key, "example.com", "user@example.com", EntryDetails{Notes: "n"}, []string{"work"}); err != nil {
+		t.Fatalf("Failed to set entry details: %v", err)
+	}
+	entry, err := store.Get("example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to load entry: %v", err)
+	}
+	if !entry.ValueUpdatedAt.Equal(added.ValueUpdatedAt) || !entry.UpdatedAt.After(added.UpdatedAt) {
+		t.Errorf("Expected only UpdatedAt to change with the details, got %v and %v", entry.ValueUpdatedAt, entry.UpdatedAt)
+	}
+
+	// The history records when the replaced value was set, not when the entry was last changed
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "value1", ""); err != nil {
+		t.Fatalf("Failed to update sensitive data: %v", err)
+	}
+	history, err := GetSensitiveDataHistory(store, key, "example.com", "user@example.com")
+	if err != nil || len(history) != 1 {
+		t.Fatalf("Expected 1 previous value, got %+v, %v", history, err)
+	}
+	if !history[0].ValueSetAt.Equal(added.ValueUpdatedAt) {
+		t.Errorf("Expected the previous value to be set at %v, got %v", added.ValueUpdatedAt, history[0].ValueSetAt)
+	}
+	if entry, _ = store.Get("example.com", "user@example.com"); !entry.ValueUpdatedAt.After(added.ValueUpdatedAt) {
+		t.Errorf("Expected a new value to update ValueUpdatedAt, got %v", entry.ValueUpdatedAt)
+	}
+}
+
+func TestRestoreSensitiveData(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "mistyped", "admin@example.com"); err != nil {
+		t.Fatalf("Failed to update sensitive data: %v", err)
+	}
+
+	if err := RestoreSensitiveData(store, key, "example.com", "admin@example.com", 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown version, got %v", err)
+	}
+	// The value recorded under the old identifier is re-encrypted for the current one
+	if err := RestoreSensitiveData(store, key, "example.com", "admin@example.com", 1); err != nil {
+		t.Fatalf("Failed to restore sensitive data: %v", err)
+	}
+	data, err := GetSensitiveData(store, key, "example.com", "admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get restored entry: %v", err)
+	}
+	if data.Value != "mypassword" {
+		t.Errorf("Expected restored value 'mypassword', got %v", data.Value)
+	}
+
+	// The replaced value is kept so the restore can be undone
+	history, err := GetSensitiveDataHistory(store, key, "example.com", "admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 2 || history[1].Value != "mistyped" {
+		t.Errorf("Expected the replaced value as version 2, got %+v", history)
+	}
+}
+
+func TestChangeMasterPasswordReencryptsHistory(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "newpassword", ""); err != nil {
+		t.Fatalf("Failed to update sensitive data: %v", err)
+	}
+	if err := ChangeMasterPassword(store, "mysecretpassword", "newsecretpassword", nil); err != nil {
+		t.Fatalf("Failed to change master password: %v", err)
+	}
+
+	newKey, err := UnlockMasterKey(store, "newsecretpassword")
+	if err != nil {
+		t.Fatalf("Failed to unlock with new master password: %v", err)
+	}
+	history, err := GetSensitiveDataHistory(store, NewLocalKeyring(newKey), "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get history after password change: %v", err)
+	}
+	if len(history) != 1 || history[0].Value != "mypassword" {
+		t.Errorf("Expected the previous value after password change, got %+v", history)
+	}
+}
+
+func TestChangeMasterPasswordReencryptsTrash(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	if err := AddSensitiveData(store, key, "example.com", "user@example.com", "mypassword", "login"); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	if err := DeleteSensitiveData(store, "example.com", "user@example.com"); err != nil {
+		t.Fatalf("Failed to delete sensitive data: %v", err)
+	}
+	if err := ChangeMasterPassword(store, "mysecretpassword", "newsecretpassword", nil); err != nil {
+		t.Fatalf("Failed to change master password: %v", err)
+	}
+
+	if _, err := store.RestoreTrashed("example.com", "user@example.com"); err != nil {
+		t.Fatalf("Failed to restore entry from the trash: %v", err)
+	}
+	newKey, err := UnlockMasterKey(store, "newsecretpassword")
+	if err != nil {
+		t.Fatalf("Failed to unlock with new master password: %v", err)
+	}
+	data, err := GetSensitiveData(store, NewLocalKeyring(newKey), "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get restored entry after password change: %v", err)
+	}
+	if data.Value != "mypassword" {
+		t.Errorf("Expected value 'mypassword', got %v", data.Value)
+	}
+}
+
+func TestEntryDetails(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	details := EntryDetails{
+		Notes:  "recovery codes in the safe",
+		URLs:   []string{"https://example.com/login"},
+		Fields: []Field{{Name: "region", Value: "us-east-1"}, {Name: "pin", Value: "1234", Secret: true}},
+		OTP:    "otpauth://totp/Example:user?secret=JBSWY3DPEHPK3PXP",
+	}
+	err := AddSensitiveDataWithDetails(store, key, "example.com", "user@example.com", "mypassword", "login", details, []string{"Prod", "web", "prod"})
+	if err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+
+	raw, _ := store.Get("example.com", "user@example.com")
+	if strings.Contains(raw.Metadata, "us-east-1") || strings.Contains(raw.Metadata, "JBSWY3DPEHPK3PXP") || raw.Tags != "prod,web" {
+		t.Errorf("Expected encrypted details and normalized tags, got %q and %q", raw.Metadata, raw.Tags)
+	}
+
+	// Details follow the entry when it is renamed and when the master password changes
+	if err := UpdateSensitiveData(store, key, "example.com", "user@example.com", "", "admin@example.com"); err != nil {
+		t.Fatalf("Failed to rename entry: %v", err)
+	}
+	if err := ChangeMasterPassword(store, "mysecretpassword", "newsecretpassword", nil); err != nil {
+		t.Fatalf("Failed to change master password: %v", err)
+	}
+	newKey, err := UnlockMasterKey(store, "newsecretpassword")
+	if err != nil {
+		t.Fatalf("Failed to unlock with new master password: %v", err)
+	}
+
+	data, err := GetSensitiveData(store, NewLocalKeyring(newKey), "example.com", "admin@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get sensitive data: %v", err)
+	}
+	if data.Details.Notes != details.Notes || len(data.Details.URLs) != 1 || len(data.Details.Fields) != 2 || !data.Details.Fields[1].Secret || data.Details.OTP != details.OTP {
+		t.Errorf("Expected the stored details, got %+v", data.Details)
+	}
+	if !data.HasTags([]string{"PROD"}) || data.HasTags([]string{"prod", "db"}) {
+		t.Errorf("Unexpected tag matching for tags %q", data.Tags)
+	}
+
+	if err := SetEntryDetails(store, NewLocalKeyring(newKey), "example.com", "admin@example.com", EntryDetails{}, nil); err != nil {
+		t.Fatalf("Failed to clear details: %v", err)
+	}
+	raw, _ = store.Get("example.com", "admin@example.com")
+	if raw.Metadata != "" || raw.Tags != "" {
+		t.Errorf("Expected no details after clearing, got %q and %q", raw.Metadata, raw.Tags)
+	}
+}
+
+func TestReplaceSensitiveData(t *testing.T) {
+	store := setup(t)
+
+	key := setupKey(t, store)
+
+	details := EntryDetails{Notes: "old notes"}
+	if err := AddSensitiveDataWithDetails(store, key, "example.com", "user@example.com", "mypassword", "login", details, []string{"web"}); err != nil {
+		t.Fatalf("Failed to add sensitive data: %v", err)
+	}
+	details = EntryDetails{URLs: []string{"https://example.com"}}
+	if err := ReplaceSensitiveData(store, key, "Example.com", "user@example.com", "newpassword", "API_KEY", details, []string{"imported"}); err != nil {
+		t.Fatalf("Failed to replace sensitive data: %v", err)
+	}
+
+	data, err := GetSensitiveData(store, key, "example.com", "user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get sensitive data: %v", err)
+	}
+	if data.Value != "newpassword" || data.Kind != "api_key" || data.Details.Notes != "" || len(data.Details.URLs) != 1 || data.Tags != "imported" {
+		t.Errorf("Expected the replaced entry, got %+v", data)
+	}
+
+	// Only a changed value is recorded in the history
+	if err := ReplaceSensitiveData(store, key, "example.com", "user@example.com", "newpassword", "login", EntryDetails{}, nil); err != nil {
+		t.Fatalf("Failed to replace sensitive data: %v", err)
+	}
+	history, err := GetSensitiveDataHistory(store, key, "example.com", "user@example.com")
+	if err != nil || len(history) != 1 || history[0].Value != "mypassword" {
+		t.Errorf("Expected the replaced value in the history, got %+v, %v", history, err)
+	}
+
+	if err := ReplaceSensitiveData(store, key, "example.com", "nobody", "x", "login", EntryDetails{}, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Web", "prod", "web"})
+	if err != nil || strings.Join(tags, ",") != "prod,web" {
+		t.Errorf("NormalizeTags() = %v, %v", tags, err)
+	}
+	for _, invalid := range []string{"", "a,b"} {
+		if _, err := NormalizeTags([]string{invalid}); err == nil {
+			t.Errorf("Expected an error for tag %q", invalid)
+		}
+	}
+}
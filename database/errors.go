@@ -0,0 +1,14 @@
+package database
+
+import "errors"
+
+// Sentinel errors returned by the database package. Wrapped errors add context
+// such as the service and identifier; test for them with errors.Is.
+var (
+	ErrNotFound         = errors.New("entry not found")
+	ErrLocked           = errors.New("vault is locked. Please unlock the vault using `unlock`")
+	ErrDuplicate        = errors.New("entry already exists")
+	ErrNoMasterPassword = errors.New("please set the master password first using 'set-master'")
+	ErrBadPassword      = errors.New("invalid master password")
+	ErrSchemaTooNew     = errors.New("vault was created by a newer version of vault-cli")
+)
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KDFArgon2id is the key derivation function used for new vaults
+const KDFArgon2id = "argon2id"
+
+const (
+	keyLength  = 32 // AES-256
+	saltLength = 16
+)
+
+// KDFParams holds the tunable cost parameters for Argon2id
+type KDFParams struct {
+	Time    uint32 // Number of passes over memory
+	Memory  uint32 // Memory cost in KiB
+	Threads uint8  // Degree of parallelism
+}
+
+// DefaultKDFParams are the parameters used when a master password is set
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Validate ensures the parameters are usable by Argon2id
+func (p KDFParams) Validate() error {
+	if p.Time < 1 {
+		return fmt.Errorf("invalid KDF time cost: %d", p.Time)
+	}
+	if p.Memory < 8*uint32(p.Threads) || p.Memory == 0 {
+		return fmt.Errorf("invalid KDF memory cost: %d KiB", p.Memory)
+	}
+	if p.Threads < 1 {
+		return fmt.Errorf("invalid KDF parallelism: %d", p.Threads)
+	}
+	return nil
+}
+
+// newSalt generates a random per-vault salt
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// deriveKey turns the master password into a 32-byte entry encryption key
+func deriveKey(password string, salt []byte, p KDFParams) []byte {
+	return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keyLength)
+}
+
+// keyVerifier returns a value that proves knowledge of the key without revealing it
+func keyVerifier(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("vault-cli key verifier"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkKeyVerifier compares the key against a stored verifier in constant time
+func checkKeyVerifier(key []byte, verifier string) bool {
+	return subtle.ConstantTimeCompare([]byte(keyVerifier(key)), []byte(verifier)) == 1
+}
+
+// newMasterPassword derives a fresh key for password and returns the row to store and the key
+func newMasterPassword(password string, p KDFParams) (MasterPassword, []byte, error) {
+	if err := p.Validate(); err != nil {
+		return MasterPassword{}, nil, err
+	}
+
+	salt, err := newSalt()
+	if err != nil {
+		return MasterPassword{}, nil, err
+	}
+
+	key := deriveKey(password, salt, p)
+	masterPassword := MasterPassword{
+		KDF:        KDFArgon2id,
+		Salt:       hex.EncodeToString(salt),
+		KDFTime:    p.Time,
+		KDFMemory:  p.Memory,
+		KDFThreads: p.Threads,
+		Verifier:   keyVerifier(key),
+	}
+	return masterPassword, key, nil
+}
+
+// deriveMasterKey derives the key for password using the parameters stored in masterPassword
+func deriveMasterKey(masterPassword MasterPassword, password string) ([]byte, bool, error) {
+	if masterPassword.KDF != KDFArgon2id {
+		return nil, false, fmt.Errorf("unsupported key derivation function: %q", masterPassword.KDF)
+	}
+
+	salt, err := hex.DecodeString(masterPassword.Salt)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid salt: %v", err)
+	}
+
+	params := KDFParams{Time: masterPassword.KDFTime, Memory: masterPassword.KDFMemory, Threads: masterPassword.KDFThreads}
+	if err := params.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	key := deriveKey(password, salt, params)
+	if !checkKeyVerifier(key, masterPassword.Verifier) {
+		return nil, false, nil
+	}
+	return key, true, nil
+}
+
+// isLegacy reports whether the vault still uses the bcrypt-hash derived key
+func (m MasterPassword) isLegacy() bool {
+	return m.KDF == "" && m.HashedPassword != ""
+}
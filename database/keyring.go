@@ -0,0 +1,36 @@
+package database
+
+// Keyring encrypts and decrypts entry values. The key itself may be held by
+// another process, such as the unlock agent.
+type Keyring interface {
+	Encrypt(plaintext string, aad []byte) (string, error)
+	Decrypt(ciphertext string, aad []byte) (string, error)
+}
+
+// LocalKeyring is a Keyring that holds the derived key in this process
+type LocalKeyring struct {
+	key []byte
+}
+
+// NewLocalKeyring returns a Keyring for the given derived key
+func NewLocalKeyring(key []byte) *LocalKeyring {
+	return &LocalKeyring{key: key}
+}
+
+// Encrypt encrypts plaintext bound to aad
+func (k *LocalKeyring) Encrypt(plaintext string, aad []byte) (string, error) {
+	return encrypt(plaintext, k.key, aad)
+}
+
+// Decrypt decrypts ciphertext bound to aad
+func (k *LocalKeyring) Decrypt(ciphertext string, aad []byte) (string, error) {
+	return decrypt(ciphertext, k.key, aad)
+}
+
+// Wipe overwrites the key in memory. The keyring cannot be used afterwards.
+func (k *LocalKeyring) Wipe() {
+	for i := range k.key {
+		k.key[i] = 0
+	}
+	k.key = nil
+}
@@ -0,0 +1,371 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore keeps a vault in memory. It is used in tests and for vaults that do not exist on disk yet.
+type MemoryStore struct {
+	mu       sync.Mutex
+	location string
+	entries  map[uint]SensitiveData
+	master   *MasterPassword
+	policies map[string]PasswordPolicy // Keyed by lower case service
+	history  []SensitiveDataHistory
+	nextID   uint
+}
+
+// NewMemoryStore returns an empty store identified by location
+func NewMemoryStore(location string) *MemoryStore {
+	return &MemoryStore{location: location, entries: make(map[uint]SensitiveData), policies: make(map[string]PasswordPolicy), nextID: 1}
+}
+
+// find returns the ID of the entry not in the trash matching service and identifier ignoring case. Callers hold mu.
+func (m *MemoryStore) find(service, identifier string) (uint, bool) {
+	for id, entry := range m.entries {
+		if !entry.DeletedAt.Valid && matches(entry, service, identifier) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// matches reports whether entry has the given service and identifier, ignoring case
+func matches(entry SensitiveData, service, identifier string) bool {
+	return strings.EqualFold(entry.Service, service) && strings.EqualFold(entry.Identifier, identifier)
+}
+
+func (m *MemoryStore) Add(entry *SensitiveData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.find(entry.Service, entry.Identifier); exists {
+		return fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+	}
+
+	now := time.Now()
+	entry.ID = m.nextID
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	m.nextID++
+	m.entries[entry.ID] = *entry
+	return nil
+}
+
+func (m *MemoryStore) Get(service, identifier string) (SensitiveData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok := m.find(service, identifier)
+	if !ok {
+		return SensitiveData{}, fmt.Errorf("%w: no data found for service '%s' and identifier '%s'", ErrNotFound, service, identifier)
+	}
+	return m.entries[id], nil
+}
+
+func (m *MemoryStore) List(kind string) ([]SensitiveData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := []SensitiveData{}
+	for _, entry := range m.entries {
+		if !entry.DeletedAt.Valid && (kind == "" || entry.Kind == kind) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+func (m *MemoryStore) Update(entry *SensitiveData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, ok := m.entries[entry.ID]
+	if !ok || current.DeletedAt.Valid != entry.DeletedAt.Valid {
+		return fmt.Errorf("%w: no entry found for service '%s' and identifier '%s'", ErrNotFound, entry.Service, entry.Identifier)
+	}
+	if id, exists := m.find(entry.Service, entry.Identifier); exists && id != entry.ID && !entry.DeletedAt.Valid {
+		return fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+	}
+
+	entry.CreatedAt = current.CreatedAt
+	entry.UpdatedAt = time.Now()
+	m.entries[entry.ID] = *entry
+	return nil
+}
+
+func (m *MemoryStore) Delete(service, identifier string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok := m.find(service, identifier)
+	if !ok {
+		return fmt.Errorf("%w: no entry found for service '%s' and identifier '%s'", ErrNotFound, service, identifier)
+	}
+	entry := m.entries[id]
+	entry.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	m.entries[id] = entry
+	return nil
+}
+
+func (m *MemoryStore) Trash() ([]SensitiveData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.trash(), nil
+}
+
+// trash returns the entries in the trash ordered by when they were deleted. Callers hold mu.
+func (m *MemoryStore) trash() []SensitiveData {
+	entries := []SensitiveData{}
+	for _, entry := range m.entries {
+		if entry.DeletedAt.Valid {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Time.Equal(entries[j].DeletedAt.Time) {
+			return entries[i].DeletedAt.Time.Before(entries[j].DeletedAt.Time)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+func (m *MemoryStore) RestoreTrashed(service, identifier string) (SensitiveData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	trashed := m.trash()
+	for i := len(trashed) - 1; i >= 0; i-- {
+		entry := trashed[i]
+		if !matches(entry, service, identifier) {
+			continue
+		}
+		if _, exists := m.find(entry.Service, entry.Identifier); exists {
+			return SensitiveData{}, fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+		}
+		entry.DeletedAt = gorm.DeletedAt{}
+		m.entries[entry.ID] = entry
+		return entry, nil
+	}
+	return SensitiveData{}, fmt.Errorf("%w: no entry in the trash for service '%s' and identifier '%s'", ErrNotFound, service, identifier)
+}
+
+func (m *MemoryStore) Purge(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.entries[id]; !ok {
+		return fmt.Errorf("%w: no entry %d", ErrNotFound, id)
+	}
+	m.purge(id)
+	return nil
+}
+
+// purge removes an entry and its history. Callers hold mu.
+func (m *MemoryStore) purge(id uint) {
+	delete(m.entries, id)
+	m.history = slices.DeleteFunc(m.history, func(record SensitiveDataHistory) bool { return record.EntryID == id })
+}
+
+func (m *MemoryStore) PurgeTrash(deletedBefore time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := 0
+	for _, entry := range m.trash() {
+		if entry.DeletedAt.Time.Before(deletedBefore) {
+			m.purge(entry.ID)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (m *MemoryStore) AddHistory(record *SensitiveDataHistory) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	latest := 0
+	for _, existing := range m.history {
+		if existing.EntryID == record.EntryID && existing.Version > latest {
+			latest = existing.Version
+		}
+	}
+	now := time.Now()
+	record.ID = m.nextID
+	record.Version = latest + 1
+	record.CreatedAt = now
+	record.UpdatedAt = now
+	m.nextID++
+	m.history = append(m.history, *record)
+	return nil
+}
+
+func (m *MemoryStore) History(entryID uint) ([]SensitiveDataHistory, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := []SensitiveDataHistory{}
+	for _, record := range m.history {
+		if record.EntryID == entryID {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	return records, nil
+}
+
+func (m *MemoryStore) UpdateHistory(record *SensitiveDataHistory) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, existing := range m.history {
+		if existing.ID == record.ID {
+			record.CreatedAt = existing.CreatedAt
+			record.UpdatedAt = time.Now()
+			m.history[i] = *record
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no history record %d", ErrNotFound, record.ID)
+}
+
+func (m *MemoryStore) PruneHistory(entryID uint, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var versions []int
+	for _, record := range m.history {
+		if record.EntryID == entryID {
+			versions = append(versions, record.Version)
+		}
+	}
+	if len(versions) <= keep {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	cutoff := versions[keep]
+	m.history = slices.DeleteFunc(m.history, func(record SensitiveDataHistory) bool {
+		return record.EntryID == entryID && record.Version <= cutoff
+	})
+	return nil
+}
+
+func (m *MemoryStore) Master() (MasterPassword, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.master == nil {
+		return MasterPassword{}, ErrNoMasterPassword
+	}
+	return *m.master, nil
+}
+
+func (m *MemoryStore) SetMaster(masterPassword *MasterPassword) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *masterPassword
+	stored.ID = 1
+	m.master = &stored
+	return nil
+}
+
+func (m *MemoryStore) Policy(service string) (PasswordPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	policy, ok := m.policies[strings.ToLower(service)]
+	if !ok {
+		return PasswordPolicy{}, fmt.Errorf("%w: no password policy for service '%s'", ErrNotFound, service)
+	}
+	return policy, nil
+}
+
+func (m *MemoryStore) Policies() ([]PasswordPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	policies := []PasswordPolicy{}
+	for _, policy := range m.policies {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return strings.ToLower(policies[i].Service) < strings.ToLower(policies[j].Service)
+	})
+	return policies, nil
+}
+
+func (m *MemoryStore) SetPolicy(policy *PasswordPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	policy.ID = m.nextID
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+	m.nextID++
+	m.policies[strings.ToLower(policy.Service)] = *policy
+	return nil
+}
+
+func (m *MemoryStore) DeletePolicy(service string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.ToLower(service)
+	if _, ok := m.policies[key]; !ok {
+		return fmt.Errorf("%w: no password policy for service '%s'", ErrNotFound, service)
+	}
+	delete(m.policies, key)
+	return nil
+}
+
+func (m *MemoryStore) State() (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	trashed := len(m.trash())
+	return State{Location: m.location, MasterPasswordSet: m.master != nil, Entries: len(m.entries) - trashed, Trashed: trashed}, nil
+}
+
+// Transaction runs fn and restores the previous contents if it fails.
+// Changes made concurrently by other goroutines are not isolated from fn.
+func (m *MemoryStore) Transaction(fn func(tx Store) error) error {
+	m.mu.Lock()
+	entries := make(map[uint]SensitiveData, len(m.entries))
+	for id, entry := range m.entries {
+		entries[id] = entry
+	}
+	policies := make(map[string]PasswordPolicy, len(m.policies))
+	for key, policy := range m.policies {
+		policies[key] = policy
+	}
+	history := slices.Clone(m.history)
+	master, nextID := m.master, m.nextID
+	m.mu.Unlock()
+
+	if err := fn(m); err != nil {
+		m.mu.Lock()
+		m.entries, m.policies, m.history, m.master, m.nextID = entries, policies, history, master, nextID
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+var _ Store = (*MemoryStore)(nil)
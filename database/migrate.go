@@ -0,0 +1,228 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a schema change applied in order of Version
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// AppliedMigration records a migration in the schema_version table
+type AppliedMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName keeps the table name singular, as it describes the schema as a whole
+func (AppliedMigration) TableName() string {
+	return "schema_version"
+}
+
+// SchemaStatus describes the schema of a vault database
+type SchemaStatus struct {
+	Current  int                // Highest applied migration, 0 for a new or unversioned vault
+	Latest   int                // Highest migration known to this binary
+	Legacy   bool               // The vault was created before schema versioning
+	Applied  []AppliedMigration // Migrations recorded in the vault
+	Pending  []Migration        // Migrations not yet applied
+	Existing bool               // The vault already held tables before this check
+}
+
+// migrations returns the embedded migrations ordered by version
+func migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Migration
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".sql")
+		number, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+		sql, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Migration{Version: version, Name: name, SQL: string(sql)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	for i, migration := range result {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be consecutive, found %d at position %d", migration.Version, i+1)
+		}
+	}
+	return result, nil
+}
+
+// schemaStatus inspects the schema of conn
+func schemaStatus(conn *gorm.DB) (SchemaStatus, error) {
+	all, err := migrations()
+	if err != nil {
+		return SchemaStatus{}, err
+	}
+	status := SchemaStatus{Latest: len(all)}
+
+	migrator := conn.Migrator()
+	status.Existing = migrator.HasTable(&SensitiveData{}) || migrator.HasTable(&MasterPassword{})
+	if migrator.HasTable(&AppliedMigration{}) {
+		if err := conn.Order("version").Find(&status.Applied).Error; err != nil {
+			return SchemaStatus{}, fmt.Errorf("failed to read schema version: %w", err)
+		}
+	} else {
+		status.Legacy = status.Existing
+	}
+
+	applied := make(map[int]bool)
+	for _, migration := range status.Applied {
+		applied[migration.Version] = true
+		if migration.Version > status.Current {
+			status.Current = migration.Version
+		}
+	}
+	for _, migration := range all {
+		if !applied[migration.Version] {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+
+	if status.Current > status.Latest {
+		return status, fmt.Errorf("%w: the vault uses schema version %d, but this version of vault-cli only supports up to %d; upgrade vault-cli",
+			ErrSchemaTooNew, status.Current, status.Latest)
+	}
+	return status, nil
+}
+
+// migrate brings the schema of the vault stored at path up to date. If the vault already
+// holds data, it is backed up first; the backup path is returned.
+func migrate(conn *gorm.DB, path string) (SchemaStatus, string, error) {
+	status, err := schemaStatus(conn)
+	if err != nil || len(status.Pending) == 0 {
+		return status, "", err
+	}
+
+	var backupPath string
+	if status.Existing {
+		if backupPath, err = backupVault(conn, path, status.Current); err != nil {
+			return status, "", fmt.Errorf("failed to back up the vault before migrating: %w", err)
+		}
+	}
+
+	if status.Legacy {
+		if err := adoptLegacySchema(conn); err != nil {
+			return status, backupPath, fmt.Errorf("failed to upgrade unversioned vault: %w", err)
+		}
+	}
+	if err := conn.AutoMigrate(&AppliedMigration{}); err != nil {
+		return status, backupPath, err
+	}
+
+	for _, migration := range status.Pending {
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.SQL).Error; err != nil {
+				return err
+			}
+			return tx.Create(&AppliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return status, backupPath, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		status.Applied = append(status.Applied, AppliedMigration{Version: migration.Version, Name: migration.Name})
+		status.Current = migration.Version
+	}
+	status.Pending = nil
+	return status, backupPath, nil
+}
+
+// adoptLegacySchema adds the master password columns introduced before schema versioning,
+// so that migration 1 finds the tables it would have created
+func adoptLegacySchema(conn *gorm.DB) error {
+	migrator := conn.Migrator()
+	if !migrator.HasTable(&MasterPassword{}) {
+		return nil
+	}
+	columns := []struct{ name, sqlType string }{
+		{"kdf", "text"},
+		{"salt", "text"},
+		{"kdf_time", "integer"},
+		{"kdf_memory", "integer"},
+		{"kdf_threads", "integer"},
+		{"verifier", "text"},
+	}
+	for _, column := range columns {
+		if migrator.HasColumn(&MasterPassword{}, column.name) {
+			continue
+		}
+		if err := conn.Exec(fmt.Sprintf("ALTER TABLE `master_passwords` ADD COLUMN `%s` %s", column.name, column.sqlType)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupPathFor returns the file a vault at schema version is backed up to before migrating
+func backupPathFor(path string, version int, now time.Time) string {
+	return fmt.Sprintf("%s.schema%d-%s.bak", path, version, now.Format("20060102-150405"))
+}
+
+// backupVault writes a consistent copy of the vault with owner-only permissions
+func backupVault(conn *gorm.DB, path string, version int) (string, error) {
+	backupPath := backupPathFor(path, version, time.Now())
+	if err := conn.Exec("VACUUM INTO ?", backupPath).Error; err != nil {
+		return "", err
+	}
+	if err := os.Chmod(backupPath, 0600); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// SchemaInfo reports the schema status of the vault at path without changing it
+func SchemaInfo(path string) (SchemaStatus, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return SchemaStatus{}, fmt.Errorf("vault %s does not exist", path)
+	}
+	conn, err := openSQLite(path)
+	if err != nil {
+		return SchemaStatus{}, err
+	}
+	defer closeSQLite(conn)
+	return schemaStatus(conn)
+}
+
+// MigrateVault applies pending migrations to the vault at path after backing it up.
+// It returns the resulting status and the backup path, if a backup was made.
+func MigrateVault(path string) (SchemaStatus, string, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return SchemaStatus{}, "", fmt.Errorf("vault %s does not exist", path)
+	}
+	conn, err := openSQLite(path)
+	if err != nil {
+		return SchemaStatus{}, "", err
+	}
+	defer closeSQLite(conn)
+	return migrate(conn, path)
+}
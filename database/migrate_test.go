@@ -0,0 +1,133 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	all, err := migrations()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if len(all) == 0 || all[0].Version != 1 {
+		t.Fatalf("Expected migrations starting at version 1, got %+v", all)
+	}
+}
+
+func TestOpenNewVaultAppliesAllMigrations(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vault.db")
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	store.Close()
+
+	status, err := SchemaInfo(path)
+	if err != nil {
+		t.Fatalf("SchemaInfo failed: %v", err)
+	}
+	if status.Current != status.Latest || len(status.Pending) != 0 || status.Legacy {
+		t.Errorf("Expected an up-to-date schema, got %+v", status)
+	}
+
+	// A new vault has nothing worth backing up
+	if backups, _ := filepath.Glob(path + ".*.bak"); len(backups) != 0 {
+		t.Errorf("Expected no backup for a new vault, got %v", backups)
+	}
+}
+
+func TestMigrateLegacyVault(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vault.db")
+
+	// Recreate the schema written by AutoMigrate in the first release
+	conn, err := openSQLite(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	for _, statement := range []string{
+		"CREATE TABLE `sensitive_data` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`service` text,`identifier` text,`value` text,`identifier_type` text)",
+		"CREATE UNIQUE INDEX `idx_service_identifier` ON `sensitive_data`(`service`,`identifier`)",
+		"CREATE TABLE `master_passwords` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`hashed_password` text)",
+		"CREATE TABLE `vault_states` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`is_locked` numeric)",
+		"INSERT INTO `master_passwords` (`hashed_password`) VALUES ('$2a$10$hash')",
+		"INSERT INTO `sensitive_data` (`service`,`identifier`,`value`,`identifier_type`) VALUES ('example.com','me','00ff','username')",
+		"INSERT INTO `sensitive_data` (`service`,`identifier`,`value`,`identifier_type`) VALUES ('aws','AKIA','00aa','secret_key')",
+	} {
+		if err := conn.Exec(statement).Error; err != nil {
+			t.Fatalf("Failed to create legacy schema: %v", err)
+		}
+	}
+	closeSQLite(conn)
+
+	status, err := SchemaInfo(path)
+	if err != nil {
+		t.Fatalf("SchemaInfo failed: %v", err)
+	}
+	if !status.Legacy || status.Current != 0 || len(status.Pending) != status.Latest {
+		t.Errorf("Expected an unversioned vault with every migration pending, got %+v", status)
+	}
+
+	status, backupPath, err := MigrateVault(path)
+	if err != nil {
+		t.Fatalf("MigrateVault failed: %v", err)
+	}
+	if status.Current != status.Latest {
+		t.Errorf("Expected schema version %d, got %d", status.Latest, status.Current)
+	}
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		t.Fatalf("Expected a backup at %q: %v", backupPath, err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected backup mode 0600, got %v", info.Mode().Perm())
+	}
+
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open migrated store: %v", err)
+	}
+	defer store.Close()
+
+	entry, err := store.Get("example.com", "me")
+	if err != nil || entry.Value != "00ff" {
+		t.Errorf("Expected the entry to survive the migration, got %+v, %v", entry, err)
+	}
+	if entry.Kind != "login" {
+		t.Errorf("Expected the username entry to become a login, got kind %q", entry.Kind)
+	}
+	if key, err := store.Get("aws", "AKIA"); err != nil || key.Kind != "api_key" {
+		t.Errorf("Expected the secret key entry to become an api_key, got %+v, %v", key, err)
+	}
+	master, err := store.Master()
+	if err != nil || master.HashedPassword != "$2a$10$hash" || !master.isLegacy() {
+		t.Errorf("Expected the legacy master password to survive the migration, got %+v, %v", master, err)
+	}
+	if store.db.Migrator().HasTable("vault_states") {
+		t.Error("Expected the vault_states table to be dropped")
+	}
+}
+
+func TestRefuseNewerSchema(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vault.db")
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	if err := store.db.Create(&AppliedMigration{Version: 999, Name: "from_the_future"}).Error; err != nil {
+		t.Fatalf("Failed to record migration: %v", err)
+	}
+	store.Close()
+
+	if _, err := OpenSQLiteStore(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+	if _, _, err := MigrateVault(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew from MigrateVault, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+-- Entries and the master password record, as created by earlier versions with AutoMigrate
+CREATE TABLE IF NOT EXISTS `sensitive_data` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`created_at` datetime,
+	`updated_at` datetime,
+	`deleted_at` datetime,
+	`service` text,
+	`identifier` text,
+	`value` text,
+	`identifier_type` text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_service_identifier` ON `sensitive_data`(`service`,`identifier`);
+CREATE INDEX IF NOT EXISTS `idx_sensitive_data_deleted_at` ON `sensitive_data`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `master_passwords` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`created_at` datetime,
+	`updated_at` datetime,
+	`deleted_at` datetime,
+	`hashed_password` text,
+	`kdf` text,
+	`salt` text,
+	`kdf_time` integer,
+	`kdf_memory` integer,
+	`kdf_threads` integer,
+	`verifier` text
+);
+CREATE INDEX IF NOT EXISTS `idx_master_passwords_deleted_at` ON `master_passwords`(`deleted_at`);
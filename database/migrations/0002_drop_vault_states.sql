@@ -0,0 +1,2 @@
+-- The lock state is held by the unlock agent, not the database
+DROP TABLE IF EXISTS `vault_states`;
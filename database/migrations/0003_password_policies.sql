@@ -0,0 +1,16 @@
+-- Password policies used when generating and checking values for a service
+CREATE TABLE IF NOT EXISTS `password_policies` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`created_at` datetime,
+	`updated_at` datetime,
+	`deleted_at` datetime,
+	`service` text,
+	`length` integer,
+	`min_length` integer,
+	`max_length` integer,
+	`charset` text,
+	`require` text,
+	`forbid` text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_password_policies_service` ON `password_policies`(`service`);
+CREATE INDEX IF NOT EXISTS `idx_password_policies_deleted_at` ON `password_policies`(`deleted_at`);
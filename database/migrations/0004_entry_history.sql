@@ -0,0 +1,15 @@
+-- Previous values of entries, kept so that updates can be rolled back
+CREATE TABLE IF NOT EXISTS `sensitive_data_histories` (
+	`id` integer PRIMARY KEY AUTOINCREMENT,
+	`created_at` datetime,
+	`updated_at` datetime,
+	`deleted_at` datetime,
+	`entry_id` integer,
+	`version` integer,
+	`service` text,
+	`identifier` text,
+	`value` text,
+	`value_set_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_history_entry_version` ON `sensitive_data_histories`(`entry_id`,`version`);
+CREATE INDEX IF NOT EXISTS `idx_sensitive_data_histories_deleted_at` ON `sensitive_data_histories`(`deleted_at`);
@@ -0,0 +1,3 @@
+-- Deleted entries are kept in the trash, so only entries not in it must be unique
+DROP INDEX IF EXISTS `idx_service_identifier`;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_service_identifier` ON `sensitive_data`(`service`,`identifier`) WHERE `deleted_at` IS NULL;
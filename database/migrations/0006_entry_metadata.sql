@@ -0,0 +1,3 @@
+-- Encrypted notes, URLs and custom fields, and plain text tags used to filter entries
+ALTER TABLE `sensitive_data` ADD COLUMN `metadata` text;
+ALTER TABLE `sensitive_data` ADD COLUMN `tags` text;
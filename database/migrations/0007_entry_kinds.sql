@@ -0,0 +1,4 @@
+-- Entry kinds replace identifier types; usernames and emails become logins, API and secret keys api_key entries
+ALTER TABLE `sensitive_data` ADD COLUMN `kind` text;
+UPDATE `sensitive_data` SET `kind` = CASE WHEN `identifier_type` IN ('api_key', 'secret_key') THEN 'api_key' ELSE 'login' END;
+ALTER TABLE `sensitive_data` DROP COLUMN `identifier_type`;
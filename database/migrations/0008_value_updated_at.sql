@@ -0,0 +1,3 @@
+-- When the value of an entry was last set, apart from changes to its identifier, details or tags
+ALTER TABLE `sensitive_data` ADD COLUMN `value_updated_at` datetime;
+UPDATE `sensitive_data` SET `value_updated_at` = `updated_at`;
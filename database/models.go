@@ -1,31 +1,111 @@
 package database
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
-type IdentifierType string
-
-const (
-	IdentifierTypeUsername IdentifierType = "username"
-	IdentifierTypeEmail    IdentifierType = "email"
-	IdentifierTypeAPIKey   IdentifierType = "api_key"
-	IdentifierTypeSecret   IdentifierType = "secret_key"
+	"gorm.io/gorm"
 )
 
 type SensitiveData struct {
 	gorm.Model
-	Service        string         `gorm:"index:idx_service_identifier,unique"`
-	Identifier     string         `gorm:"index:idx_service_identifier,unique"` // can be username, email, API key, etc.
-	Value          string         // this could be the actual password, API key, or sensitive value
-	IdentifierType IdentifierType // type of identifier (e.g., username, email, API key)
+	Service        string       `gorm:"index:idx_service_identifier,unique,where:deleted_at IS NULL"`
+	Identifier     string       `gorm:"index:idx_service_identifier,unique,where:deleted_at IS NULL"` // can be username, email, API key, etc.
+	Value          string       // this could be the actual password, API key, or sensitive value
+	ValueUpdatedAt time.Time    // When Value was last set; UpdatedAt also changes with the identifier, details and tags
+	Kind           string       // kind of entry (e.g., login, api_key, ssh_key), whose schema describes its fields
+	Metadata       string       `json:"-"` // Details encrypted as JSON, or empty if the entry has none
+	Tags           string       // Comma-separated tags, kept in plain text so entries can be filtered without decrypting them
+	Details        EntryDetails `gorm:"-"` // Decrypted Metadata, filled in when the entry is decrypted
+}
+
+// TagList returns the entry's tags
+func (e SensitiveData) TagList() []string {
+	if e.Tags == "" {
+		return []string{}
+	}
+	return strings.Split(e.Tags, ",")
+}
+
+// HasTags reports whether the entry has every one of tags, ignoring case
+func (e SensitiveData) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(e.TagList(), strings.ToLower(strings.TrimSpace(tag))) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeTags lower-cases, trims, sorts and de-duplicates tags. Tags must not be empty or contain commas.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || strings.Contains(tag, ",") {
+			return nil, fmt.Errorf("invalid tag %q: tags must not be empty or contain commas", tag)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// EntryDetails holds the optional metadata of an entry. It is stored encrypted, bound to the entry.
+type EntryDetails struct {
+	Notes  string   `json:"notes,omitempty"`
+	URLs   []string `json:"urls,omitempty"`
+	Fields []Field  `json:"fields,omitempty"`
+	OTP    string   `json:"otp,omitempty"` // otpauth:// URI of the entry's one-time password seed
+}
+
+// IsZero reports whether there are no details to store
+func (d EntryDetails) IsZero() bool {
+	return d.Notes == "" && len(d.URLs) == 0 && len(d.Fields) == 0 && d.OTP == ""
+}
+
+// Field is a custom key/value pair of an entry, such as a region or a recovery code
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"` // Hidden when the entry is shown unless revealed
+}
+
+// SensitiveDataHistory is a previous value of an entry. The value stays encrypted for the
+// service and identifier the entry had at the time, which are recorded alongside it.
+type SensitiveDataHistory struct {
+	gorm.Model
+	EntryID    uint `gorm:"index:idx_history_entry_version,unique"` // ID of the SensitiveData the value belonged to
+	Version    int  `gorm:"index:idx_history_entry_version,unique"` // Increases with every update of the entry
+	Service    string
+	Identifier string
+	Value      string
+	ValueSetAt time.Time // When the value was stored; CreatedAt is when it was replaced
 }
 
 type MasterPassword struct {
 	gorm.Model
-	HashedPassword string `gorm:"uniqueIndex"` // Store the hashed password
+	HashedPassword string // Legacy bcrypt hash, cleared once the vault is migrated to a KDF
+	KDF            string // Key derivation function (e.g., argon2id)
+	Salt           string // Hex-encoded per-vault salt
+	KDFTime        uint32 // Argon2id time cost
+	KDFMemory      uint32 // Argon2id memory cost in KiB
+	KDFThreads     uint8  // Argon2id parallelism
+	Verifier       string // HMAC of the derived key, used to check the master password
 }
 
-// VaultState represents the state of the vault (locked or unlocked)
-type VaultState struct {
+// PasswordPolicy describes the values a service accepts. Zero values mean no constraint.
+type PasswordPolicy struct {
 	gorm.Model
-	IsLocked bool `gorm:"default:true"` // Default to true (locked)
+	Service   string `gorm:"uniqueIndex"`
+	Length    int    // Length of generated values
+	MinLength int    // Shortest value the service accepts
+	MaxLength int    // Longest value the service accepts
+	Charset   string // Characters generated values are drawn from
+	Require   string // Comma-separated character classes values must contain
+	Forbid    string // Comma-separated character classes values must not contain
 }
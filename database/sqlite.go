@@ -0,0 +1,356 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SQLiteStore keeps a vault in a SQLite database file
+type SQLiteStore struct {
+	db   *gorm.DB
+	path string
+}
+
+// OpenSQLiteStore opens the vault database at path, creating it and applying pending schema
+// migrations as needed. A vault that already holds data is backed up before it is migrated.
+// Vaults written by a newer version of vault-cli are refused with ErrSchemaTooNew.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	conn, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := migrate(conn, path); err != nil {
+		closeSQLite(conn)
+		return nil, err
+	}
+	return &SQLiteStore{db: conn, path: path}, nil
+}
+
+// openSQLite opens the database file at path without touching its schema
+func openSQLite(path string) (*gorm.DB, error) {
+	return gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+}
+
+// closeSQLite closes the connection opened by openSQLite
+func closeSQLite(conn *gorm.DB) error {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// whereEntry matches service and identifier ignoring case
+func (s *SQLiteStore) whereEntry(service, identifier string) *gorm.DB {
+	return s.db.Where("LOWER(service) = ? AND LOWER(identifier) = ?", strings.ToLower(service), strings.ToLower(identifier))
+}
+
+func (s *SQLiteStore) Add(entry *SensitiveData) error {
+	// Lookups are case-insensitive, so entries differing only in case would be ambiguous
+	var count int64
+	if err := s.whereEntry(entry.Service, entry.Identifier).Model(&SensitiveData{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+	}
+
+	if err := s.db.Create(entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Get(service, identifier string) (SensitiveData, error) {
+	var entry SensitiveData
+	err := s.whereEntry(service, identifier).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return SensitiveData{}, fmt.Errorf("%w: no data found for service '%s' and identifier '%s'", ErrNotFound, service, identifier)
+	}
+	if err != nil {
+		return SensitiveData{}, fmt.Errorf("error querying sensitive data: %w", err)
+	}
+	return entry, nil
+}
+
+func (s *SQLiteStore) List(kind string) ([]SensitiveData, error) {
+	var entries []SensitiveData
+	query := s.db.Order("id")
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *SQLiteStore) Update(entry *SensitiveData) error {
+	// Entries in the trash may share a service and identifier with any other entry
+	if !entry.DeletedAt.Valid {
+		var count int64
+		err := s.whereEntry(entry.Service, entry.Identifier).Where("id <> ?", entry.ID).Model(&SensitiveData{}).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+		}
+	}
+
+	// A copy of an entry read before it was moved to or out of the trash no longer matches
+	query := s.db.Unscoped().Model(entry)
+	if entry.DeletedAt.Valid {
+		query = query.Where("deleted_at IS NOT NULL")
+	} else {
+		query = query.Where("deleted_at IS NULL")
+	}
+	result := query.Select("*").Omit("created_at").Updates(entry)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+		}
+		return fmt.Errorf("error updating the entry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: no entry found for service '%s' and identifier '%s'", ErrNotFound, entry.Service, entry.Identifier)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Delete(service, identifier string) error {
+	entry, err := s.Get(service, identifier)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(&entry).Error; err != nil {
+		return fmt.Errorf("error moving the entry to the trash: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Trash() ([]SensitiveData, error) {
+	var entries []SensitiveData
+	if err := s.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at, id").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("error querying the trash: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *SQLiteStore) RestoreTrashed(service, identifier string) (SensitiveData, error) {
+	var entry SensitiveData
+	err := s.whereEntry(service, identifier).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC, id DESC").First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return SensitiveData{}, fmt.Errorf("%w: no entry in the trash for service '%s' and identifier '%s'", ErrNotFound, service, identifier)
+	}
+	if err != nil {
+		return SensitiveData{}, fmt.Errorf("error querying the trash: %w", err)
+	}
+
+	if _, err := s.Get(entry.Service, entry.Identifier); err == nil {
+		return SensitiveData{}, fmt.Errorf("%w: service '%s' and identifier '%s'", ErrDuplicate, entry.Service, entry.Identifier)
+	}
+	if err := s.db.Unscoped().Model(&entry).Update("deleted_at", nil).Error; err != nil {
+		return SensitiveData{}, fmt.Errorf("error restoring the entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (s *SQLiteStore) Purge(id uint) error {
+	return s.Transaction(func(tx Store) error {
+		conn := tx.(*SQLiteStore).db
+		if err := conn.Unscoped().Where("entry_id = ?", id).Delete(&SensitiveDataHistory{}).Error; err != nil {
+			return fmt.Errorf("error deleting the entry history: %w", err)
+		}
+		result := conn.Unscoped().Delete(&SensitiveData{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("error deleting the entry: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: no entry %d", ErrNotFound, id)
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) PurgeTrash(deletedBefore time.Time) (int, error) {
+	// Deletion times are compared here rather than in SQL, where they are stored as text
+	purged := 0
+	err := s.Transaction(func(tx Store) error {
+		trashed, err := tx.Trash()
+		if err != nil {
+			return err
+		}
+		for _, entry := range trashed {
+			if !entry.DeletedAt.Time.Before(deletedBefore) {
+				continue
+			}
+			if err := tx.Purge(entry.ID); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func (s *SQLiteStore) AddHistory(record *SensitiveDataHistory) error {
+	var latest int
+	err := s.db.Model(&SensitiveDataHistory{}).Where("entry_id = ?", record.EntryID).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error
+	if err != nil {
+		return fmt.Errorf("error querying entry history: %w", err)
+	}
+	record.ID = 0
+	record.Version = latest + 1
+	return s.db.Create(record).Error
+}
+
+func (s *SQLiteStore) History(entryID uint) ([]SensitiveDataHistory, error) {
+	var records []SensitiveDataHistory
+	if err := s.db.Where("entry_id = ?", entryID).Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("error querying entry history: %w", err)
+	}
+	return records, nil
+}
+
+func (s *SQLiteStore) UpdateHistory(record *SensitiveDataHistory) error {
+	result := s.db.Model(record).Select("*").Omit("created_at").Updates(record)
+	if result.Error != nil {
+		return fmt.Errorf("error updating entry history: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: no history record %d", ErrNotFound, record.ID)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) PruneHistory(entryID uint, keep int) error {
+	// Versions above the cutoff are kept
+	var cutoff int
+	err := s.db.Model(&SensitiveDataHistory{}).Where("entry_id = ?", entryID).Order("version DESC").Offset(keep).Limit(1).
+		Select("version").Scan(&cutoff).Error
+	if err != nil {
+		return fmt.Errorf("error querying entry history: %w", err)
+	}
+	if cutoff == 0 {
+		return nil
+	}
+	err = s.db.Unscoped().Where("entry_id = ? AND version <= ?", entryID, cutoff).Delete(&SensitiveDataHistory{}).Error
+	if err != nil {
+		return fmt.Errorf("error pruning entry history: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Master() (MasterPassword, error) {
+	var masterPassword MasterPassword
+	err := s.db.First(&masterPassword).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return MasterPassword{}, ErrNoMasterPassword
+	}
+	if err != nil {
+		return MasterPassword{}, fmt.Errorf("could not retrieve master password: %w", err)
+	}
+	return masterPassword, nil
+}
+
+func (s *SQLiteStore) SetMaster(masterPassword *MasterPassword) error {
+	return s.Transaction(func(tx Store) error {
+		conn := tx.(*SQLiteStore).db
+		if err := conn.Unscoped().Where("1 = 1").Delete(&MasterPassword{}).Error; err != nil {
+			return fmt.Errorf("failed to delete old master password: %w", err)
+		}
+		masterPassword.ID = 0
+		return conn.Create(masterPassword).Error
+	})
+}
+
+func (s *SQLiteStore) Policy(service string) (PasswordPolicy, error) {
+	var policy PasswordPolicy
+	err := s.db.Where("LOWER(service) = ?", strings.ToLower(service)).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return PasswordPolicy{}, fmt.Errorf("%w: no password policy for service '%s'", ErrNotFound, service)
+	}
+	if err != nil {
+		return PasswordPolicy{}, fmt.Errorf("error querying password policy: %w", err)
+	}
+	return policy, nil
+}
+
+func (s *SQLiteStore) Policies() ([]PasswordPolicy, error) {
+	var policies []PasswordPolicy
+	if err := s.db.Order("LOWER(service)").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (s *SQLiteStore) SetPolicy(policy *PasswordPolicy) error {
+	return s.Transaction(func(tx Store) error {
+		conn := tx.(*SQLiteStore).db
+		err := conn.Unscoped().Where("LOWER(service) = ?", strings.ToLower(policy.Service)).Delete(&PasswordPolicy{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to replace password policy: %w", err)
+		}
+		policy.ID = 0
+		return conn.Create(policy).Error
+	})
+}
+
+func (s *SQLiteStore) DeletePolicy(service string) error {
+	result := s.db.Unscoped().Where("LOWER(service) = ?", strings.ToLower(service)).Delete(&PasswordPolicy{})
+	if result.Error != nil {
+		return fmt.Errorf("error deleting password policy: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: no password policy for service '%s'", ErrNotFound, service)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) State() (State, error) {
+	state := State{Location: s.path}
+
+	var masters, entries, trashed int64
+	if err := s.db.Model(&MasterPassword{}).Count(&masters).Error; err != nil {
+		return State{}, err
+	}
+	if err := s.db.Model(&SensitiveData{}).Count(&entries).Error; err != nil {
+		return State{}, err
+	}
+	if err := s.db.Unscoped().Model(&SensitiveData{}).Where("deleted_at IS NOT NULL").Count(&trashed).Error; err != nil {
+		return State{}, err
+	}
+	state.MasterPasswordSet = masters > 0
+	state.Entries = int(entries)
+	state.Trashed = int(trashed)
+	return state, nil
+}
+
+func (s *SQLiteStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&SQLiteStore{db: tx, path: s.path})
+	})
+}
+
+func (s *SQLiteStore) Close() error {
+	return closeSQLite(s.db)
+}
+
+var _ Store = (*SQLiteStore)(nil)
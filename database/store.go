@@ -0,0 +1,73 @@
+package database
+
+import "time"
+
+// Store persists encrypted entries and the master password record of a single vault.
+// Values passed to and returned by a Store are ciphertexts; encryption is handled by
+// the package-level functions such as AddSensitiveData, which take the Store to use.
+//
+// Service and identifier lookups ignore case.
+type Store interface {
+	// Add stores a new entry, or returns ErrDuplicate if one with the same service and identifier exists
+	Add(entry *SensitiveData) error
+	// Get returns the entry for service and identifier, or ErrNotFound
+	Get(service, identifier string) (SensitiveData, error)
+	// List returns all entries ordered by ID, optionally filtered by kind ("" for all)
+	List(kind string) ([]SensitiveData, error)
+	// Update saves a modified entry, matched by ID, including entries in the trash. It returns
+	// ErrDuplicate if the entry was renamed onto another entry, or ErrNotFound if it no longer exists.
+	Update(entry *SensitiveData) error
+	// Delete moves the entry for service and identifier to the trash, or returns ErrNotFound
+	Delete(service, identifier string) error
+
+	// Trash returns the entries in the trash ordered by when they were deleted
+	Trash() ([]SensitiveData, error)
+	// RestoreTrashed moves the most recently deleted entry for service and identifier out of the trash.
+	// It returns ErrNotFound if there is none, or ErrDuplicate if another entry has taken its place.
+	RestoreTrashed(service, identifier string) (SensitiveData, error)
+	// Purge permanently removes the entry with ID id, in the trash or not, and its history
+	Purge(id uint) error
+	// PurgeTrash permanently removes the entries deleted before the given time and returns how many there were
+	PurgeTrash(deletedBefore time.Time) (int, error)
+
+	// AddHistory records a previous value of the entry with ID record.EntryID, numbering it
+	// one higher than the latest recorded version
+	AddHistory(record *SensitiveDataHistory) error
+	// History returns the recorded previous values of an entry ordered by version
+	History(entryID uint) ([]SensitiveDataHistory, error)
+	// UpdateHistory saves a modified history record, matched by ID
+	UpdateHistory(record *SensitiveDataHistory) error
+	// PruneHistory deletes all but the keep most recent versions of an entry
+	PruneHistory(entryID uint, keep int) error
+
+	// Master returns the master password record, or ErrNoMasterPassword
+	Master() (MasterPassword, error)
+	// SetMaster replaces the master password record
+	SetMaster(masterPassword *MasterPassword) error
+
+	// Policy returns the password policy for service, or ErrNotFound
+	Policy(service string) (PasswordPolicy, error)
+	// Policies returns all password policies ordered by service
+	Policies() ([]PasswordPolicy, error)
+	// SetPolicy creates or replaces the password policy for policy.Service
+	SetPolicy(policy *PasswordPolicy) error
+	// DeletePolicy removes the password policy for service, or returns ErrNotFound
+	DeletePolicy(service string) error
+
+	// State describes the vault held by the store
+	State() (State, error)
+
+	// Transaction runs fn against a store whose changes are only kept if fn returns nil
+	Transaction(fn func(tx Store) error) error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// State describes a vault held by a Store
+type State struct {
+	// Location identifies the vault: the database file for SQLite stores. Unlock sessions are keyed by it.
+	Location          string
+	MasterPasswordSet bool
+	Entries           int // Entries not in the trash
+	Trashed           int
+}
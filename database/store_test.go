@@ -0,0 +1,322 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// forEachStore runs test against an empty store of every implementation
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, setup(t))
+	})
+	t.Run("memory", func(t *testing.T) {
+		t.Parallel()
+		test(t, NewMemoryStore(filepath.Join(t.TempDir(), "memory.db")))
+	})
+}
+
+func TestStoreEntries(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		github := SensitiveData{Service: "github.com", Identifier: "octocat", Value: "v2:aa", Kind: "login"}
+		example := SensitiveData{Service: "example.com", Identifier: "user@example.com", Value: "v2:bb", Kind: "api_key"}
+		for _, entry := range []*SensitiveData{&github, &example} {
+			if err := store.Add(entry); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+		}
+		if github.ID == 0 || github.CreatedAt.IsZero() {
+			t.Errorf("Expected Add to assign an ID and timestamps, got %+v", github)
+		}
+
+		duplicate := SensitiveData{Service: "GitHub.com", Identifier: "OctoCat", Value: "v2:cc", Kind: "login"}
+		if err := store.Add(&duplicate); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("Expected ErrDuplicate, got %v", err)
+		}
+
+		got, err := store.Get("GITHUB.COM", "octocat")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.ID != github.ID || got.Value != "v2:aa" {
+			t.Errorf("Get returned %+v", got)
+		}
+		if _, err := store.Get("missing.com", "nobody"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		all, err := store.List("")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(all) != 2 || all[0].Service != "github.com" || all[1].Service != "example.com" {
+			t.Errorf("Expected entries in insertion order, got %+v", all)
+		}
+		apiKeys, err := store.List("api_key")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(apiKeys) != 1 || apiKeys[0].Service != "example.com" {
+			t.Errorf("Expected only the api_key entry, got %+v", apiKeys)
+		}
+
+		got.Value = "v2:dd"
+		if err := store.Update(&got); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if updated, _ := store.Get("github.com", "octocat"); updated.Value != "v2:dd" {
+			t.Errorf("Expected updated value, got %q", updated.Value)
+		}
+		got.Service, got.Identifier = "example.com", "USER@example.com"
+		if err := store.Update(&got); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("Expected ErrDuplicate renaming onto another entry, got %v", err)
+		}
+
+		if err := store.Delete("example.com", "user@example.com"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := store.Delete("example.com", "user@example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+		}
+		if err := store.Update(&example); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound updating a deleted entry, got %v", err)
+		}
+	})
+}
+
+func TestStoreMasterAndState(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		if _, err := store.Master(); !errors.Is(err, ErrNoMasterPassword) {
+			t.Errorf("Expected ErrNoMasterPassword, got %v", err)
+		}
+
+		for _, verifier := range []string{"first", "second"} {
+			if err := store.SetMaster(&MasterPassword{KDF: KDFArgon2id, Verifier: verifier}); err != nil {
+				t.Fatalf("SetMaster failed: %v", err)
+			}
+		}
+		master, err := store.Master()
+		if err != nil {
+			t.Fatalf("Master failed: %v", err)
+		}
+		if master.Verifier != "second" {
+			t.Errorf("Expected SetMaster to replace the record, got verifier %q", master.Verifier)
+		}
+
+		if err := store.Add(&SensitiveData{Service: "example.com", Identifier: "me", Value: "v2:aa", Kind: "login"}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		state, err := store.State()
+		if err != nil {
+			t.Fatalf("State failed: %v", err)
+		}
+		if !state.MasterPasswordSet || state.Entries != 1 || filepath.Ext(state.Location) != ".db" {
+			t.Errorf("Unexpected state %+v", state)
+		}
+	})
+}
+
+func TestStoreTransactionRollsBack(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		if err := store.Add(&SensitiveData{Service: "example.com", Identifier: "me", Value: "v2:aa", Kind: "login"}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+
+		failure := errors.New("failure")
+		err := store.Transaction(func(tx Store) error {
+			entry, err := tx.Get("example.com", "me")
+			if err != nil {
+				return err
+			}
+			entry.Value = "v2:bb"
+			if err := tx.Update(&entry); err != nil {
+				return err
+			}
+			if err := tx.Add(&SensitiveData{Service: "other.com", Identifier: "me", Value: "v2:cc", Kind: "login"}); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Expected the transaction error, got %v", err)
+		}
+
+		entry, err := store.Get("example.com", "me")
+		if err != nil || entry.Value != "v2:aa" {
+			t.Errorf("Expected the update to be rolled back, got %+v, %v", entry, err)
+		}
+		if _, err := store.Get("other.com", "me"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected the insert to be rolled back, got %v", err)
+		}
+	})
+}
+
+func TestStorePolicies(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		if _, err := store.Policy("github.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+
+		for _, policy := range []*PasswordPolicy{
+			{Service: "github.com", Length: 32, Require: "symbol"},
+			{Service: "bank.com", Length: 16, MaxLength: 16, Forbid: "symbol"},
+			{Service: "GitHub.com", Length: 40},
+		} {
+			if err := store.SetPolicy(policy); err != nil {
+				t.Fatalf("SetPolicy failed: %v", err)
+			}
+		}
+
+		// Setting a policy replaces the one for the same service, ignoring case
+		got, err := store.Policy("GITHUB.COM")
+		if err != nil {
+			t.Fatalf("Policy failed: %v", err)
+		}
+		if got.Length != 40 || got.Require != "" {
+			t.Errorf("Expected the replaced policy, got %+v", got)
+		}
+
+		policies, err := store.Policies()
+		if err != nil {
+			t.Fatalf("Policies failed: %v", err)
+		}
+		if len(policies) != 2 || policies[0].Service != "bank.com" || policies[1].Service != "GitHub.com" {
+			t.Errorf("Expected policies ordered by service, got %+v", policies)
+		}
+
+		if err := store.DeletePolicy("bank.com"); err != nil {
+			t.Fatalf("DeletePolicy failed: %v", err)
+		}
+		if err := store.DeletePolicy("bank.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestStoreHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		entry := &SensitiveData{Service: "github.com", Identifier: "octocat", Value: "v3", Kind: "login"}
+		if err := store.Add(entry); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		for _, value := range []string{"v0", "v1", "v2"} {
+			record := &SensitiveDataHistory{EntryID: entry.ID, Service: entry.Service, Identifier: entry.Identifier, Value: value}
+			if err := store.AddHistory(record); err != nil {
+				t.Fatalf("AddHistory failed: %v", err)
+			}
+		}
+
+		records, err := store.History(entry.ID)
+		if err != nil {
+			t.Fatalf("History failed: %v", err)
+		}
+		if len(records) != 3 || records[0].Version != 1 || records[2].Version != 3 || records[2].Value != "v2" {
+			t.Fatalf("Expected versions 1 to 3 in order, got %+v", records)
+		}
+
+		records[0].Value = "re-encrypted"
+		if err := store.UpdateHistory(&records[0]); err != nil {
+			t.Fatalf("UpdateHistory failed: %v", err)
+		}
+
+		if err := store.PruneHistory(entry.ID, 2); err != nil {
+			t.Fatalf("PruneHistory failed: %v", err)
+		}
+		records, _ = store.History(entry.ID)
+		if len(records) != 2 || records[0].Version != 2 {
+			t.Errorf("Expected the 2 most recent versions to be kept, got %+v", records)
+		}
+
+		// Versions keep increasing after pruning
+		record := &SensitiveDataHistory{EntryID: entry.ID, Service: entry.Service, Identifier: entry.Identifier, Value: "v3"}
+		if err := store.AddHistory(record); err != nil {
+			t.Fatalf("AddHistory failed: %v", err)
+		}
+		if record.Version != 4 {
+			t.Errorf("Expected version 4, got %d", record.Version)
+		}
+
+		// Purging the entry deletes its history
+		if err := store.Purge(entry.ID); err != nil {
+			t.Fatalf("Purge failed: %v", err)
+		}
+		if records, _ := store.History(entry.ID); len(records) != 0 {
+			t.Errorf("Expected no history after purge, got %+v", records)
+		}
+	})
+}
+
+func TestStoreTrash(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		first := &SensitiveData{Service: "github.com", Identifier: "octocat", Value: "v2:aa", Kind: "login"}
+		if err := store.Add(first); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if err := store.Delete("github.com", "octocat"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := store.Get("github.com", "octocat"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for an entry in the trash, got %v", err)
+		}
+		if entries, _ := store.List(""); len(entries) != 0 {
+			t.Errorf("Expected no entries outside the trash, got %+v", entries)
+		}
+
+		// The name of an entry in the trash can be reused, which blocks restoring it
+		second := &SensitiveData{Service: "GitHub.com", Identifier: "octocat", Value: "v2:bb", Kind: "login"}
+		if err := store.Add(second); err != nil {
+			t.Fatalf("Add with the name of a trashed entry failed: %v", err)
+		}
+		if _, err := store.RestoreTrashed("github.com", "octocat"); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("Expected ErrDuplicate restoring over an entry, got %v", err)
+		}
+
+		if err := store.Delete("github.com", "octocat"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		trash, err := store.Trash()
+		if err != nil {
+			t.Fatalf("Trash failed: %v", err)
+		}
+		if len(trash) != 2 || trash[0].ID != first.ID || !trash[1].DeletedAt.Valid {
+			t.Fatalf("Expected both entries in deletion order, got %+v", trash)
+		}
+		state, _ := store.State()
+		if state.Entries != 0 || state.Trashed != 2 {
+			t.Errorf("Expected 0 entries and 2 in the trash, got %+v", state)
+		}
+
+		// Entries in the trash can still be re-encrypted
+		trash[0].Value = "v2:cc"
+		if err := store.Update(&trash[0]); err != nil {
+			t.Fatalf("Update of a trashed entry failed: %v", err)
+		}
+
+		// The most recently deleted entry is restored first
+		restored, err := store.RestoreTrashed("github.com", "OCTOCAT")
+		if err != nil {
+			t.Fatalf("RestoreTrashed failed: %v", err)
+		}
+		if restored.ID != second.ID {
+			t.Errorf("Expected the most recently deleted entry, got %+v", restored)
+		}
+		if got, err := store.Get("github.com", "octocat"); err != nil || got.Value != "v2:bb" {
+			t.Errorf("Expected the restored entry, got %+v, %v", got, err)
+		}
+
+		if purged, err := store.PurgeTrash(time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+			t.Errorf("Expected nothing purged before the deletion, got %d, %v", purged, err)
+		}
+		if purged, err := store.PurgeTrash(time.Now().Add(time.Second)); err != nil || purged != 1 {
+			t.Errorf("Expected 1 entry purged, got %d, %v", purged, err)
+		}
+		if trash, _ := store.Trash(); len(trash) != 0 {
+			t.Errorf("Expected an empty trash, got %+v", trash)
+		}
+		if err := store.Purge(first.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound purging twice, got %v", err)
+		}
+	})
+}
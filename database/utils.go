@@ -5,58 +5,122 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
-// ParseIdentifierType attempts to convert a string to IdentifierType
-func ParseIdentifierType(idType string) (IdentifierType, error) {
-	switch idType {
-	case "username":
-		return IdentifierTypeUsername, nil
-	case "email":
-		return IdentifierTypeEmail, nil
-	case "api_key":
-		return IdentifierTypeAPIKey, nil
-	case "secret_key":
-		return IdentifierTypeSecret, nil
-	default:
-		return "", fmt.Errorf("invalid identifier type: %s", idType)
-	}
-}
-
-// DeriveAESKey derives a 32-byte AES key from the bcrypt-hashed password using SHA-256
-func deriveAESKey(hashedPassword string) []byte {
+// deriveLegacyKey derives the 32-byte AES key used by vaults created before the KDF migration.
+// It hashes the stored bcrypt hash, so it is only used to read and migrate old entries.
+func deriveLegacyKey(hashedPassword string) []byte {
 	hash := sha256.Sum256([]byte(hashedPassword))
 	return hash[:]
 }
 
-// Encrypt encrypts the given plaintext using the provided key
-func encrypt(plaintext string, key []byte) (string, error) {
+// ErrIntegrity is returned when a ciphertext fails authentication, e.g. because it was tampered with
+var ErrIntegrity = errors.New("integrity check failed: data may have been tampered with")
+
+// Ciphertext format versions. Legacy AES-CFB values carry no prefix. They were only ever written with
+// the bcrypt-derived key, and are re-encrypted when that key is migrated, so decrypt rejects them.
+const (
+	ciphertextVersionAESGCM = "v2"
+	ciphertextSeparator     = ":"
+)
+
+// entryAAD binds a ciphertext to the entry it belongs to so values cannot be swapped between rows.
+// Each part is prefixed with its length, so that no two entries share an AAD whatever their names contain.
+func entryAAD(service, identifier string) []byte {
+	aad := make([]byte, 0, 8+len(service)+len(identifier))
+	aad = binary.BigEndian.AppendUint32(aad, uint32(len(service)))
+	aad = append(aad, service...)
+	aad = binary.BigEndian.AppendUint32(aad, uint32(len(identifier)))
+	return append(aad, identifier...)
+}
+
+// metadataAAD binds encrypted details to their entry, distinct from the entry's value
+func metadataAAD(service, identifier string) []byte {
+	return append(entryAAD(service, identifier), "\x00metadata"...)
+}
+
+// isLegacyCiphertext reports whether the value was written with the old unauthenticated AES-CFB format
+func isLegacyCiphertext(ciphertext string) bool {
+	return !strings.Contains(ciphertext, ciphertextSeparator)
+}
+
+// Encrypt encrypts the given plaintext with AES-256-GCM, authenticating it together with aad
+func encrypt(plaintext string, key []byte, aad []byte) (string, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
 	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
 
 	// Generate a nonce (number used once)
-	nonce := make([]byte, aes.BlockSize)
+	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	// Encrypt the data
-	ciphertext := make([]byte, len(plaintext))
-	stream := cipher.NewCFBEncrypter(block, nonce)
-	stream.XORKeyStream(ciphertext, []byte(plaintext))
+	// Encrypt and authenticate the data
+	ciphertext := gcm.Seal(nil, nonce, []byte(plaintext), aad)
+
+	// Return the version prefix followed by the nonce + ciphertext as a hex string
+	return ciphertextVersionAESGCM + ciphertextSeparator + hex.EncodeToString(nonce) + hex.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts and authenticates the given ciphertext using the provided key.
+// Values in the legacy AES-CFB format cannot be authenticated and fail with ErrIntegrity.
+func decrypt(ciphertext string, key []byte, aad []byte) (string, error) {
+	if isLegacyCiphertext(ciphertext) {
+		return "", ErrIntegrity
+	}
+	version, payload, _ := strings.Cut(ciphertext, ciphertextSeparator)
+	if version != ciphertextVersionAESGCM {
+		return "", fmt.Errorf("unsupported ciphertext version: %q", version)
+	}
+	return decryptAESGCM(payload, key, aad)
+}
 
-	// Return the nonce + ciphertext as a hex string
-	return hex.EncodeToString(nonce) + hex.EncodeToString(ciphertext), nil
+// decryptAESGCM decrypts the hex-encoded nonce and ciphertext of a value in the AES-GCM format
+func decryptAESGCM(payload string, key []byte, aad []byte) (string, error) {
+	// Decode the hex string
+	data, err := hex.DecodeString(payload)
+	if err != nil {
+		return "", ErrIntegrity
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return "", ErrIntegrity
+	}
+
+	// Separate the nonce and the actual ciphertext
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, aad)
+	if err != nil {
+		return "", ErrIntegrity
+	}
+
+	return string(plaintext), nil
 }
 
-// Decrypt decrypts the given ciphertext using the provided key
-func decrypt(ciphertextHex string, key []byte) (string, error) {
+// decryptLegacy decrypts a value written with the old AES-CFB format
+func decryptLegacy(ciphertextHex string, key []byte) (string, error) {
 	// Decode the hex string
 	data, err := hex.DecodeString(ciphertextHex)
 	if err != nil {
@@ -0,0 +1,222 @@
+// Package generator creates random passwords and passphrases.
+//
+// Every character and word is drawn uniformly from crypto/rand. Passwords that
+// must contain certain kinds of characters are generated by rejection sampling,
+// so each password meeting the requirements is equally likely.
+package generator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidOptions is returned when the requested password cannot be generated from the given options
+var ErrInvalidOptions = errors.New("invalid generator options")
+
+// Ambiguous lists the characters removed by Options.NoAmbiguous because they are easily confused
+const Ambiguous = "0OoIl1|"
+
+// maxAttempts bounds rejection sampling for passwords that must contain several classes
+const maxAttempts = 100000
+
+// Class is a kind of character a password can be required to contain
+type Class string
+
+const (
+	Upper  Class = "upper"
+	Lower  Class = "lower"
+	Digit  Class = "digit"
+	Symbol Class = "symbol"
+)
+
+// Classes lists the supported character classes
+var Classes = []Class{Upper, Lower, Digit, Symbol}
+
+// Contains reports whether r belongs to the class. Symbols are all characters
+// that are neither letters nor digits.
+func (c Class) Contains(r rune) bool {
+	switch c {
+	case Upper:
+		return unicode.IsUpper(r)
+	case Lower:
+		return unicode.IsLower(r)
+	case Digit:
+		return unicode.IsDigit(r)
+	case Symbol:
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+	return false
+}
+
+// describe returns a phrase naming one character of the class, or several if plural is set
+func (c Class) describe(plural bool) string {
+	names := map[Class][2]string{
+		Upper:  {"an upper case letter", "upper case letters"},
+		Lower:  {"a lower case letter", "lower case letters"},
+		Digit:  {"a digit", "digits"},
+		Symbol: {"a symbol", "symbols"},
+	}
+	if plural {
+		return names[c][1]
+	}
+	return names[c][0]
+}
+
+// ParseClasses parses class names such as "upper" or "digit"
+func ParseClasses(names []string) ([]Class, error) {
+	var classes []Class
+	seen := make(map[Class]bool)
+	for _, name := range names {
+		class := Class(strings.ToLower(strings.TrimSpace(name)))
+		if class == "" || seen[class] {
+			continue
+		}
+		if !class.valid() {
+			return nil, fmt.Errorf("%w: unknown character class %q (expected upper, lower, digit or symbol)", ErrInvalidOptions, name)
+		}
+		seen[class] = true
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+func (c Class) valid() bool {
+	for _, class := range Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Options describes a password to generate
+type Options struct {
+	Length      int
+	Charset     string  // Characters to draw from; duplicates are ignored
+	NoAmbiguous bool    // Leave out the characters in Ambiguous
+	Exclude     string  // Characters to leave out
+	Require     []Class // Classes the password must contain at least one character of
+}
+
+// Result is a generated secret and its estimated strength
+type Result struct {
+	Value   string
+	Entropy float64 // Bits of entropy, given that the options used are known to an attacker
+}
+
+// pool returns the distinct characters a password is drawn from
+func (o Options) pool() []rune {
+	excluded := o.Exclude
+	if o.NoAmbiguous {
+		excluded += Ambiguous
+	}
+
+	var pool []rune
+	seen := make(map[rune]bool)
+	for _, r := range o.Charset {
+		if seen[r] || strings.ContainsRune(excluded, r) {
+			continue
+		}
+		seen[r] = true
+		pool = append(pool, r)
+	}
+	return pool
+}
+
+// Password generates a password of opts.Length characters drawn from the pool described by opts
+func Password(opts Options) (Result, error) {
+	if opts.Length <= 0 {
+		return Result{}, fmt.Errorf("%w: length must be a positive integer", ErrInvalidOptions)
+	}
+	pool := opts.pool()
+	if len(pool) == 0 {
+		return Result{}, fmt.Errorf("%w: no characters left to draw from", ErrInvalidOptions)
+	}
+	if len(opts.Require) > opts.Length {
+		return Result{}, fmt.Errorf("%w: a password of %d characters cannot contain %d required classes", ErrInvalidOptions, opts.Length, len(opts.Require))
+	}
+
+	classSizes := make([]int, len(opts.Require))
+	for i, class := range opts.Require {
+		for _, r := range pool {
+			if class.Contains(r) {
+				classSizes[i]++
+			}
+		}
+		if classSizes[i] == 0 {
+			return Result{}, fmt.Errorf("%w: the character set has no %s", ErrInvalidOptions, class.describe(true))
+		}
+	}
+
+	// Share of all passwords over the pool that meet the requirements
+	share := requirementShare(len(pool), classSizes, opts.Length)
+	if share*maxAttempts < 20 {
+		return Result{}, fmt.Errorf("%w: too few characters to meet the requirements reliably; increase the length", ErrInvalidOptions)
+	}
+
+	password := make([]rune, opts.Length)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		for i := range password {
+			n, err := randomIndex(len(pool))
+			if err != nil {
+				return Result{}, err
+			}
+			password[i] = pool[n]
+		}
+		if meets(password, opts.Require) {
+			entropy := float64(opts.Length)*math.Log2(float64(len(pool))) + math.Log2(share)
+			return Result{Value: string(password), Entropy: entropy}, nil
+		}
+	}
+	return Result{}, errors.New("failed to generate a password meeting the requirements")
+}
+
+// meets reports whether password contains a character of every class
+func meets(password []rune, classes []Class) bool {
+	for _, class := range classes {
+		found := false
+		for _, r := range password {
+			if class.Contains(r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// requirementShare returns the share of passwords of length characters drawn from a pool
+// of poolSize that contain at least one character of each class, by inclusion-exclusion
+// over the (disjoint) classes with the given sizes
+func requirementShare(poolSize int, classSizes []int, length int) float64 {
+	share := 0.0
+	for subset := 0; subset < 1<<len(classSizes); subset++ {
+		missing, sign := 0, 1.0
+		for i, size := range classSizes {
+			if subset&(1<<i) != 0 {
+				missing += size
+				sign = -sign
+			}
+		}
+		share += sign * math.Pow(1-float64(missing)/float64(poolSize), float64(length))
+	}
+	return share
+}
+
+// randomIndex returns a uniformly distributed integer in [0, n). rand.Int rejects
+// samples outside the range instead of reducing them modulo n, so there is no bias.
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read random data: %w", err)
+	}
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+const testCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!@#$%"
+
+func TestPasswordUsesOnlyAllowedCharacters(t *testing.T) {
+	opts := Options{Length: 200, Charset: testCharset, NoAmbiguous: true, Exclude: "ABC#"}
+	result, err := Password(opts)
+	if err != nil {
+		t.Fatalf("Password failed: %v", err)
+	}
+	if len(result.Value) != 200 {
+		t.Errorf("Expected 200 characters, got %d", len(result.Value))
+	}
+	if strings.ContainsAny(result.Value, Ambiguous+"ABC#") {
+		t.Errorf("Expected no ambiguous or excluded characters, got %q", result.Value)
+	}
+	for _, r := range result.Value {
+		if !strings.ContainsRune(testCharset, r) {
+			t.Errorf("Unexpected character %q", r)
+		}
+	}
+}
+
+func TestPasswordMeetsRequirements(t *testing.T) {
+	opts := Options{Length: 4, Charset: testCharset, Require: Classes}
+	for i := 0; i < 50; i++ {
+		result, err := Password(opts)
+		if err != nil {
+			t.Fatalf("Password failed: %v", err)
+		}
+		for _, class := range Classes {
+			if !strings.ContainsFunc(result.Value, class.Contains) {
+				t.Fatalf("Expected %q to contain a %s character", result.Value, class)
+			}
+		}
+	}
+}
+
+func TestPasswordRejectsImpossibleOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"zero length", Options{Length: 0, Charset: testCharset}},
+		{"empty pool", Options{Length: 8, Charset: "abc", Exclude: "abc"}},
+		{"missing class", Options{Length: 8, Charset: "abc", Require: []Class{Digit}}},
+		{"too short", Options{Length: 2, Charset: testCharset, Require: Classes}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Password(tt.opts); !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("Expected ErrInvalidOptions, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	result, err := Password(Options{Length: 10, Charset: "0123456789"})
+	if err != nil {
+		t.Fatalf("Password failed: %v", err)
+	}
+	if want := 10 * math.Log2(10); math.Abs(result.Entropy-want) > 1e-9 {
+		t.Errorf("Expected %.2f bits, got %.2f", want, result.Entropy)
+	}
+
+	// Two characters from "aB" that must contain an uppercase letter: only "aB", "Ba" and "BB" remain
+	result, err = Password(Options{Length: 2, Charset: "aB", Require: []Class{Upper}})
+	if err != nil {
+		t.Fatalf("Password failed: %v", err)
+	}
+	if want := math.Log2(3); math.Abs(result.Entropy-want) > 1e-9 {
+		t.Errorf("Expected %.2f bits, got %.2f", want, result.Entropy)
+	}
+}
+
+func TestParseClasses(t *testing.T) {
+	classes, err := ParseClasses([]string{"Upper", " digit", "upper"})
+	if err != nil {
+		t.Fatalf("ParseClasses failed: %v", err)
+	}
+	if len(classes) != 2 || classes[0] != Upper || classes[1] != Digit {
+		t.Errorf("Unexpected classes %v", classes)
+	}
+	if _, err := ParseClasses([]string{"emoji"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions, got %v", err)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	if n := len(Wordlist()); n != 7776 {
+		t.Fatalf("Expected 7776 words, got %d", n)
+	}
+
+	result, err := Passphrase(6, "-")
+	if err != nil {
+		t.Fatalf("Passphrase failed: %v", err)
+	}
+	words := strings.Split(result.Value, "-")
+	if len(words) < 6 {
+		t.Errorf("Expected 6 words, got %q", result.Value)
+	}
+	if want := 6 * math.Log2(7776); math.Abs(result.Entropy-want) > 1e-9 {
+		t.Errorf("Expected %.2f bits, got %.2f", want, result.Entropy)
+	}
+}
@@ -0,0 +1,47 @@
+package generator
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// eff_large_wordlist.txt is the EFF large wordlist for diceware passphrases:
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+// Wordlist returns the words passphrases are drawn from
+var Wordlist = sync.OnceValue(func() []string {
+	var words []string
+	for _, line := range strings.Split(strings.TrimSpace(effLargeWordlist), "\n") {
+		// Lines hold the dice rolls and the word separated by a tab
+		_, word, _ := strings.Cut(line, "\t")
+		words = append(words, strings.TrimSpace(word))
+	}
+	return words
+})
+
+// Passphrase generates a passphrase of words random words from Wordlist joined by separator
+func Passphrase(words int, separator string) (Result, error) {
+	if words <= 0 {
+		return Result{}, fmt.Errorf("%w: the number of words must be a positive integer", ErrInvalidOptions)
+	}
+
+	list := Wordlist()
+	chosen := make([]string, words)
+	for i := range chosen {
+		n, err := randomIndex(len(list))
+		if err != nil {
+			return Result{}, err
+		}
+		chosen[i] = list[n]
+	}
+	return Result{
+		Value:   strings.Join(chosen, separator),
+		Entropy: float64(words) * math.Log2(float64(len(list))),
+	}, nil
+}
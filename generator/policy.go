@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPolicyViolation is returned by Policy.Check for values the policy does not allow
+var ErrPolicyViolation = errors.New("value does not meet the password policy")
+
+// Policy describes the values a service accepts and how to generate them. Zero values mean no constraint.
+type Policy struct {
+	Length    int     // Length of generated values
+	MinLength int     // Shortest accepted value
+	MaxLength int     // Longest accepted value
+	Charset   string  // Characters generated values are drawn from
+	Require   []Class // Classes values must contain
+	Forbid    []Class // Classes values must not contain
+}
+
+// Validate checks that the policy can be satisfied
+func (p Policy) Validate() error {
+	if p.Length < 0 || p.MinLength < 0 || p.MaxLength < 0 {
+		return fmt.Errorf("%w: lengths must not be negative", ErrInvalidOptions)
+	}
+	if p.MaxLength > 0 && p.MinLength > p.MaxLength {
+		return fmt.Errorf("%w: the minimum length %d exceeds the maximum length %d", ErrInvalidOptions, p.MinLength, p.MaxLength)
+	}
+	if p.Length > 0 && (p.Length < p.MinLength || p.MaxLength > 0 && p.Length > p.MaxLength) {
+		return fmt.Errorf("%w: the length %d is outside the accepted range", ErrInvalidOptions, p.Length)
+	}
+	for _, class := range p.Require {
+		for _, forbidden := range p.Forbid {
+			if class == forbidden {
+				return fmt.Errorf("%w: %s characters cannot be both required and forbidden", ErrInvalidOptions, class)
+			}
+		}
+	}
+	return nil
+}
+
+// Options returns the generator options for the policy. The length and charset fall back to
+// the given defaults, with the length moved into the accepted range.
+func (p Policy) Options(length int, charset string) Options {
+	if p.Length > 0 {
+		length = p.Length
+	}
+	if length < p.MinLength {
+		length = p.MinLength
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		length = p.MaxLength
+	}
+	if p.Charset != "" {
+		charset = p.Charset
+	}
+
+	var exclude []rune
+	for _, r := range charset {
+		for _, class := range p.Forbid {
+			if class.Contains(r) {
+				exclude = append(exclude, r)
+			}
+		}
+	}
+	return Options{Length: length, Charset: charset, Exclude: string(exclude), Require: p.Require}
+}
+
+// Check returns ErrPolicyViolation, listing every problem, if value is not accepted by the policy
+func (p Policy) Check(value string) error {
+	var problems []string
+	length := len([]rune(value))
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+	for _, class := range p.Require {
+		if !strings.ContainsFunc(value, class.Contains) {
+			problems = append(problems, "must contain "+class.describe(false))
+		}
+	}
+	for _, class := range p.Forbid {
+		if strings.ContainsFunc(value, class.Contains) {
+			problems = append(problems, "must not contain "+class.describe(true))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrPolicyViolation, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPolicyOptions(t *testing.T) {
+	policy := Policy{MaxLength: 16, Forbid: []Class{Symbol}, Require: []Class{Digit}}
+	opts := policy.Options(24, testCharset)
+	if opts.Length != 16 {
+		t.Errorf("Expected the length to be capped at 16, got %d", opts.Length)
+	}
+
+	result, err := Password(opts)
+	if err != nil {
+		t.Fatalf("Password failed: %v", err)
+	}
+	if err := policy.Check(result.Value); err != nil {
+		t.Errorf("Expected the generated value %q to meet the policy: %v", result.Value, err)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxLength: 12, Require: []Class{Digit}, Forbid: []Class{Symbol}}
+	if err := policy.Check("hunter22hunter"); !errors.Is(err, ErrPolicyViolation) || !strings.Contains(err.Error(), "at most 12") {
+		t.Errorf("Expected a length violation, got %v", err)
+	}
+
+	err := policy.Check("hunter!")
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("Expected ErrPolicyViolation, got %v", err)
+	}
+	for _, problem := range []string{"at least 8", "digit", "symbol"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected %q to mention %q", err, problem)
+		}
+	}
+
+	if err := policy.Check("hunter2hun"); err != nil {
+		t.Errorf("Expected the value to be accepted, got %v", err)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	invalid := []Policy{
+		{MinLength: 20, MaxLength: 16},
+		{Length: 32, MaxLength: 16},
+		{Require: []Class{Symbol}, Forbid: []Class{Symbol}},
+	}
+	for _, policy := range invalid {
+		if err := policy.Validate(); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("Expected %+v to be invalid, got %v", policy, err)
+		}
+	}
+	if err := (Policy{Length: 16, MaxLength: 16}).Validate(); err != nil {
+		t.Errorf("Expected a valid policy, got %v", err)
+	}
+}
@@ -7,7 +7,6 @@ require (
 	github.com/spf13/cobra v1.8.1
 	golang.org/x/crypto v0.27.0
 	golang.org/x/term v0.24.0
-	golang.org/x/text v0.18.0
 	gorm.io/driver/sqlite v1.5.6
 	gorm.io/gorm v1.25.12
 )
@@ -20,4 +19,5 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.22 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/sys v0.25.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
 )
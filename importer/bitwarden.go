@@ -0,0 +1,200 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"vault-cli/kinds"
+)
+
+// Bitwarden item types
+const (
+	bitwardenLogin    = 1
+	bitwardenNote     = 2
+	bitwardenCard     = 3
+	bitwardenIdentity = 4
+	bitwardenSSHKey   = 5
+)
+
+// Bitwarden custom field types
+const (
+	bitwardenText    = 0
+	bitwardenHidden  = 1
+	bitwardenBoolean = 2
+	bitwardenLinked  = 3
+)
+
+// bitwardenExport is the unencrypted JSON export of Bitwarden
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []struct {
+		Type         int       `json:"type"`
+		Name         string    `json:"name"`
+		Notes        string    `json:"notes"`
+		FolderID     string    `json:"folderId"`
+		RevisionDate time.Time `json:"revisionDate"`
+		Fields       []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Type  int    `json:"type"`
+		} `json:"fields"`
+		Login *struct {
+			URIs []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+			TOTP     string `json:"totp"`
+		} `json:"login"`
+		Card *struct {
+			CardholderName string `json:"cardholderName"`
+			Number         string `json:"number"`
+			ExpMonth       string `json:"expMonth"`
+			ExpYear        string `json:"expYear"`
+			Code           string `json:"code"`
+		} `json:"card"`
+		SSHKey *struct {
+			PrivateKey string `json:"privateKey"`
+			PublicKey  string `json:"publicKey"`
+		} `json:"sshKey"`
+	} `json:"items"`
+}
+
+// detectBitwarden recognizes the JSON export by its items and folders, and the CSV export by its login columns
+func detectBitwarden(fileName string, data []byte) bool {
+	if startsWith(data, "{") {
+		var export map[string]json.RawMessage
+		if json.Unmarshal(data, &export) != nil {
+			return false
+		}
+		_, items := export["items"]
+		_, encrypted := export["encrypted"]
+		_, folders := export["folders"]
+		return items && (encrypted || folders)
+	}
+	return hasColumns(csvHeader(data), "type", "name", "login_username", "login_password")
+}
+
+// parseBitwarden reads the JSON or CSV export of Bitwarden. Folders become tags.
+func parseBitwarden(data []byte) (Result, error) {
+	if !startsWith(data, "{") {
+		return parseBitwardenCSV(data)
+	}
+
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return Result{}, fmt.Errorf("failed to decode JSON: %v", err)
+	}
+	if export.Encrypted {
+		return Result{}, fmt.Errorf("the export is encrypted; export the vault again choosing the unencrypted JSON format")
+	}
+	folders := make(map[string]string)
+	for _, folder := range export.Folders {
+		folders[folder.ID] = folder.Name
+	}
+
+	var result Result
+	for _, bw := range export.Items {
+		item := Item{Service: bw.Name, Tags: splitTags(folders[bw.FolderID], "/"), UpdatedAt: bw.RevisionDate}
+		item.Details.Notes = bw.Notes
+
+		switch {
+		case bw.Type == bitwardenLogin && bw.Login != nil:
+			item.Kind, item.Identifier, item.Value = kinds.Login, bw.Login.Username, bw.Login.Password
+			for _, uri := range bw.Login.URIs {
+				item.addURL(uri.URI)
+			}
+			item.setOTP(bw.Login.TOTP)
+		case bw.Type == bitwardenNote:
+			item.Kind, item.Identifier, item.Value = kinds.SecureNote, bw.Name, bw.Notes
+			item.Details.Notes = ""
+		case bw.Type == bitwardenCard && bw.Card != nil:
+			item.Kind, item.Identifier, item.Value = kinds.CreditCard, bw.Card.CardholderName, bw.Card.Number
+			if item.Identifier == "" {
+				item.Identifier = bw.Name
+			}
+			item.addField("expiry", cardExpiry(bw.Card.ExpMonth, bw.Card.ExpYear), false)
+			item.addField("cvv", bw.Card.Code, true)
+		case bw.Type == bitwardenSSHKey && bw.SSHKey != nil:
+			item.Kind, item.Identifier, item.Value = kinds.SSHKey, bw.Name, bw.SSHKey.PrivateKey
+			item.addField("public_key", bw.SSHKey.PublicKey, false)
+		case bw.Type == bitwardenIdentity:
+			result.skip(bw.Name, "identities are not supported")
+			continue
+		default:
+			result.skip(bw.Name, "unsupported item type %d", bw.Type)
+			continue
+		}
+
+		for _, field := range bw.Fields {
+			switch field.Type {
+			case bitwardenText, bitwardenBoolean:
+				item.addField(field.Name, field.Value, false)
+			case bitwardenHidden:
+				item.addField(field.Name, field.Value, true)
+			}
+			// Linked fields only point at other fields of the item
+		}
+		result.add(bw.Name, item)
+	}
+	return result, nil
+}
+
+// parseBitwardenCSV reads the CSV export of Bitwarden, which holds logins and secure notes
+func parseBitwardenCSV(data []byte) (Result, error) {
+	records, err := csvRecords(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, record := range records {
+		name := record["name"]
+		item := Item{Service: name, Tags: splitTags(record["folder"], "/")}
+		item.Details.Notes = record["notes"]
+		switch record["type"] {
+		case "login":
+			item.Kind, item.Identifier, item.Value = kinds.Login, record["login_username"], record["login_password"]
+			for _, uri := range strings.Split(record["login_uri"], ",") {
+				item.addURL(uri)
+			}
+			item.setOTP(record["login_totp"])
+		case "note":
+			item.Kind, item.Identifier, item.Value = kinds.SecureNote, name, record["notes"]
+			item.Details.Notes = ""
+		default:
+			result.skip(name, "unsupported item type %q", record["type"])
+			continue
+		}
+
+		// Custom fields are written one per line as "name: value"
+		for _, line := range strings.Split(record["fields"], "\n") {
+			if fieldName, value, ok := strings.Cut(line, ": "); ok {
+				item.addField(fieldName, value, false)
+			}
+		}
+		result.add(name, item)
+	}
+	return result, nil
+}
+
+// cardExpiry returns the MM/YY expiry date of a card, or "" if either part is missing
+func cardExpiry(month, year string) string {
+	month, year = strings.TrimSpace(month), strings.TrimSpace(year)
+	if month == "" || year == "" {
+		return ""
+	}
+	if len(month) == 1 {
+		month = "0" + month
+	}
+	if len(year) > 2 {
+		year = year[len(year)-2:]
+	}
+	return month + "/" + year
+}
@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"strconv"
+	"time"
+
+	"vault-cli/kinds"
+)
+
+// detectFirefox recognizes the CSV export of Firefox by its columns for HTTP authentication and forms
+func detectFirefox(fileName string, data []byte) bool {
+	header := csvHeader(data)
+	return hasColumns(header, "url", "username", "password") && (hasColumns(header, "httprealm") || hasColumns(header, "formactionorigin"))
+}
+
+// parseFirefox reads the CSV export of Firefox. Entries are named after the host of their URL.
+func parseFirefox(data []byte) (Result, error) {
+	records, err := csvRecords(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for i, record := range records {
+		item := Item{Service: hostName(record["url"]), Kind: kinds.Login, Identifier: record["username"], Value: record["password"]}
+		item.addURL(record["url"])
+		// Times are in milliseconds since the Unix epoch
+		if changed, err := strconv.ParseInt(record["timepasswordchanged"], 10, 64); err == nil && changed > 0 {
+			item.UpdatedAt = time.UnixMilli(changed)
+		}
+		result.add(browserItemName(i, record), item)
+	}
+	return result, nil
+}
+
+// detectChrome recognizes the CSV export of Chromium browsers, which name each password
+func detectChrome(fileName string, data []byte) bool {
+	header := csvHeader(data)
+	return hasColumns(header, "name", "url", "username", "password") && !hasColumns(header, "grouping")
+}
+
+// parseChrome reads the CSV export of Chrome, Edge, Brave and other Chromium browsers
+func parseChrome(data []byte) (Result, error) {
+	records, err := csvRecords(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for i, record := range records {
+		item := Item{Service: record["name"], Kind: kinds.Login, Identifier: record["username"], Value: record["password"]}
+		if item.Service == "" {
+			item.Service = hostName(record["url"])
+		}
+		item.Details.Notes = record["note"]
+		item.addURL(record["url"])
+		result.add(browserItemName(i, record), item)
+	}
+	return result, nil
+}
+
+// browserItemName names a row of a browser export in the skip report
+func browserItemName(i int, record map[string]string) string {
+	if record["url"] != "" {
+		return record["url"]
+	}
+	return "row " + strconv.Itoa(i+2)
+}
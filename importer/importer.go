@@ -0,0 +1,269 @@
+// Package importer reads the exports of password managers into vault entries. Each supported
+// export is a Format, found by name or detected from the file's name and content.
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	db "vault-cli/database"
+	"vault-cli/otp"
+)
+
+// byteOrderMark starts some UTF-8 exports, notably those written on Windows
+const byteOrderMark = "\ufeff"
+
+// ErrUnknownFormat is returned for format names that are not registered and files no format recognizes
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Item is an entry read from an export
+type Item struct {
+	Service    string
+	Identifier string
+	Kind       string // Name of the entry's kind; aliases and legacy identifier types are resolved by the caller
+	Value      string
+	Details    db.EntryDetails
+	Tags       []string
+	UpdatedAt  time.Time // When the item was last changed, or zero if the export does not say
+}
+
+// Skipped is an item of an export that could not be imported
+type Skipped struct {
+	Name   string // Title of the item in the export, or its position if it has none
+	Reason string
+}
+
+// Result holds what was read from an export
+type Result struct {
+	Items   []Item
+	Skipped []Skipped
+}
+
+// skip records an item that is not imported
+func (r *Result) skip(name, format string, args ...any) {
+	if name == "" {
+		name = "(untitled)"
+	}
+	r.Skipped = append(r.Skipped, Skipped{Name: name, Reason: fmt.Sprintf(format, args...)})
+}
+
+// add records an item, or skips it if it lacks a service or value. Items without a username
+// are identified by their service.
+func (r *Result) add(name string, item Item) {
+	item.Service, item.Identifier = strings.TrimSpace(item.Service), strings.TrimSpace(item.Identifier)
+	if item.Identifier == "" {
+		item.Identifier = item.Service
+	}
+	switch {
+	case item.Service == "":
+		r.skip(name, "no name or URL to use as the service")
+	case item.Value == "":
+		r.skip(name, "no password or secret")
+	default:
+		r.Items = append(r.Items, item)
+	}
+}
+
+// Format reads the exports of one password manager
+type Format struct {
+	Name        string
+	Description string
+	detect      func(fileName string, data []byte) bool // Reports whether data is an export in this format
+	parse       func(data []byte) (Result, error)
+}
+
+// Parse reads the items of an export
+func (f Format) Parse(data []byte) (Result, error) {
+	result, err := f.parse(bytes.TrimPrefix(data, []byte(byteOrderMark)))
+	if err != nil {
+		return Result{}, fmt.Errorf("error reading %s export: %w", f.Name, err)
+	}
+	return result, nil
+}
+
+// formats lists the supported exports, in the order they are tried when detecting the format
+var formats = []Format{
+	{Name: "vault-cli", Description: "JSON or CSV export of vault-cli", detect: detectNative, parse: parseNative},
+	{Name: "bitwarden", Description: "Bitwarden unencrypted JSON or CSV export", detect: detectBitwarden, parse: parseBitwarden},
+	{Name: "1password", Description: "1Password 1PUX or CSV export", detect: detectOnePassword, parse: parseOnePassword},
+	{Name: "lastpass", Description: "LastPass CSV export", detect: detectLastPass, parse: parseLastPass},
+	{Name: "keepass-xml", Description: "KeePass 2 or KeePassXC XML export", detect: detectKeePass, parse: parseKeePass},
+	{Name: "firefox", Description: "Firefox passwords CSV export", detect: detectFirefox, parse: parseFirefox},
+	{Name: "chrome", Description: "Chrome or other Chromium browsers' passwords CSV export", detect: detectChrome, parse: parseChrome},
+}
+
+// Formats returns the supported formats
+func Formats() []Format {
+	return slices.Clone(formats)
+}
+
+// Names returns the names of the supported formats
+func Names() []string {
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		names[i] = format.Name
+	}
+	return names
+}
+
+// Lookup returns the format with the given name
+func Lookup(name string) (Format, error) {
+	for _, format := range formats {
+		if strings.EqualFold(format.Name, name) {
+			return format, nil
+		}
+	}
+	return Format{}, fmt.Errorf("%w %q: expected one of %s", ErrUnknownFormat, name, strings.Join(Names(), ", "))
+}
+
+// Detect returns the format of an export from its file name and content
+func Detect(fileName string, data []byte) (Format, error) {
+	data = bytes.TrimPrefix(data, []byte(byteOrderMark))
+	for _, format := range formats {
+		if format.detect(fileName, data) {
+			return format, nil
+		}
+	}
+	return Format{}, fmt.Errorf("%w: could not recognize %s, name its format with --from (%s)", ErrUnknownFormat, fileName, strings.Join(Names(), ", "))
+}
+
+// startsWith reports whether data, ignoring leading white space, starts with prefix
+func startsWith(data []byte, prefix string) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(prefix))
+}
+
+// csvHeader returns the lower-case column names of a CSV file, or nil if data does not start with a CSV header
+func csvHeader(data []byte) []string {
+	if startsWith(data, "{") || startsWith(data, "[") || startsWith(data, "<") {
+		return nil
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	return header
+}
+
+// hasColumns reports whether header contains every one of columns
+func hasColumns(header []string, columns ...string) bool {
+	for _, column := range columns {
+		if !slices.Contains(header, column) {
+			return false
+		}
+	}
+	return len(header) > 0
+}
+
+// csvRecords reads a CSV file with a header row, returning each row as a map from the lower-case column name to its value
+func csvRecords(data []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CSV header: %v", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var records []map[string]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %v", err)
+		}
+		record := make(map[string]string, len(header))
+		for i, value := range row {
+			if i < len(header) {
+				record[header[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// hostName returns the host of a URL without a leading "www.", or "" if it has none
+func hostName(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return ""
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// splitTags returns the non-empty tags in s, separated by any of seps. Commas, which tags
+// cannot contain, are replaced with spaces.
+func splitTags(s string, seps string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return strings.ContainsRune(seps, r) }) {
+		if tag = strings.TrimSpace(strings.ReplaceAll(tag, ",", " ")); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// setOTP stores a one-time password seed given as an otpauth:// URI or a base32 secret. Seeds
+// in other forms, such as Steam's, are kept as a secret field so that nothing is lost.
+func (i *Item) setOTP(seed string) {
+	seed = strings.TrimSpace(seed)
+	if seed == "" {
+		return
+	}
+	key, err := otp.ParseURI(seed)
+	if err != nil {
+		if key, err = otp.NewKey(seed); err == nil {
+			key.Issuer, key.Account = i.Service, i.Identifier
+		}
+	}
+	if err != nil {
+		i.addField("otp", seed, true)
+		return
+	}
+	i.Details.OTP = key.URI()
+}
+
+// addField adds a custom field unless its value is empty. Fields named like one already present are numbered.
+func (i *Item) addField(name, value string, secret bool) {
+	name = strings.TrimSpace(name)
+	if value == "" {
+		return
+	}
+	if name == "" {
+		name = "field"
+	}
+	unique := name
+	for n := 2; slices.ContainsFunc(i.Details.Fields, func(f db.Field) bool { return strings.EqualFold(f.Name, unique) }); n++ {
+		unique = fmt.Sprintf("%s_%d", name, n)
+	}
+	i.Details.Fields = append(i.Details.Fields, db.Field{Name: unique, Value: value, Secret: secret})
+}
+
+// addURL adds a URL unless it is empty or already present
+func (i *Item) addURL(rawURL string) {
+	if rawURL = strings.TrimSpace(rawURL); rawURL != "" && !slices.Contains(i.Details.URLs, rawURL) {
+		i.Details.URLs = append(i.Details.URLs, rawURL)
+	}
+}
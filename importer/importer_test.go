@@ -0,0 +1,323 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	db "vault-cli/database"
+)
+
+const bitwardenJSON = `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work/Infra"}],
+  "items": [
+    {"type": 1, "name": "GitHub", "folderId": "f1", "notes": "2FA on phone", "revisionDate": "2024-03-01T10:00:00.000Z",
+     "fields": [{"name": "recovery", "value": "abcd-efgh", "type": 1}, {"name": "linked", "value": null, "type": 3}],
+     "login": {"uris": [{"uri": "https://github.com/login"}], "username": "octocat", "password": "hunter2", "totp": "JBSWY3DPEHPK3PXP"}},
+    {"type": 2, "name": "Door code", "notes": "1234#"},
+    {"type": 3, "name": "Visa", "card": {"cardholderName": "Jane Doe", "number": "4111111111111111", "expMonth": "7", "expYear": "2027", "code": "123"}},
+    {"type": 4, "name": "Me", "identity": {}},
+    {"type": 1, "name": "Empty", "login": {"username": "nobody"}}
+  ]
+}`
+
+const bitwardenCSV = byteOrderMark + "folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+	"Work,,login,GitHub,,\"team: core\",0,https://github.com,octocat,hunter2,\n" +
+	",,note,Door code,1234#,,0,,,,\n"
+
+const lastPassCSV = "url,username,password,totp,extra,name,grouping,fav\n" +
+	"https://github.com,octocat,hunter2,JBSWY3DPEHPK3PXP,some notes,GitHub,Work\\Dev,0\n" +
+	"http://sn,,,,\"NoteType:Credit Card\nNumber:4111\",Visa,,0\n" +
+	"http://sn,,,,just a note,Note,,0\n"
+
+const keePassXML = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+  <Meta><RecycleBinUUID>BIN</RecycleBinUUID></Meta>
+  <Root>
+    <Group>
+      <UUID>ROOT</UUID><Name>Database</Name>
+      <Entry>
+        <Tags>prod;db</Tags>
+        <Times><LastModificationTime>2024-01-02T03:04:05Z</LastModificationTime></Times>
+        <String><Key>Title</Key><Value>Postgres</Value></String>
+        <String><Key>UserName</Key><Value>admin</Value></String>
+        <String><Key>Password</Key><Value ProtectInMemory="True">s3cret</Value></String>
+        <String><Key>URL</Key><Value>https://db.example.com</Value></String>
+        <String><Key>otp</Key><Value>otpauth://totp/DB:admin?secret=JBSWY3DPEHPK3PXP</Value></String>
+        <String><Key>PIN</Key><Value ProtectInMemory="True">9999</Value></String>
+        <History><Entry><String><Key>Title</Key><Value>Old</Value></String></Entry></History>
+      </Entry>
+      <Group>
+        <UUID>WEB</UUID><Name>Web</Name>
+        <Entry>
+          <String><Key>Title</Key><Value>Forum</Value></String>
+          <String><Key>Password</Key><Value>pw</Value></String>
+        </Entry>
+      </Group>
+      <Group>
+        <UUID>BIN</UUID><Name>Recycle Bin</Name>
+        <Entry><String><Key>Title</Key><Value>Deleted</Value></String><String><Key>Password</Key><Value>x</Value></String></Entry>
+      </Group>
+    </Group>
+  </Root>
+</KeePassFile>`
+
+const firefoxCSV = `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"
+"https://www.example.com","alice","pw1",,"https://www.example.com","{1}","1700000000000","1700000000000","1700000000000"
+`
+
+const chromeCSV = "name,url,username,password,note\n" +
+	"example.com,https://example.com/login,alice,pw1,\n" +
+	",https://nouser.example.org/,,pw2,a note\n"
+
+const onePasswordCSV = "Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+	"GitHub,https://github.com,octocat,hunter2,otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP,false,false,dev;work,\n" +
+	"Old,https://old.example.com,me,pw,,false,true,,\n"
+
+const onePasswordExportData = `{"accounts": [{"vaults": [{"attrs": {"name": "Personal"}, "items": [
+  {"updatedAt": 1700000000, "state": "active", "categoryUuid": "001",
+   "overview": {"title": "GitHub", "url": "https://github.com", "tags": ["dev"]},
+   "details": {"loginFields": [{"value": "octocat", "designation": "username"}, {"value": "hunter2", "designation": "password"}],
+     "notesPlain": "n", "sections": [{"fields": [{"title": "one-time password", "id": "TOTP_1", "value": {"totp": "JBSWY3DPEHPK3PXP"}},
+       {"title": "recovery", "id": "r", "value": {"concealed": "abcd"}}]}]}},
+  {"state": "active", "categoryUuid": "002", "overview": {"title": "Visa"},
+   "details": {"sections": [{"fields": [{"id": "cardholder", "value": {"string": "Jane Doe"}},
+     {"id": "ccnum", "value": {"creditCardNumber": "4111111111111111"}}, {"id": "expiry", "value": {"monthYear": 202712}},
+     {"id": "cvv", "value": {"concealed": "123"}}]}]}},
+  {"state": "archived", "categoryUuid": "001", "overview": {"title": "Gone"}, "details": {}},
+  {"state": "active", "categoryUuid": "006", "overview": {"title": "Passport scan"}, "details": {}}
+]}]}]}`
+
+// onePasswordArchive returns a 1PUX archive holding data as export.data
+func onePasswordArchive(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"export.attributes": "{}", "export.data": data} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// findItem returns the item with the given service, failing the test if there is none
+func findItem(t *testing.T, result Result, service string) Item {
+	t.Helper()
+	for _, item := range result.Items {
+		if item.Service == service {
+			return item
+		}
+	}
+	t.Fatalf("no item for %q in %+v", service, result.Items)
+	return Item{}
+}
+
+// skippedNames returns the names of the skipped items
+func skippedNames(result Result) []string {
+	var names []string
+	for _, skipped := range result.Skipped {
+		names = append(names, skipped.Name)
+	}
+	return names
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string][]byte{
+		"vault-cli":   []byte("Service,Identifier,Kind,Value\na,b,login,c\n"),
+		"bitwarden":   []byte(bitwardenJSON),
+		"1password":   onePasswordArchive(t, onePasswordExportData),
+		"lastpass":    []byte(lastPassCSV),
+		"keepass-xml": []byte(keePassXML),
+		"firefox":     []byte(firefoxCSV),
+		"chrome":      []byte(chromeCSV),
+	}
+	for want, data := range tests {
+		format, err := Detect("export", data)
+		if err != nil || format.Name != want {
+			t.Errorf("Detect(%s export) = %q, %v", want, format.Name, err)
+		}
+	}
+	for _, data := range []string{bitwardenCSV, onePasswordCSV, `[{"Service": "a"}]`} {
+		if _, err := Detect("export", []byte(data)); err != nil {
+			t.Errorf("Detect(%.30q) failed: %v", data, err)
+		}
+	}
+
+	if _, err := Detect("notes.txt", []byte("just,some,columns\n")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+	if _, err := Lookup("Bitwarden"); err != nil {
+		t.Errorf("Lookup is expected to ignore case, got %v", err)
+	}
+	if _, err := Lookup("dashlane"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestNativeCSV(t *testing.T) {
+	data := "Service,Identifier,Kind,Value,Notes,URLs,Tags,OTP,Fields\n" +
+		"GitHub,octocat,login,hunter2,2FA on phone,\"https://github.com\nhttps://gist.github.com\",\"dev,work\",otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP," +
+		"\"[{\"\"name\"\":\"\"recovery\"\",\"\"value\"\":\"\"abcd\"\",\"\"secret\"\":true}]\"\n" +
+		"Broken,me,login,pw,,,,,{not json\n"
+	format, err := Detect("export.csv", []byte(data))
+	if err != nil || format.Name != "vault-cli" {
+		t.Fatalf("Detect() = %q, %v", format.Name, err)
+	}
+	result, err := format.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(result.Items) != 1 || strings.Join(skippedNames(result), ",") != "row 3 (Broken)" {
+		t.Fatalf("unexpected items %+v, skipped %+v", result.Items, result.Skipped)
+	}
+
+	// Everything the export writes is read back
+	github := result.Items[0]
+	fields := github.Details.Fields
+	if github.Details.Notes != "2FA on phone" || len(github.Details.URLs) != 2 || strings.Join(github.Tags, ",") != "dev,work" ||
+		github.Details.OTP == "" || len(fields) != 1 || fields[0] != (db.Field{Name: "recovery", Value: "abcd", Secret: true}) {
+		t.Errorf("unexpected item %+v", github)
+	}
+}
+
+func TestBitwarden(t *testing.T) {
+	format, _ := Lookup("bitwarden")
+	result, err := format.Parse([]byte(bitwardenJSON))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(result.Items) != 3 || strings.Join(skippedNames(result), ",") != "Me,Empty" {
+		t.Fatalf("unexpected items %+v, skipped %+v", result.Items, result.Skipped)
+	}
+
+	github := findItem(t, result, "GitHub")
+	if github.Kind != "login" || github.Identifier != "octocat" || github.Value != "hunter2" ||
+		github.Details.Notes != "2FA on phone" || github.Details.URLs[0] != "https://github.com/login" ||
+		!strings.HasPrefix(github.Details.OTP, "otpauth://totp/") || strings.Join(github.Tags, ",") != "Work,Infra" ||
+		!github.UpdatedAt.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected login %+v", github)
+	}
+	if len(github.Details.Fields) != 1 || github.Details.Fields[0] != (db.Field{Name: "recovery", Value: "abcd-efgh", Secret: true}) {
+		t.Errorf("unexpected fields %+v", github.Details.Fields)
+	}
+	if note := findItem(t, result, "Door code"); note.Kind != "secure_note" || note.Identifier != "Door code" || note.Value != "1234#" || note.Details.Notes != "" {
+		t.Errorf("unexpected note %+v", note)
+	}
+	card := findItem(t, result, "Visa")
+	if card.Kind != "credit_card" || card.Identifier != "Jane Doe" || card.Details.Fields[0].Value != "07/27" || !card.Details.Fields[1].Secret {
+		t.Errorf("unexpected card %+v", card)
+	}
+
+	if _, err := format.Parse([]byte(`{"encrypted": true, "items": []}`)); err == nil {
+		t.Error("expected encrypted exports to be rejected")
+	}
+
+	result, err = format.Parse([]byte(bitwardenCSV))
+	if err != nil || len(result.Items) != 2 {
+		t.Fatalf("Parse(CSV) = %+v, %v", result, err)
+	}
+	if github := findItem(t, result, "GitHub"); github.Details.Fields[0].Name != "team" || github.Tags[0] != "Work" {
+		t.Errorf("unexpected CSV login %+v", github)
+	}
+}
+
+func TestOnePassword(t *testing.T) {
+	format, _ := Lookup("1password")
+	result, err := format.Parse(onePasswordArchive(t, onePasswordExportData))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(result.Items) != 2 || strings.Join(skippedNames(result), ",") != "Gone,Passport scan" {
+		t.Fatalf("unexpected items %+v, skipped %+v", result.Items, result.Skipped)
+	}
+	github := findItem(t, result, "GitHub")
+	if github.Identifier != "octocat" || github.Value != "hunter2" || github.Details.OTP == "" ||
+		len(github.Details.Fields) != 1 || !github.Details.Fields[0].Secret || strings.Join(github.Tags, ",") != "Personal,dev" ||
+		github.UpdatedAt.Unix() != 1700000000 {
+		t.Errorf("unexpected login %+v", github)
+	}
+	card := findItem(t, result, "Visa")
+	if card.Kind != "credit_card" || card.Identifier != "Jane Doe" || card.Value != "4111111111111111" || card.Details.Fields[0].Value != "12/27" {
+		t.Errorf("unexpected card %+v", card)
+	}
+
+	result, err = format.Parse([]byte(onePasswordCSV))
+	if err != nil || len(result.Items) != 1 || len(result.Skipped) != 1 {
+		t.Fatalf("Parse(CSV) = %+v, %v", result, err)
+	}
+	if github := result.Items[0]; github.Details.OTP == "" || strings.Join(github.Tags, ",") != "dev,work" {
+		t.Errorf("unexpected CSV login %+v", github)
+	}
+}
+
+func TestLastPass(t *testing.T) {
+	format, _ := Lookup("lastpass")
+	result, err := format.Parse([]byte(lastPassCSV))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(result.Items) != 2 || strings.Join(skippedNames(result), ",") != "Visa" {
+		t.Fatalf("unexpected items %+v, skipped %+v", result.Items, result.Skipped)
+	}
+	github := findItem(t, result, "GitHub")
+	if github.Details.Notes != "some notes" || github.Details.OTP == "" || strings.Join(github.Tags, ",") != "Work,Dev" {
+		t.Errorf("unexpected login %+v", github)
+	}
+	if note := findItem(t, result, "Note"); note.Kind != "secure_note" || note.Value != "just a note" {
+		t.Errorf("unexpected note %+v", note)
+	}
+}
+
+func TestKeePass(t *testing.T) {
+	format, _ := Lookup("keepass-xml")
+	result, err := format.Parse([]byte(keePassXML))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(result.Items) != 2 || strings.Join(skippedNames(result), ",") != "Deleted" {
+		t.Fatalf("unexpected items %+v, skipped %+v", result.Items, result.Skipped)
+	}
+	pg := findItem(t, result, "Postgres")
+	if pg.Identifier != "admin" || pg.Value != "s3cret" || pg.Details.OTP == "" || strings.Join(pg.Tags, ",") != "prod,db" ||
+		len(pg.Details.Fields) != 1 || pg.Details.Fields[0] != (db.Field{Name: "PIN", Value: "9999", Secret: true}) ||
+		!pg.UpdatedAt.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected entry %+v", pg)
+	}
+	if forum := findItem(t, result, "Forum"); forum.Identifier != "Forum" || strings.Join(forum.Tags, ",") != "Web" {
+		t.Errorf("unexpected entry %+v", forum)
+	}
+
+	// KDBX 4 writes times as base64 seconds since year 1
+	if got := keePassTime("KHQl3Q4AAAA="); !got.Equal(time.Date(2024, 1, 2, 3, 4, 8, 0, time.UTC)) {
+		t.Errorf("keePassTime = %v", got)
+	}
+}
+
+func TestBrowsers(t *testing.T) {
+	firefox, _ := Lookup("firefox")
+	result, err := firefox.Parse([]byte(firefoxCSV))
+	if err != nil || len(result.Items) != 1 {
+		t.Fatalf("Parse = %+v, %v", result, err)
+	}
+	if item := result.Items[0]; item.Service != "example.com" || item.Identifier != "alice" || item.UpdatedAt.UnixMilli() != 1700000000000 {
+		t.Errorf("unexpected Firefox item %+v", item)
+	}
+
+	chrome, _ := Lookup("chrome")
+	result, err = chrome.Parse([]byte(chromeCSV))
+	if err != nil || len(result.Items) != 2 {
+		t.Fatalf("Parse = %+v, %v", result, err)
+	}
+	if item := findItem(t, result, "nouser.example.org"); item.Identifier != "nouser.example.org" || item.Details.Notes != "a note" {
+		t.Errorf("unexpected Chrome item %+v", item)
+	}
+}
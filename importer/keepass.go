@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"vault-cli/kinds"
+)
+
+// keePassFile is the XML export of KeePass 2 and KeePassXC
+type keePassFile struct {
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"` // Previous versions of entries are nested in their History and not read
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Tags  string `xml:"Tags"`
+	Times struct {
+		LastModificationTime string `xml:"LastModificationTime"`
+	} `xml:"Times"`
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Text            string `xml:",chardata"`
+			Protected       string `xml:"Protected,attr"`       // Encrypted with the database's inner stream
+			ProtectInMemory string `xml:"ProtectInMemory,attr"` // Exported in clear text, but meant to be hidden
+		} `xml:"Value"`
+	} `xml:"String"`
+}
+
+// detectKeePass recognizes the KeePassFile root element
+func detectKeePass(fileName string, data []byte) bool {
+	return startsWith(data, "<") && bytes.Contains(data, []byte("<KeePassFile"))
+}
+
+// parseKeePass reads the XML export of KeePass. The groups an entry is in become its tags,
+// and entries in the recycle bin are skipped.
+func parseKeePass(data []byte) (Result, error) {
+	var file keePassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return Result{}, fmt.Errorf("failed to decode XML: %v", err)
+	}
+
+	var result Result
+	var walk func(group keePassGroup, path []string)
+	walk = func(group keePassGroup, path []string) {
+		inRecycleBin := group.UUID != "" && group.UUID == file.Meta.RecycleBinUUID
+		for _, entry := range group.Entries {
+			parseKeePassEntry(&result, entry, path, inRecycleBin)
+		}
+		for _, child := range group.Groups {
+			if inRecycleBin {
+				child.UUID = group.UUID // Everything below the recycle bin is deleted too
+			}
+			walk(child, append(path[:len(path):len(path)], child.Name))
+		}
+	}
+	// The top group is the database itself, not a folder
+	for _, root := range file.Root.Groups {
+		walk(root, nil)
+	}
+	return result, nil
+}
+
+// parseKeePassEntry adds or skips an entry of a KeePass export
+func parseKeePassEntry(result *Result, entry keePassEntry, groups []string, inRecycleBin bool) {
+	strs := make(map[string]string)
+	for _, s := range entry.Strings {
+		strs[s.Key] = s.Value.Text
+	}
+	name := strs["Title"]
+	if inRecycleBin {
+		result.skip(name, "in the recycle bin")
+		return
+	}
+
+	item := Item{Service: name, Kind: kinds.Login, Identifier: strs["UserName"], Value: strs["Password"]}
+	if item.Service == "" {
+		item.Service = hostName(strs["URL"])
+	}
+	for _, group := range groups {
+		item.Tags = append(item.Tags, splitTags(group, "")...)
+	}
+	item.Tags = append(item.Tags, splitTags(entry.Tags, ",;")...)
+	item.UpdatedAt = keePassTime(entry.Times.LastModificationTime)
+	item.Details.Notes = strs["Notes"]
+	item.addURL(strs["URL"])
+
+	for _, s := range entry.Strings {
+		if strings.EqualFold(s.Value.Protected, "true") {
+			result.skip(name, "the export holds encrypted values; export the database to XML from KeePass instead")
+			return
+		}
+		switch s.Key {
+		case "Title", "UserName", "Password", "URL", "Notes":
+		case "otp", "TimeOtp-Secret-Base32", "TOTP Seed":
+			// KeePassXC stores an otpauth:// URI, KeePass and the KeeOtp plugin a base32 seed
+			item.setOTP(s.Value.Text)
+		default:
+			item.addField(s.Key, s.Value.Text, strings.EqualFold(s.Value.ProtectInMemory, "true"))
+		}
+	}
+	result.add(name, item)
+}
+
+// secondsFromYearOne is the number of seconds from 0001-01-01 to the Unix epoch
+const secondsFromYearOne = 62135596800
+
+// keePassTime parses a time of a KeePass export: RFC 3339 text, or in newer files the
+// base64-encoded little-endian number of seconds since year 1. It returns zero if t is neither.
+func keePassTime(t string) time.Time {
+	if parsed, err := time.Parse(time.RFC3339, t); err == nil {
+		return parsed
+	}
+	raw, err := base64.StdEncoding.DecodeString(t)
+	if err != nil || len(raw) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(int64(binary.LittleEndian.Uint64(raw))-secondsFromYearOne, 0).UTC()
+}
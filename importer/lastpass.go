@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"strings"
+
+	"vault-cli/kinds"
+)
+
+// lastPassNoteURL is the URL LastPass gives secure notes in its CSV export
+const lastPassNoteURL = "http://sn"
+
+// detectLastPass recognizes the CSV export of LastPass by its extra and grouping columns
+func detectLastPass(fileName string, data []byte) bool {
+	return hasColumns(csvHeader(data), "url", "username", "password", "extra", "name", "grouping")
+}
+
+// parseLastPass reads the CSV export of LastPass. Folders, written as "a\b", become tags.
+func parseLastPass(data []byte) (Result, error) {
+	records, err := csvRecords(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, record := range records {
+		name := record["name"]
+		item := Item{Service: name, Tags: splitTags(record["grouping"], `\`)}
+		if item.Service == "" {
+			item.Service = hostName(record["url"])
+		}
+
+		if record["url"] == lastPassNoteURL {
+			// Notes of other types, such as credit cards, hold their fields as "NoteType:..." lines
+			if noteType, _, _ := strings.Cut(record["extra"], "\n"); strings.HasPrefix(noteType, "NoteType:") {
+				result.skip(name, "LastPass %s notes are not supported", strings.TrimPrefix(noteType, "NoteType:"))
+				continue
+			}
+			item.Kind, item.Identifier, item.Value = kinds.SecureNote, name, record["extra"]
+			result.add(name, item)
+			continue
+		}
+
+		item.Kind, item.Identifier, item.Value = kinds.Login, record["username"], record["password"]
+		item.Details.Notes = record["extra"]
+		item.addURL(record["url"])
+		item.setOTP(record["totp"])
+		result.add(name, item)
+	}
+	return result, nil
+}
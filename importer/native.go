@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	db "vault-cli/database"
+)
+
+// nativeHeaders are the CSV columns written by export, those written before the entries' details
+// were exported, and those written before entry kinds existed
+var nativeHeaders = [][]string{
+	{"service", "identifier", "kind", "value", "notes", "urls", "tags", "otp", "fields"},
+	{"service", "identifier", "kind", "value"},
+	{"service", "identifier", "identifier type", "value"},
+}
+
+// detectNative recognizes the JSON array and the CSV columns written by export
+func detectNative(fileName string, data []byte) bool {
+	if startsWith(data, "[") {
+		return true
+	}
+	header := csvHeader(data)
+	return slices.ContainsFunc(nativeHeaders, func(columns []string) bool { return slices.Equal(header, columns) })
+}
+
+// parseNative reads an export of vault-cli, in JSON with the entries' details or in CSV
+func parseNative(data []byte) (Result, error) {
+	if !startsWith(data, "[") {
+		return parseNativeCSV(data)
+	}
+
+	// Exports made before entry kinds existed carry an IdentifierType instead of a Kind
+	var entries []struct {
+		db.SensitiveData
+		IdentifierType string
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return Result{}, fmt.Errorf("failed to decode JSON: %v", err)
+	}
+
+	var result Result
+	for i, entry := range entries {
+		kind := entry.Kind
+		if kind == "" {
+			kind = entry.IdentifierType
+		}
+		result.add(fmt.Sprintf("entry %d (%s)", i+1, entry.Service), Item{
+			Service:    entry.Service,
+			Identifier: entry.Identifier,
+			Kind:       kind,
+			Value:      entry.Value,
+			Details:    entry.Details,
+			Tags:       entry.TagList(),
+			UpdatedAt:  entry.UpdatedAt,
+		})
+	}
+	return result, nil
+}
+
+// parseNativeCSV reads a CSV export: the service, identifier, kind and value of each entry, and its details if exported
+func parseNativeCSV(data []byte) (Result, error) {
+	header := csvHeader(data)
+	if !slices.ContainsFunc(nativeHeaders, func(columns []string) bool { return slices.Equal(header, columns) }) {
+		return Result{}, fmt.Errorf("CSV headers do not match the expected format")
+	}
+	records, err := csvRecords(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for i, record := range records {
+		kind := record["kind"]
+		if kind == "" {
+			kind = record["identifier type"]
+		}
+		name := fmt.Sprintf("row %d (%s)", i+2, record["service"])
+		item := Item{
+			Service:    record["service"],
+			Identifier: record["identifier"],
+			Kind:       kind,
+			Value:      record["value"],
+			Tags:       splitTags(record["tags"], ","),
+		}
+		item.Details.Notes = record["notes"]
+		for _, rawURL := range strings.Split(record["urls"], "\n") {
+			item.addURL(rawURL)
+		}
+		item.Details.OTP = record["otp"]
+		if record["fields"] != "" {
+			if err := json.Unmarshal([]byte(record["fields"]), &item.Details.Fields); err != nil {
+				result.skip(name, "invalid fields: %v", err)
+				continue
+			}
+		}
+		result.add(name, item)
+	}
+	return result, nil
+}
@@ -0,0 +1,305 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"vault-cli/kinds"
+)
+
+// zipMagic starts every ZIP archive, such as a 1PUX export
+const zipMagic = "PK\x03\x04"
+
+// onePasswordExport is the export.data file of a 1PUX archive
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	UpdatedAt    int64  `json:"updatedAt"`
+	State        string `json:"state"`
+	CategoryUUID string `json:"categoryUuid"`
+	Overview     struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+		Tags []string `json:"tags"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Name        string `json:"name"`
+			FieldType   string `json:"fieldType"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []struct {
+				Title string                     `json:"title"`
+				ID    string                     `json:"id"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+	} `json:"details"`
+}
+
+// 1Password item categories that are imported
+const (
+	onePasswordLogin         = "001"
+	onePasswordCreditCard    = "002"
+	onePasswordSecureNote    = "003"
+	onePasswordPassword      = "005"
+	onePasswordDatabase      = "102"
+	onePasswordServer        = "110"
+	onePasswordAPICredential = "112"
+	onePasswordSSHKey        = "114"
+)
+
+// detectOnePassword recognizes a 1PUX archive and the CSV export of 1Password 8
+func detectOnePassword(fileName string, data []byte) bool {
+	if bytes.HasPrefix(data, []byte(zipMagic)) {
+		_, err := onePasswordData(data)
+		return err == nil
+	}
+	header := csvHeader(data)
+	return hasColumns(header, "title", "username", "password") && (hasColumns(header, "url") || hasColumns(header, "otpauth"))
+}
+
+// onePasswordData returns the export.data file of a 1PUX archive
+func onePasswordData(data []byte) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the 1PUX archive: %v", err)
+	}
+	file, err := archive.Open("export.data")
+	if err != nil {
+		return nil, fmt.Errorf("the archive has no export.data: %v", err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// parseOnePassword reads a 1PUX archive or a CSV export of 1Password. Vault names and tags become tags.
+func parseOnePassword(data []byte) (Result, error) {
+	if !bytes.HasPrefix(data, []byte(zipMagic)) {
+		return parseOnePasswordCSV(data)
+	}
+	exportData, err := onePasswordData(data)
+	if err != nil {
+		return Result{}, err
+	}
+	var export onePasswordExport
+	if err := json.Unmarshal(exportData, &export); err != nil {
+		return Result{}, fmt.Errorf("failed to decode export.data: %v", err)
+	}
+
+	var result Result
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, op := range vault.Items {
+				parseOnePasswordItem(&result, op, vault.Attrs.Name)
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseOnePasswordItem adds or skips an item of a 1PUX export
+func parseOnePasswordItem(result *Result, op onePasswordItem, vaultName string) {
+	name := op.Overview.Title
+	if op.State == "archived" {
+		result.skip(name, "archived")
+		return
+	}
+
+	item := Item{Service: name, Tags: append(splitTags(vaultName, ""), op.Overview.Tags...)}
+	if op.UpdatedAt > 0 {
+		item.UpdatedAt = time.Unix(op.UpdatedAt, 0)
+	}
+	item.Details.Notes = op.Details.NotesPlain
+	item.addURL(op.Overview.URL)
+	for _, u := range op.Overview.URLs {
+		item.addURL(u.URL)
+	}
+
+	// Values of section fields by id; those not mapped to the entry are kept as custom fields
+	fields := make(map[string]string)
+	used := make(map[string]bool)
+	take := func(id string) string {
+		used[id] = true
+		return fields[id]
+	}
+	for _, section := range op.Details.Sections {
+		for _, field := range section.Fields {
+			value, _ := onePasswordValue(field.Value)
+			if _, ok := fields[field.ID]; !ok && field.ID != "" {
+				fields[field.ID] = value
+			}
+		}
+	}
+
+	switch op.CategoryUUID {
+	case onePasswordLogin, onePasswordServer:
+		item.Kind = kinds.Login
+		for _, field := range op.Details.LoginFields {
+			switch {
+			case field.Designation == "username":
+				item.Identifier = field.Value
+			case field.Designation == "password":
+				item.Value = field.Value
+			case field.Value != "":
+				item.addField(field.Name, field.Value, field.FieldType == "P")
+			}
+		}
+		if item.Identifier == "" {
+			item.Identifier = take("username")
+		}
+		if item.Value == "" {
+			item.Value = take("password")
+		}
+		item.addURL(take("url"))
+	case onePasswordPassword:
+		item.Kind, item.Identifier, item.Value = kinds.Login, name, op.Details.Password
+	case onePasswordSecureNote:
+		item.Kind, item.Identifier, item.Value = kinds.SecureNote, name, op.Details.NotesPlain
+		item.Details.Notes = ""
+	case onePasswordCreditCard:
+		item.Kind, item.Identifier, item.Value = kinds.CreditCard, take("cardholder"), take("ccnum")
+		item.addField("expiry", take("expiry"), false)
+		item.addField("cvv", take("cvv"), true)
+		item.addField("pin", take("pin"), true)
+	case onePasswordAPICredential:
+		item.Kind, item.Identifier, item.Value = kinds.APIKey, take("username"), take("credential")
+	case onePasswordDatabase:
+		item.Kind, item.Identifier, item.Value = kinds.Database, take("username"), take("password")
+		item.addField("engine", databaseEngine(take("database_type")), false)
+		item.addField("host", take("hostname"), false)
+		item.addField("port", take("port"), false)
+		item.addField("database", take("database"), false)
+	case onePasswordSSHKey:
+		item.Kind, item.Identifier = kinds.SSHKey, name
+		for _, section := range op.Details.Sections {
+			for _, field := range section.Fields {
+				if key, ok := field.Value["sshKey"]; ok {
+					var sshKey struct {
+						PrivateKey string `json:"privateKey"`
+						Metadata   struct {
+							PublicKey string `json:"publicKey"`
+						} `json:"metadata"`
+					}
+					json.Unmarshal(key, &sshKey)
+					item.Value = sshKey.PrivateKey
+					item.addField("public_key", sshKey.Metadata.PublicKey, false)
+					used[field.ID] = true
+				}
+			}
+		}
+	default:
+		result.skip(name, "unsupported 1Password category %s", op.CategoryUUID)
+		return
+	}
+
+	for _, section := range op.Details.Sections {
+		for _, field := range section.Fields {
+			if used[field.ID] {
+				continue
+			}
+			if seed, ok := field.Value["totp"]; ok {
+				var uri string
+				json.Unmarshal(seed, &uri)
+				item.setOTP(uri)
+				continue
+			}
+			value, secret := onePasswordValue(field.Value)
+			title := field.Title
+			if title == "" {
+				title = field.ID
+			}
+			item.addField(title, value, secret)
+		}
+	}
+	result.add(name, item)
+}
+
+// onePasswordValue returns the value of a section field as text, and whether it is concealed
+func onePasswordValue(value map[string]json.RawMessage) (string, bool) {
+	for typ, raw := range value {
+		var text string
+		if json.Unmarshal(raw, &text) == nil {
+			return text, typ == "concealed" || typ == "totp"
+		}
+		var number int64
+		if json.Unmarshal(raw, &number) == nil {
+			switch typ {
+			case "monthYear":
+				// Expiry dates are stored as YYYYMM
+				return fmt.Sprintf("%02d/%02d", number%100, number/100%100), false
+			case "date":
+				return time.Unix(number, 0).UTC().Format(time.DateOnly), false
+			}
+			return strconv.FormatInt(number, 10), false
+		}
+		var email struct {
+			Address string `json:"email_address"`
+		}
+		if json.Unmarshal(raw, &email) == nil && email.Address != "" {
+			return email.Address, false
+		}
+	}
+	return "", false
+}
+
+// databaseEngine maps a 1Password database type to a choice of the database kind
+func databaseEngine(databaseType string) string {
+	switch engine := strings.ToLower(databaseType); engine {
+	case "":
+		return ""
+	case "postgresql":
+		return "postgres"
+	case "sql_server", "mssql":
+		return "sqlserver"
+	case "postgres", "mysql", "mariadb", "sqlserver", "oracle", "mongodb", "redis":
+		return engine
+	default:
+		return "other"
+	}
+}
+
+// parseOnePasswordCSV reads the CSV export of 1Password 8, which holds logins
+func parseOnePasswordCSV(data []byte) (Result, error) {
+	records, err := csvRecords(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, record := range records {
+		name := record["title"]
+		if strings.EqualFold(record["archived"], "true") {
+			result.skip(name, "archived")
+			continue
+		}
+		item := Item{Service: name, Kind: kinds.Login, Identifier: record["username"], Value: record["password"], Tags: splitTags(record["tags"], ",;")}
+		item.Details.Notes = record["notes"]
+		item.addURL(record["url"])
+		item.setOTP(record["otpauth"])
+		result.add(name, item)
+	}
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package kinds
+
+// Names of the built-in kinds
+const (
+	Login      = "login"
+	APIKey     = "api_key"
+	SSHKey     = "ssh_key"
+	Database   = "database"
+	CreditCard = "credit_card"
+	SecureNote = "secure_note"
+	TOTP       = "totp"
+)
+
+// builtins returns the kinds every vault supports
+func builtins() []Kind {
+	return []Kind{
+		{
+			Name:        Login,
+			Description: "Username or email and password for a website or application",
+			Identifier:  Field{Name: "identifier", Label: "Username or email", Type: Text},
+			Value:       Field{Name: "value", Label: "Password", Type: Secret, Required: true},
+			Generate:    true,
+		},
+		{
+			Name:        APIKey,
+			Description: "API key ID and secret",
+			Identifier:  Field{Name: "identifier", Label: "Key ID", Type: Text},
+			Value:       Field{Name: "value", Label: "Secret key", Type: Secret, Required: true},
+			Generate:    true,
+		},
+		{
+			Name:        SSHKey,
+			Description: "SSH private key with its public key and passphrase",
+			Identifier:  Field{Name: "identifier", Label: "Key name", Type: Text},
+			Value:       Field{Name: "value", Label: "Private key", Type: Multiline, Required: true},
+			Fields: []Field{
+				{Name: "public_key", Label: "Public key", Type: Text},
+				{Name: "passphrase", Label: "Passphrase", Type: Secret},
+			},
+		},
+		{
+			Name:        Database,
+			Description: "Database connection credentials",
+			Identifier:  Field{Name: "identifier", Label: "Username", Type: Text},
+			Value:       Field{Name: "value", Label: "Password", Type: Secret, Required: true},
+			Fields: []Field{
+				{Name: "engine", Label: "Engine", Type: Choice, Choices: []string{"postgres", "mysql", "mariadb", "sqlserver", "oracle", "mongodb", "redis", "other"}, Required: true},
+				{Name: "host", Label: "Host", Type: Text, Required: true},
+				{Name: "port", Label: "Port", Type: Number},
+				{Name: "database", Label: "Database name", Type: Text},
+			},
+			Generate: true,
+		},
+		{
+			Name:        CreditCard,
+			Description: "Payment card number, expiry date and security code",
+			Identifier:  Field{Name: "identifier", Label: "Cardholder name", Type: Text},
+			Value:       Field{Name: "value", Label: "Card number", Type: CardNumber, Required: true},
+			Fields: []Field{
+				{Name: "expiry", Label: "Expiry date (MM/YY)", Type: Expiry, Required: true},
+				{Name: "cvv", Label: "Security code", Type: Secret, Pattern: `[0-9]{3,4}`},
+				{Name: "pin", Label: "PIN", Type: Secret, Pattern: `[0-9]{4,12}`},
+			},
+		},
+		{
+			Name:        SecureNote,
+			Description: "Free-form text kept encrypted",
+			Identifier:  Field{Name: "identifier", Label: "Title", Type: Text},
+			Value:       Field{Name: "value", Label: "Note", Type: Multiline, Required: true},
+		},
+		{
+			Name:        TOTP,
+			Description: "Seed for one-time passwords used in two-factor authentication",
+			Identifier:  Field{Name: "identifier", Label: "Account", Type: Text},
+			Value:       Field{Name: "value", Label: "Secret (base32)", Type: Base32, Required: true},
+		},
+	}
+}
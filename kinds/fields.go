@@ -0,0 +1,244 @@
+package kinds
+
+import (
+	"encoding/base32"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FieldType determines how a field is prompted for and which values it accepts
+type FieldType string
+
+const (
+	Text       FieldType = "text"        // A single line of text
+	Secret     FieldType = "secret"      // A single line read without echo and hidden unless revealed
+	Multiline  FieldType = "multiline"   // Several lines of text, such as a private key
+	Number     FieldType = "number"      // A non-negative integer
+	URL        FieldType = "url"         // An absolute URL
+	Email      FieldType = "email"       // An email address
+	CardNumber FieldType = "card_number" // A payment card number passing the Luhn check
+	Expiry     FieldType = "expiry"      // A month and year as MM/YY or MM/YYYY
+	Base32     FieldType = "base32"      // Base32 data such as a TOTP seed
+	Choice     FieldType = "choice"      // One of the field's choices
+)
+
+// FieldTypes lists every field type
+var FieldTypes = []FieldType{Text, Secret, Multiline, Number, URL, Email, CardNumber, Expiry, Base32, Choice}
+
+// Field describes a named value of an entry
+type Field struct {
+	Name     string
+	Label    string    // Shown when prompting for the field
+	Type     FieldType // Defaults to Text
+	Required bool
+	Default  string   // Used when no value is entered
+	Pattern  string   // Regular expression the whole value must match
+	Choices  []string // Accepted values of a Choice field
+}
+
+// IsSecret reports whether the field's value is hidden when an entry is shown
+func (f Field) IsSecret() bool {
+	return f.Type == Secret
+}
+
+// Normalize returns value in the form it is stored in: trimmed, with card numbers and
+// base32 data stripped of separators and choices spelled as in the schema
+func (f Field) Normalize(value string) string {
+	if f.Type != Multiline {
+		value = strings.TrimSpace(value)
+	}
+	switch f.Type {
+	case CardNumber:
+		value = strings.NewReplacer(" ", "", "-", "").Replace(value)
+	case Base32:
+		value = strings.TrimRight(strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(value)), "=")
+	case Choice:
+		for _, choice := range f.Choices {
+			if strings.EqualFold(choice, value) {
+				return choice
+			}
+		}
+	}
+	return value
+}
+
+// Validate checks that value is acceptable for the field. An empty value is only accepted for optional fields.
+func (f Field) Validate(value string) error {
+	value = f.Normalize(value)
+	if value == "" {
+		if f.Required {
+			return fmt.Errorf("%s is required", f.Label)
+		}
+		return nil
+	}
+	if f.Type != Multiline && strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%s must be a single line", f.Label)
+	}
+
+	switch f.Type {
+	case Number:
+		if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+			return fmt.Errorf("%s must be a whole number", f.Label)
+		}
+	case URL:
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s must be an absolute URL such as https://example.com", f.Label)
+		}
+	case Email:
+		if _, err := mail.ParseAddress(value); err != nil {
+			return fmt.Errorf("%s must be an email address", f.Label)
+		}
+	case CardNumber:
+		if !luhn(value) {
+			return fmt.Errorf("%s is not a valid card number", f.Label)
+		}
+	case Expiry:
+		if !validExpiry(value) {
+			return fmt.Errorf("%s must be a month and year as MM/YY", f.Label)
+		}
+	case Base32:
+		if _, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(value); err != nil {
+			return fmt.Errorf("%s must be base32 encoded", f.Label)
+		}
+	case Choice:
+		if !slices.Contains(f.Choices, value) {
+			return fmt.Errorf("%s must be one of %s", f.Label, strings.Join(f.Choices, ", "))
+		}
+	}
+
+	if f.Pattern != "" {
+		pattern, err := regexp.Compile(`^(?:` + f.Pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("%s has an invalid pattern: %v", f.Label, err)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("%s does not match the pattern %s", f.Label, f.Pattern)
+		}
+	}
+	return nil
+}
+
+// check reports problems with the field definition itself
+func (f Field) check() error {
+	if !slices.Contains(FieldTypes, f.Type) {
+		return fmt.Errorf("field %q has an unknown type %q", f.Name, f.Type)
+	}
+	if f.Type == Choice && len(f.Choices) == 0 {
+		return fmt.Errorf("field %q must list its choices", f.Name)
+	}
+	if f.Pattern != "" {
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return fmt.Errorf("field %q has an invalid pattern: %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// luhn reports whether number is 12 to 19 digits with a valid Luhn check digit
+func luhn(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+		if (len(number)-i)%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// validExpiry reports whether value is a month and year written as MM/YY or MM/YYYY
+func validExpiry(value string) bool {
+	month, year, ok := strings.Cut(value, "/")
+	if !ok || len(month) != 2 || (len(year) != 2 && len(year) != 4) {
+		return false
+	}
+	m, err := strconv.Atoi(month)
+	if err != nil || m < 1 || m > 12 {
+		return false
+	}
+	_, err = strconv.Atoi(year)
+	return err == nil
+}
+
+// ParseFields parses the field list of a custom kind. Fields are separated by commas and
+// written as name[:type][!], where a trailing ! marks the field as required and choices
+// are listed as choice(a|b|c). The type defaults to text. For example:
+//
+//	security:choice(WPA2|WPA3)!, router:url, admin_pin:secret
+func ParseFields(spec string) ([]Field, error) {
+	var fields []Field
+	for _, part := range strings.Split(spec, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		field, err := ParseField(part)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(fields, func(f Field) bool { return f.Name == field.Name }) {
+			return nil, fmt.Errorf("field %q is listed more than once", field.Name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// ParseField parses a single field written as name[:type][!]
+func ParseField(spec string) (Field, error) {
+	spec = strings.TrimSpace(spec)
+	field := Field{Type: Text}
+	if rest, ok := strings.CutSuffix(spec, "!"); ok {
+		field.Required = true
+		spec = strings.TrimSpace(rest)
+	}
+
+	name, fieldType, _ := strings.Cut(spec, ":")
+	field.Name = strings.ToLower(strings.TrimSpace(name))
+	if !validName(field.Name) {
+		return Field{}, fmt.Errorf("invalid field name %q: use letters, digits, '_' and '-'", name)
+	}
+	field.Label = labelFor(field.Name)
+
+	fieldType = strings.TrimSpace(fieldType)
+	if choices, ok := strings.CutPrefix(fieldType, "choice("); ok {
+		choices, ok = strings.CutSuffix(choices, ")")
+		if !ok {
+			return Field{}, fmt.Errorf("field %q: choices must be written as choice(a|b)", field.Name)
+		}
+		field.Type = Choice
+		for _, choice := range strings.Split(choices, "|") {
+			if choice = strings.TrimSpace(choice); choice != "" {
+				field.Choices = append(field.Choices, choice)
+			}
+		}
+	} else if fieldType != "" {
+		field.Type = FieldType(strings.ToLower(fieldType))
+	}
+	if err := field.check(); err != nil {
+		return Field{}, err
+	}
+	return field, nil
+}
+
+// labelFor turns a field name such as admin_pin into a label such as "Admin pin"
+func labelFor(name string) string {
+	label := []rune(strings.NewReplacer("_", " ", "-", " ").Replace(name))
+	label[0] = unicode.ToUpper(label[0])
+	return string(label)
+}
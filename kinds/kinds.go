@@ -0,0 +1,192 @@
+// Package kinds describes the kinds of entries a vault can hold, such as logins, SSH keys
+// or payment cards. Each kind has a schema of named fields used to prompt for and validate
+// entries. Built-in kinds can be extended with custom kinds defined in the config file.
+package kinds
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownKind is returned by Registry.Lookup for names that are not registered
+var ErrUnknownKind = errors.New("unknown entry kind")
+
+// ErrInvalidEntry is returned by Kind.Validate for entries that do not match the schema
+var ErrInvalidEntry = errors.New("invalid entry")
+
+// reservedNames cannot be used for extra fields since they name the parts every entry has
+var reservedNames = []string{"service", "identifier", "value", "kind", "notes", "urls", "tags"}
+
+// Kind is a type of entry with a schema describing its identifier, value and extra fields
+type Kind struct {
+	Name        string
+	Description string
+	Identifier  Field   // Names the entry within its service, such as the username
+	Value       Field   // The main secret, such as the password
+	Fields      []Field // Extra fields stored with the entry's custom fields
+	Generate    bool    // Empty values are generated from the service's password policy
+	Custom      bool    // Defined in the config file rather than built in
+}
+
+// Field returns the schema field called name, ignoring case
+func (k Kind) Field(name string) (Field, bool) {
+	for _, field := range k.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// Validate checks an entry's identifier, value and fields against the schema. Fields are keyed
+// by name; fields that are not in the schema are not checked. An empty value is accepted
+// when the kind generates values.
+func (k Kind) Validate(identifier, value string, fields map[string]string) error {
+	var problems []string
+	if strings.TrimSpace(identifier) == "" {
+		problems = append(problems, k.Identifier.Label+" is required")
+	} else if err := k.Identifier.Validate(identifier); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if value != "" || !k.Generate {
+		if err := k.Value.Validate(value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	for _, field := range k.Fields {
+		var fieldValue string
+		for name, v := range fields {
+			if strings.EqualFold(name, field.Name) {
+				fieldValue = v
+			}
+		}
+		if err := field.Validate(fieldValue); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w for kind %s: %s", ErrInvalidEntry, k.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Generic returns the schema used for entries whose kind is not registered, such as a custom
+// kind that has been removed from the config file. It accepts any identifier and value.
+func Generic(name string) Kind {
+	return Kind{
+		Name:       name,
+		Identifier: Field{Name: "identifier", Label: "Identifier", Type: Text},
+		Value:      Field{Name: "value", Label: "Value", Type: Secret},
+	}
+}
+
+// Registry holds the kinds that entries can have
+type Registry struct {
+	kinds   map[string]Kind
+	aliases map[string]string
+}
+
+// NewRegistry returns a registry holding the built-in kinds. The identifier types used
+// before entry kinds existed are accepted as aliases.
+func NewRegistry() *Registry {
+	r := &Registry{
+		kinds: make(map[string]Kind),
+		aliases: map[string]string{
+			"username":   Login,
+			"email":      Login,
+			"secret_key": APIKey,
+		},
+	}
+	for _, kind := range builtins() {
+		r.kinds[kind.Name] = kind
+	}
+	return r
+}
+
+// Register adds a custom kind. Its name must not be taken by another kind or an alias.
+func (r *Registry) Register(kind Kind) error {
+	name := strings.ToLower(kind.Name)
+	if !validName(name) {
+		return fmt.Errorf("invalid kind name %q: use letters, digits, '_' and '-'", kind.Name)
+	}
+	if _, exists := r.kinds[name]; exists {
+		return fmt.Errorf("kind %q is already defined", name)
+	}
+	if _, exists := r.aliases[name]; exists {
+		return fmt.Errorf("kind %q is already defined", name)
+	}
+	for _, field := range kind.Fields {
+		if slices.Contains(reservedNames, field.Name) {
+			return fmt.Errorf("kind %q: field name %q is reserved", name, field.Name)
+		}
+	}
+	for _, field := range append([]Field{kind.Identifier, kind.Value}, kind.Fields...) {
+		if err := field.check(); err != nil {
+			return fmt.Errorf("kind %q: %w", name, err)
+		}
+	}
+	kind.Name = name
+	r.kinds[name] = kind
+	return nil
+}
+
+// Lookup returns the kind called name, resolving aliases and ignoring case
+func (r *Registry) Lookup(name string) (Kind, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := r.aliases[name]; ok {
+		name = alias
+	}
+	kind, ok := r.kinds[name]
+	if !ok {
+		return Kind{}, fmt.Errorf("%w %q: expected one of %s", ErrUnknownKind, name, strings.Join(r.Names(), ", "))
+	}
+	return kind, nil
+}
+
+// Resolve returns the kind called name, or a generic schema if it is not registered
+func (r *Registry) Resolve(name string) Kind {
+	if kind, err := r.Lookup(name); err == nil {
+		return kind
+	}
+	return Generic(name)
+}
+
+// Kinds returns every registered kind, built-in kinds first, each group sorted by name
+func (r *Registry) Kinds() []Kind {
+	all := make([]Kind, 0, len(r.kinds))
+	for _, kind := range r.kinds {
+		all = append(all, kind)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Custom != all[j].Custom {
+			return !all[i].Custom
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// Names returns the names of every registered kind in the order of Kinds
+func (r *Registry) Names() []string {
+	var names []string
+	for _, kind := range r.Kinds() {
+		names = append(names, kind.Name)
+	}
+	return names
+}
+
+// validName reports whether name can be used as a kind or field name
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
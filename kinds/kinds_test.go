@@ -0,0 +1,133 @@
+package kinds
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLookupResolvesAliases(t *testing.T) {
+	registry := NewRegistry()
+	for name, want := range map[string]string{"username": Login, "Email": Login, "secret_key": APIKey, "api_key": APIKey, "ssh_key": SSHKey} {
+		kind, err := registry.Lookup(name)
+		if err != nil || kind.Name != want {
+			t.Errorf("Lookup(%q) = %q, %v, want %q", name, kind.Name, err, want)
+		}
+	}
+	if _, err := registry.Lookup("nope"); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("expected ErrUnknownKind, got %v", err)
+	}
+	if kind := registry.Resolve("removed"); kind.Name != "removed" || kind.Generate || len(kind.Fields) != 0 {
+		t.Errorf("expected a generic kind for an unknown name, got %+v", kind)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	registry := NewRegistry()
+	wifi := Kind{Name: "WiFi", Identifier: Field{Label: "Network", Type: Text}, Value: Field{Label: "Password", Type: Secret}, Custom: true}
+	if err := registry.Register(wifi); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if kind, err := registry.Lookup("wifi"); err != nil || !kind.Custom {
+		t.Errorf("expected the custom kind to be registered, got %+v, %v", kind, err)
+	}
+	if names := registry.Names(); names[len(names)-1] != "wifi" {
+		t.Errorf("expected custom kinds to be listed last, got %v", names)
+	}
+
+	for _, invalid := range []Kind{
+		wifi,
+		{Name: "login", Identifier: wifi.Identifier, Value: wifi.Value},
+		{Name: "email", Identifier: wifi.Identifier, Value: wifi.Value},
+		{Name: "bad name", Identifier: wifi.Identifier, Value: wifi.Value},
+		{Name: "router", Identifier: wifi.Identifier, Value: Field{Label: "Password", Type: "blob"}},
+		{Name: "router", Identifier: wifi.Identifier, Value: wifi.Value, Fields: []Field{{Name: "tags", Type: Text}}},
+	} {
+		if err := registry.Register(invalid); err == nil {
+			t.Errorf("expected Register(%q) to fail", invalid.Name)
+		}
+	}
+}
+
+func TestFieldValidate(t *testing.T) {
+	tests := []struct {
+		field Field
+		value string
+		valid bool
+	}{
+		{Field{Type: Text}, "", true},
+		{Field{Type: Text, Required: true}, " ", false},
+		{Field{Type: Text}, "two\nlines", false},
+		{Field{Type: Multiline}, "two\nlines", true},
+		{Field{Type: Number}, "5432", true},
+		{Field{Type: Number}, "-1", false},
+		{Field{Type: URL}, "https://example.com/login", true},
+		{Field{Type: URL}, "example.com", false},
+		{Field{Type: Email}, "me@example.com", true},
+		{Field{Type: Email}, "me", false},
+		{Field{Type: CardNumber}, "4111 1111 1111 1111", true},
+		{Field{Type: CardNumber}, "4111 1111 1111 1112", false},
+		{Field{Type: Expiry}, "09/27", true},
+		{Field{Type: Expiry}, "13/27", false},
+		{Field{Type: Expiry}, "9/2027", false},
+		{Field{Type: Base32}, "jbsw y3dp ehpk 3pxp", true},
+		{Field{Type: Base32}, "not base32!", false},
+		{Field{Type: Choice, Choices: []string{"SHA1", "SHA256"}}, "sha256", true},
+		{Field{Type: Choice, Choices: []string{"SHA1", "SHA256"}}, "MD5", false},
+		{Field{Type: Secret, Pattern: `[0-9]{3,4}`}, "123", true},
+		{Field{Type: Secret, Pattern: `[0-9]{3,4}`}, "12345", false},
+	}
+	for _, test := range tests {
+		test.field.Label = "Field"
+		if err := test.field.Validate(test.value); (err == nil) != test.valid {
+			t.Errorf("%s field: Validate(%q) = %v, want valid %v", test.field.Type, test.value, err, test.valid)
+		}
+	}
+}
+
+func TestKindValidate(t *testing.T) {
+	registry := NewRegistry()
+	card, _ := registry.Lookup(CreditCard)
+	if err := card.Validate("Jane Doe", "4111111111111111", map[string]string{"Expiry": "09/27", "cvv": "123"}); err != nil {
+		t.Errorf("expected a valid card, got %v", err)
+	}
+	err := card.Validate("", "4111111111111112", map[string]string{"cvv": "12"})
+	if !errors.Is(err, ErrInvalidEntry) {
+		t.Fatalf("expected ErrInvalidEntry, got %v", err)
+	}
+
+	// Logins generate a password when none is given, secure notes need their text
+	login, _ := registry.Lookup(Login)
+	if err := login.Validate("me", "", nil); err != nil {
+		t.Errorf("expected an empty login password to be accepted, got %v", err)
+	}
+	note, _ := registry.Lookup(SecureNote)
+	if err := note.Validate("wifi", "", nil); err == nil {
+		t.Error("expected an empty note to be rejected")
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("security:choice(WPA2 | WPA3)!, router_url:url, admin_pin:secret,notes")
+	if err != nil {
+		t.Fatalf("ParseFields failed: %v", err)
+	}
+	if len(fields) != 4 {
+		t.Fatalf("expected 4 fields, got %+v", fields)
+	}
+	security := fields[0]
+	if security.Name != "security" || security.Type != Choice || !security.Required || len(security.Choices) != 2 || security.Choices[1] != "WPA3" {
+		t.Errorf("unexpected choice field %+v", security)
+	}
+	if fields[1].Type != URL || fields[1].Label != "Router url" || fields[1].Required {
+		t.Errorf("unexpected url field %+v", fields[1])
+	}
+	if !fields[2].IsSecret() || fields[3].Type != Text {
+		t.Errorf("unexpected fields %+v", fields[2:])
+	}
+
+	for _, invalid := range []string{"pin:blob", "a b", "x:choice()", "x:choice(a|b", "x, x"} {
+		if _, err := ParseFields(invalid); err == nil {
+			t.Errorf("expected error parsing %q", invalid)
+		}
+	}
+}
@@ -2,25 +2,9 @@ package main
 
 import (
 	"vault-cli/cmd"
-	"vault-cli/database"
-	"log"
-	"os"
-	"path/filepath"
 )
 
 func main() {
-	// Determine the database path (defaulting to user's home directory)
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Fatalf("Could not get user home directory: %v", err)
-	}
-	dbPath := filepath.Join(homeDir, "vault.db") // Place it in the home directory
-
-	// Initialize the database
-	if err := database.InitDB(dbPath); err != nil {
-		log.Fatalf("Could not initialize the database: %v", err)
-	}
-
-	// Execute the commands
+	// Execute the commands. The vault database is located and opened by the root command.
 	cmd.Execute()
 }
@@ -0,0 +1,141 @@
+package otp
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseMigrationURI reads the keys from an otpauth-migration://offline?data=... URI, as exported
+// by Google Authenticator. The data is a base64 protocol buffer listing the accounts.
+func ParseMigrationURI(uri string) ([]Key, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || u.Scheme != "otpauth-migration" {
+		return nil, fmt.Errorf("%w: expected an otpauth-migration:// URI", ErrInvalidKey)
+	}
+	// A '+' that was not percent-encoded is decoded as a space by the query parser
+	encoded := strings.ReplaceAll(u.Query().Get("data"), " ", "+")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "=")); err != nil {
+			return nil, fmt.Errorf("%w: the migration data is not base64 encoded", ErrInvalidKey)
+		}
+	}
+
+	// MigrationPayload: repeated OtpParameters otp_parameters = 1; the batch fields are ignored
+	var keys []Key
+	err = readMessage(data, func(field int, value uint64, bytes []byte) error {
+		if field != 1 || bytes == nil {
+			return nil
+		}
+		key, err := parseMigrationKey(bytes)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: the migration URI holds no accounts", ErrInvalidKey)
+	}
+	return keys, nil
+}
+
+// parseMigrationKey decodes an OtpParameters message
+func parseMigrationKey(data []byte) (Key, error) {
+	key := Key{Type: TOTP, Algorithm: DefaultAlgorithm, Digits: DefaultDigits, Period: DefaultPeriod}
+	var secret []byte
+	err := readMessage(data, func(field int, value uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			secret = bytes
+		case 2:
+			key.Account = string(bytes)
+		case 3:
+			key.Issuer = string(bytes)
+		case 4:
+			algorithms := map[uint64]string{0: "SHA1", 1: "SHA1", 2: "SHA256", 3: "SHA512", 4: "MD5"}
+			key.Algorithm = algorithms[value]
+		case 5:
+			if value == 2 {
+				key.Digits = 8
+			}
+		case 6:
+			if value == 1 {
+				key.Type = HOTP
+			}
+		case 7:
+			key.Counter = value
+		}
+		return nil
+	})
+	if err != nil {
+		return Key{}, err
+	}
+
+	// Names are often "issuer:account"; keep only the account when the issuer is known
+	if issuer, account, ok := strings.Cut(key.Account, ":"); ok && (key.Issuer == "" || strings.EqualFold(issuer, key.Issuer)) {
+		if key.Issuer == "" {
+			key.Issuer = strings.TrimSpace(issuer)
+		}
+		key.Account = strings.TrimSpace(account)
+	}
+	key.Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+	if err := key.Validate(); err != nil {
+		return Key{}, fmt.Errorf("account %q: %w", key.Account, err)
+	}
+	return key, nil
+}
+
+// readMessage calls fn for each field of a protocol buffer message with the field number and either
+// its varint value or, for length-delimited fields, its bytes. Fixed-width fields are skipped.
+func readMessage(data []byte, fn func(field int, value uint64, bytes []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("%w: malformed migration data", ErrInvalidKey)
+		}
+		data = data[n:]
+		field, wireType := int(tag>>3), tag&7
+
+		switch wireType {
+		case 0:
+			value, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("%w: malformed migration data", ErrInvalidKey)
+			}
+			data = data[n:]
+			if err := fn(field, value, nil); err != nil {
+				return err
+			}
+		case 2:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("%w: malformed migration data", ErrInvalidKey)
+			}
+			bytes := data[n : n+int(length)]
+			data = data[n+int(length):]
+			if err := fn(field, 0, bytes); err != nil {
+				return err
+			}
+		case 1, 5:
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+			if len(data) < size {
+				return fmt.Errorf("%w: malformed migration data", ErrInvalidKey)
+			}
+			data = data[size:]
+		default:
+			return fmt.Errorf("%w: malformed migration data", ErrInvalidKey)
+		}
+	}
+	return nil
+}
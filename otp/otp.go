@@ -0,0 +1,214 @@
+// Package otp generates one-time passwords: HOTP codes from a counter (RFC 4226) and TOTP
+// codes from the current time (RFC 6238). Keys are read from base32 seeds, otpauth:// URIs
+// and the otpauth-migration:// URIs exported by authenticator apps.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidKey is returned for seeds and URIs that cannot be used to generate codes
+var ErrInvalidKey = errors.New("invalid one-time password key")
+
+// Type is the way codes are derived from the seed
+type Type string
+
+const (
+	TOTP Type = "totp" // Codes change every Period seconds
+	HOTP Type = "hotp" // Codes change every time the counter is incremented
+)
+
+// Defaults used by authenticator apps when a URI does not say otherwise
+const (
+	DefaultAlgorithm = "SHA1"
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
+)
+
+// Key holds everything needed to generate codes for an account
+type Key struct {
+	Type      Type
+	Secret    string // Base32 seed in upper case without padding
+	Issuer    string
+	Account   string
+	Algorithm string // SHA1, SHA256 or SHA512
+	Digits    int
+	Period    int    // Seconds each TOTP code is valid for
+	Counter   uint64 // Counter of the next HOTP code
+}
+
+// NewKey returns a TOTP key for a base32 seed with the default algorithm, digits and period
+func NewKey(secret string) (Key, error) {
+	key := Key{Type: TOTP, Secret: NormalizeSecret(secret), Algorithm: DefaultAlgorithm, Digits: DefaultDigits, Period: DefaultPeriod}
+	return key, key.Validate()
+}
+
+// NormalizeSecret upper-cases a base32 seed and strips spaces, dashes and padding
+func NormalizeSecret(secret string) string {
+	return strings.TrimRight(strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(secret))), "=")
+}
+
+// Validate checks that codes can be generated with the key
+func (k Key) Validate() error {
+	if k.Type != TOTP && k.Type != HOTP {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidKey, k.Type)
+	}
+	if k.Secret == "" {
+		return fmt.Errorf("%w: the secret is empty", ErrInvalidKey)
+	}
+	if _, err := k.secret(); err != nil {
+		return fmt.Errorf("%w: the secret is not base32 encoded", ErrInvalidKey)
+	}
+	if newHash(k.Algorithm) == nil {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidKey, k.Algorithm)
+	}
+	if k.Digits < 6 || k.Digits > 10 {
+		return fmt.Errorf("%w: codes must have 6 to 10 digits, not %d", ErrInvalidKey, k.Digits)
+	}
+	if k.Type == TOTP && k.Period <= 0 {
+		return fmt.Errorf("%w: the period must be positive", ErrInvalidKey)
+	}
+	return nil
+}
+
+// secret decodes the base32 seed
+func (k Key) secret() ([]byte, error) {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(k.Secret)
+}
+
+// Code returns the TOTP code for time t, or the HOTP code for the key's counter
+func (k Key) Code(t time.Time) (string, error) {
+	if err := k.Validate(); err != nil {
+		return "", err
+	}
+	secret, _ := k.secret()
+	counter := k.Counter
+	if k.Type == TOTP {
+		counter = uint64(t.Unix()) / uint64(k.Period)
+	}
+	return HOTPCode(secret, counter, k.Digits, k.Algorithm)
+}
+
+// Remaining returns how long the TOTP code for time t stays valid
+func (k Key) Remaining(t time.Time) time.Duration {
+	if k.Type != TOTP || k.Period <= 0 {
+		return 0
+	}
+	period := int64(k.Period)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+// HOTPCode computes the code for counter as described in RFC 4226, using the given hash algorithm
+func HOTPCode(secret []byte, counter uint64, digits int, algorithm string) (string, error) {
+	hashFunc := newHash(algorithm)
+	if hashFunc == nil {
+		return "", fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidKey, algorithm)
+	}
+	mac := hmac.New(hashFunc, secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation: the low nibble of the last byte picks four bytes of the MAC
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+
+	modulus := uint64(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulus), nil
+}
+
+// newHash returns the hash constructor for an algorithm name, or nil if it is not supported
+func newHash(algorithm string) func() hash.Hash {
+	switch strings.ToUpper(algorithm) {
+	case "SHA1":
+		return sha1.New
+	case "SHA256":
+		return sha256.New
+	case "SHA512":
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// ParseURI reads a key from an otpauth://totp/... or otpauth://hotp/... URI
+func ParseURI(uri string) (Key, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || u.Scheme != "otpauth" {
+		return Key{}, fmt.Errorf("%w: expected an otpauth:// URI", ErrInvalidKey)
+	}
+
+	key := Key{Type: Type(strings.ToLower(u.Host)), Algorithm: DefaultAlgorithm, Digits: DefaultDigits, Period: DefaultPeriod}
+	query := u.Query()
+	key.Secret = NormalizeSecret(query.Get("secret"))
+
+	// The label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		key.Issuer, key.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		key.Account = strings.TrimSpace(label)
+	}
+	if issuer := query.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		key.Algorithm = strings.ToUpper(algorithm)
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if key.Digits, err = strconv.Atoi(digits); err != nil {
+			return Key{}, fmt.Errorf("%w: invalid digits %q", ErrInvalidKey, digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		if key.Period, err = strconv.Atoi(period); err != nil {
+			return Key{}, fmt.Errorf("%w: invalid period %q", ErrInvalidKey, period)
+		}
+	}
+	if counter := query.Get("counter"); counter != "" {
+		if key.Counter, err = strconv.ParseUint(counter, 10, 64); err != nil {
+			return Key{}, fmt.Errorf("%w: invalid counter %q", ErrInvalidKey, counter)
+		}
+	} else if key.Type == HOTP {
+		return Key{}, fmt.Errorf("%w: HOTP URIs must include a counter", ErrInvalidKey)
+	}
+
+	return key, key.Validate()
+}
+
+// URI returns the key as an otpauth:// URI
+func (k Key) URI() string {
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+	query := url.Values{}
+	query.Set("secret", k.Secret)
+	if k.Issuer != "" {
+		query.Set("issuer", k.Issuer)
+	}
+	query.Set("algorithm", k.Algorithm)
+	query.Set("digits", strconv.Itoa(k.Digits))
+	if k.Type == HOTP {
+		query.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else {
+		query.Set("period", strconv.Itoa(k.Period))
+	}
+	u := url.URL{Scheme: "otpauth", Host: string(k.Type), Path: "/" + label, RawQuery: query.Encode()}
+	return u.String()
+}
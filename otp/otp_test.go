@@ -0,0 +1,163 @@
+package otp
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHOTPCode(t *testing.T) {
+	// RFC 4226, appendix D
+	secret := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, want := range expected {
+		code, err := HOTPCode(secret, uint64(counter), 6, "SHA1")
+		if err != nil || code != want {
+			t.Errorf("HOTPCode(counter %d) = %q, %v, want %q", counter, code, err, want)
+		}
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238, appendix B
+	seeds := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	tests := []struct {
+		algorithm string
+		unix      int64
+		want      string
+	}{
+		{"SHA1", 59, "94287082"},
+		{"SHA256", 59, "46119246"},
+		{"SHA512", 59, "90693936"},
+		{"SHA1", 1111111109, "07081804"},
+		{"SHA256", 1111111109, "68084774"},
+		{"SHA512", 1111111109, "25091201"},
+		{"SHA1", 20000000000, "65353130"},
+	}
+	for _, test := range tests {
+		key := Key{
+			Type:      TOTP,
+			Secret:    base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(seeds[test.algorithm])),
+			Algorithm: test.algorithm,
+			Digits:    8,
+			Period:    30,
+		}
+		code, err := key.Code(time.Unix(test.unix, 0))
+		if err != nil || code != test.want {
+			t.Errorf("%s code at %d = %q, %v, want %q", test.algorithm, test.unix, code, err, test.want)
+		}
+	}
+
+	key, _ := NewKey("JBSWY3DPEHPK3PXP")
+	if remaining := key.Remaining(time.Unix(59, 0)); remaining != time.Second {
+		t.Errorf("expected 1s remaining, got %v", remaining)
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	key, err := ParseURI("otpauth://totp/Example:alice@example.com?secret=jbswy3dpehpk3pxp&issuer=Example&algorithm=sha256&digits=8&period=60")
+	if err != nil {
+		t.Fatalf("ParseURI failed: %v", err)
+	}
+	want := Key{Type: TOTP, Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example", Account: "alice@example.com", Algorithm: "SHA256", Digits: 8, Period: 60}
+	if key != want {
+		t.Errorf("ParseURI = %+v, want %+v", key, want)
+	}
+
+	// URI round-trips the key
+	if again, err := ParseURI(key.URI()); err != nil || again != key {
+		t.Errorf("ParseURI(URI()) = %+v, %v, want %+v", again, err, key)
+	}
+
+	hotp, err := ParseURI("otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=7")
+	if err != nil || hotp.Type != HOTP || hotp.Counter != 7 || hotp.Account != "alice" || hotp.Digits != 6 {
+		t.Errorf("unexpected HOTP key %+v, %v", hotp, err)
+	}
+	if again, err := ParseURI(hotp.URI()); err != nil || again != hotp {
+		t.Errorf("ParseURI(URI()) = %+v, %v, want %+v", again, err, hotp)
+	}
+
+	for _, invalid := range []string{
+		"https://example.com",
+		"otpauth://totp/alice",
+		"otpauth://totp/alice?secret=not-base32!",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://push/alice?secret=JBSWY3DPEHPK3PXP",
+	} {
+		if _, err := ParseURI(invalid); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ParseURI(%q) = %v, want ErrInvalidKey", invalid, err)
+		}
+	}
+}
+
+// field encodes a protocol buffer field holding a varint or, if bytes is not nil, a length-delimited value
+func field(number int, value uint64, bytes []byte) []byte {
+	var out []byte
+	if bytes == nil {
+		out = binary.AppendUvarint(out, uint64(number)<<3)
+		return binary.AppendUvarint(out, value)
+	}
+	out = binary.AppendUvarint(out, uint64(number)<<3|2)
+	out = binary.AppendUvarint(out, uint64(len(bytes)))
+	return append(out, bytes...)
+}
+
+func TestParseMigrationURI(t *testing.T) {
+	var totp, hotp, payload []byte
+	totp = append(totp, field(1, 0, []byte("12345678901234567890"))...)
+	totp = append(totp, field(2, 0, []byte("GitHub:octocat"))...)
+	totp = append(totp, field(4, 2, nil)...) // SHA256
+	totp = append(totp, field(5, 2, nil)...) // eight digits
+	totp = append(totp, field(6, 2, nil)...) // TOTP
+	hotp = append(hotp, field(1, 0, []byte("secret"))...)
+	hotp = append(hotp, field(2, 0, []byte("bob"))...)
+	hotp = append(hotp, field(3, 0, []byte("Bank"))...)
+	hotp = append(hotp, field(6, 1, nil)...) // HOTP
+	hotp = append(hotp, field(7, 42, nil)...)
+	payload = append(payload, field(1, 0, totp)...)
+	payload = append(payload, field(1, 0, hotp)...)
+	payload = append(payload, field(2, 1, nil)...) // version
+
+	uri := "otpauth-migration://offline?data=" + url.QueryEscape(base64.StdEncoding.EncodeToString(payload))
+	keys, err := ParseMigrationURI(uri)
+	if err != nil {
+		t.Fatalf("ParseMigrationURI failed: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %+v", keys)
+	}
+	github := Key{Type: TOTP, Secret: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890")), Issuer: "GitHub", Account: "octocat", Algorithm: "SHA256", Digits: 8, Period: 30}
+	if keys[0] != github {
+		t.Errorf("first key = %+v, want %+v", keys[0], github)
+	}
+	if keys[1].Type != HOTP || keys[1].Counter != 42 || keys[1].Issuer != "Bank" || keys[1].Account != "bob" || keys[1].Digits != 6 {
+		t.Errorf("unexpected second key %+v", keys[1])
+	}
+
+	// Unescaped '+' characters in the data are decoded as spaces by URL parsers
+	if _, err := ParseMigrationURI(strings.ReplaceAll(uri, "%2B", "+")); err != nil {
+		t.Errorf("expected unescaped data to parse, got %v", err)
+	}
+
+	for _, invalid := range []string{
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth-migration://offline?data=%%%",
+		"otpauth-migration://offline?data=" + base64.StdEncoding.EncodeToString([]byte{0x0a, 0x05, 0x01}),
+		"otpauth-migration://offline?data=",
+	} {
+		if _, err := ParseMigrationURI(invalid); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ParseMigrationURI(%q) = %v, want ErrInvalidKey", invalid, err)
+		}
+	}
+}
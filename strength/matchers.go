@@ -0,0 +1,334 @@
+package strength
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"vault-cli/generator"
+)
+
+// match is a part of a password recognized as a guessable pattern
+type match struct {
+	start, end int     // Rune offsets, end exclusive
+	guesses    float64 // Base-2 logarithm of the guesses needed for this part
+	warning    string
+}
+
+// minSubmatchGuesses is the fewest guesses counted for a pattern that is only part of the password,
+// so that a short pattern is not assumed to be the first thing an attacker tries
+var minSubmatchGuesses = math.Log2(50)
+
+// dictionaryWords is the set of dictionary words recognized in passwords
+var dictionaryWords = sync.OnceValue(func() map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range generator.Wordlist() {
+		words[word] = true
+	}
+	return words
+})
+
+// maxWordLength is the length of the longest word looked up in the dictionaries
+const maxWordLength = 32
+
+// leet maps common character substitutions back to the letters they replace
+var leet = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '3': {'e'}, '9': {'g'}, '6': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '$': {'s'}, '5': {'s'},
+	'7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+// keyboardRows are the rows of a QWERTY keyboard, unshifted and shifted
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./", "~!@#$%^&*()_+"}
+
+// findMatches returns every pattern found in password
+func findMatches(password []rune) []match {
+	lower := make([]rune, len(password))
+	for i, r := range password {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(password, lower)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, keyboardMatches(lower)...)
+	matches = append(matches, repeatMatches(password)...)
+	matches = append(matches, yearMatches(password)...)
+
+	for i := range matches {
+		whole := matches[i].start == 0 && matches[i].end == len(password)
+		if !whole && matches[i].guesses < minSubmatchGuesses {
+			matches[i].guesses = minSubmatchGuesses
+		}
+	}
+	return matches
+}
+
+// dictionaryMatches finds common passwords and dictionary words, also when
+// capitalized, spelled with substitutions such as "p@ssw0rd", or reversed
+func dictionaryMatches(password, lower []rune) []match {
+	var matches []match
+	for i := 0; i < len(lower); i++ {
+		for j := i + 3; j <= len(lower) && j-i <= maxWordLength; j++ {
+			whole := i == 0 && j == len(lower)
+			caseBits := uppercaseBits(password[i:j])
+
+			candidates := []spelling{{string(lower[i:j]), 0}, {reverse(lower[i:j]), 1}}
+			candidates = append(candidates, unleet(lower[i:j])...)
+
+			found := false
+			var best match
+			for _, candidate := range candidates {
+				m, ok := lookupWord(candidate.word, whole)
+				if !ok {
+					continue
+				}
+				m.start, m.end = i, j
+				m.guesses += caseBits + candidate.extra
+				if !found || m.guesses < best.guesses {
+					best, found = m, true
+				}
+			}
+			if found {
+				matches = append(matches, best)
+			}
+		}
+	}
+	return matches
+}
+
+// lookupWord returns a match for word if it is a common password or dictionary word
+func lookupWord(word string, whole bool) (match, bool) {
+	if rank, ok := commonPasswords()[word]; ok {
+		warning := "This is similar to a commonly used password"
+		if whole && rank <= 10 {
+			warning = "This is a top-10 common password"
+		} else if whole {
+			warning = "This is a very common password"
+		}
+		return match{guesses: math.Log2(float64(rank)), warning: warning}, true
+	}
+	if dictionaryWords()[word] {
+		warning := "Dictionary words are easy to guess"
+		if whole {
+			warning = "A word by itself is easy to guess"
+		}
+		return match{guesses: math.Log2(float64(len(generator.Wordlist()))), warning: warning}, true
+	}
+	return match{}, false
+}
+
+// uppercaseBits returns the extra guesses, in bits, needed for the capitalization of word
+func uppercaseBits(word []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 0
+	}
+	// Capitalized, all caps and a trailing capital are tried first
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(word[0]) || unicode.IsUpper(word[len(word)-1]))) {
+		return 1
+	}
+	variations := 0.0
+	for k := 1; k <= min(upper, lower); k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return math.Log2(variations)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// spelling is a way to read part of a password, and the extra guesses, in bits, needed to try it
+type spelling struct {
+	word  string
+	extra float64
+}
+
+// unleet returns the spellings of word with substitutions replaced by letters,
+// counting one extra bit per substitution
+func unleet(word []rune) []spelling {
+	var variants []spelling
+	// Try the first and the last meaning of every substitution
+	for _, pick := range []func([]rune) rune{
+		func(letters []rune) rune { return letters[0] },
+		func(letters []rune) rune { return letters[len(letters)-1] },
+	} {
+		var b strings.Builder
+		substitutions := 0
+		for _, r := range word {
+			if letters, ok := leet[r]; ok {
+				b.WriteRune(pick(letters))
+				substitutions++
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		if substitutions > 0 && substitutions < len(word) {
+			variants = append(variants, spelling{b.String(), float64(substitutions)})
+		}
+	}
+	return variants
+}
+
+func reverse(word []rune) string {
+	reversed := make([]rune, len(word))
+	for i, r := range word {
+		reversed[len(word)-1-i] = r
+	}
+	return string(reversed)
+}
+
+// sequenceMatches finds runs such as "abcd", "4321" or "XYZ"
+func sequenceMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i < len(password)-1; {
+		delta := password[i+1] - password[i]
+		if (delta != 1 && delta != -1) || charClass(password[i]) != charClass(password[i+1]) || charClass(password[i]) == 0 {
+			i++
+			continue
+		}
+		j := i + 1
+		for j+1 < len(password) && password[j+1]-password[j] == delta && charClass(password[j+1]) == charClass(password[i]) {
+			j++
+		}
+		if length := j - i + 1; length >= 3 {
+			base := 26.0
+			if strings.ContainsRune("aAzZ019", password[i]) {
+				base = 4
+			} else if unicode.IsDigit(password[i]) {
+				base = 10
+			}
+			guesses := math.Log2(base * float64(length))
+			if delta < 0 {
+				guesses++
+			}
+			matches = append(matches, match{start: i, end: j + 1, guesses: guesses, warning: "Sequences like abc or 6543 are easy to guess"})
+		}
+		i = j
+	}
+	return matches
+}
+
+// charClass groups the characters that can form a sequence, returning 0 for other characters
+func charClass(r rune) int {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return 1
+	case r >= 'A' && r <= 'Z':
+		return 2
+	case r >= '0' && r <= '9':
+		return 3
+	}
+	return 0
+}
+
+// keyboardMatches finds straight runs of at least four adjacent keys, such as "qwerty" or "lkjh"
+func keyboardMatches(lower []rune) []match {
+	var matches []match
+	for _, row := range keyboardRows {
+		keys := []rune(row)
+		position := make(map[rune]int, len(keys))
+		for i, key := range keys {
+			position[key] = i
+		}
+
+		for i := 0; i < len(lower)-1; {
+			p, ok1 := position[lower[i]]
+			q, ok2 := position[lower[i+1]]
+			direction := q - p
+			if !ok1 || !ok2 || (direction != 1 && direction != -1) {
+				i++
+				continue
+			}
+			j := i + 1
+			for j+1 < len(lower) {
+				next, ok := position[lower[j+1]]
+				if !ok || next-position[lower[j]] != direction {
+					break
+				}
+				j++
+			}
+			if length := j - i + 1; length >= 4 {
+				guesses := math.Log2(float64(len(keyboardRows)*len(keys)) * float64(length))
+				if direction < 0 {
+					guesses++
+				}
+				matches = append(matches, match{start: i, end: j + 1, guesses: guesses, warning: "Straight rows of keys are easy to guess"})
+			}
+			i = j
+		}
+	}
+	return matches
+}
+
+// repeatMatches finds repeated characters such as "aaa" and repeated blocks such as "abcabc"
+func repeatMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i < len(password); i++ {
+		// Only the shortest repeating block starting at i is considered
+		for period := 1; i+2*period <= len(password); period++ {
+			block := string(password[i : i+period])
+			count := 1
+			for end := i + period; end+period <= len(password) && string(password[end:end+period]) == block; end += period {
+				count++
+			}
+			if count < 2 || (period == 1 && count < 3) {
+				continue
+			}
+
+			var m match
+			if period == 1 {
+				m.guesses = math.Log2(cardinality(password[i]) * float64(count))
+				m.warning = `Repeats like "aaa" are easy to guess`
+			} else {
+				m.guesses = Estimate(block).Entropy + math.Log2(float64(count))
+				m.warning = `Repeats like "abcabc" are only slightly harder to guess than "abc"`
+			}
+			m.start, m.end = i, i+period*count
+			matches = append(matches, m)
+			break
+		}
+	}
+	return matches
+}
+
+// cardinality returns the number of characters of the kind of r
+func cardinality(r rune) float64 {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLetter(r):
+		return 26
+	}
+	return 33
+}
+
+// yearMatches finds years from 1900 to 2099
+func yearMatches(password []rune) []match {
+	var matches []match
+	now := time.Now().Year()
+	for i := 0; i+4 <= len(password); i++ {
+		year, err := strconv.Atoi(string(password[i : i+4]))
+		if err != nil || year < 1900 || year > 2099 {
+			continue
+		}
+		distance := math.Max(math.Abs(float64(year-now)), 20)
+		matches = append(matches, match{start: i, end: i + 4, guesses: math.Log2(distance), warning: "Recent years are easy to guess"})
+	}
+	return matches
+}
@@ -0,0 +1,129 @@
+// Package strength estimates how hard a password is to guess.
+//
+// The estimate follows the approach of zxcvbn: the password is split into the
+// cheapest sequence of recognizable patterns (common passwords, dictionary words,
+// sequences, keyboard rows, repeats and years), with anything left over guessed
+// by brute force. The number of guesses needed is the product of the guesses for
+// each part.
+package strength
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Scores, from easiest to hardest to guess
+const (
+	VeryWeak   = 0 // Fewer than 10^3 guesses
+	Weak       = 1 // Fewer than 10^6 guesses
+	Fair       = 2 // Fewer than 10^8 guesses
+	Strong     = 3 // Fewer than 10^10 guesses
+	VeryStrong = 4
+)
+
+// bruteforceCardinality is the number of guesses per character not covered by a pattern
+const bruteforceCardinality = 10
+
+// Result is the estimated strength of a password
+type Result struct {
+	Score   int     // VeryWeak to VeryStrong
+	Entropy float64 // Base-2 logarithm of the estimated number of guesses
+	Warning string  // Explains the weakest part of the password, if any
+}
+
+// ScoreName returns a short description of score
+func ScoreName(score int) string {
+	switch score {
+	case VeryWeak:
+		return "very weak"
+	case Weak:
+		return "weak"
+	case Fair:
+		return "fair"
+	case Strong:
+		return "strong"
+	default:
+		return "very strong"
+	}
+}
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords maps common passwords to their popularity rank, starting at 1
+var commonPasswords = sync.OnceValue(func() map[string]int {
+	ranks := make(map[string]int)
+	for i, word := range strings.Fields(commonPasswordList) {
+		ranks[word] = i + 1
+	}
+	return ranks
+})
+
+// Estimate returns the estimated strength of password
+func Estimate(password string) Result {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Result{Score: VeryWeak, Warning: "The value is empty"}
+	}
+
+	matches := findMatches(runes)
+
+	// best[k] is the cheapest way to guess the first k characters, in bits, and last[k]
+	// the match ending the cheapest way, or nil if the last character is brute forced
+	best := make([]float64, len(runes)+1)
+	last := make([]*match, len(runes)+1)
+	for k := 1; k <= len(runes); k++ {
+		best[k] = best[k-1] + math.Log2(bruteforceCardinality)
+		for i := range matches {
+			m := &matches[i]
+			if m.end != k {
+				continue
+			}
+			if cost := best[m.start] + m.guesses; cost < best[k] {
+				best[k] = cost
+				last[k] = m
+			}
+		}
+	}
+
+	// Walk back through the chosen matches to find the warning for the longest one
+	var warning string
+	longest := 0
+	for k := len(runes); k > 0; {
+		m := last[k]
+		if m == nil {
+			k--
+			continue
+		}
+		if m.end-m.start > longest {
+			longest = m.end - m.start
+			warning = m.warning
+		}
+		k = m.start
+	}
+	if warning == "" && len(runes) < 8 {
+		warning = "Short passwords are easy to guess"
+	}
+
+	entropy := best[len(runes)]
+	return Result{Score: score(entropy), Entropy: entropy, Warning: warning}
+}
+
+// score maps the estimated guesses, in bits, to a score
+func score(bits float64) int {
+	guesses := bits * math.Log10(2)
+	switch {
+	case guesses < 3:
+		return VeryWeak
+	case guesses < 6:
+		return Weak
+	case guesses < 8:
+		return Fair
+	case guesses < 10:
+		return Strong
+	default:
+		return VeryStrong
+	}
+}
@@ -0,0 +1,58 @@
+package strength
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateScores(t *testing.T) {
+	tests := []struct {
+		password string
+		maxScore int
+		minScore int
+		warning  string
+	}{
+		{"password", VeryWeak, VeryWeak, "top-10 common password"},
+		{"P@ssw0rd", Weak, VeryWeak, "common"},
+		{"qwertyuiop", VeryWeak, VeryWeak, "common"},
+		{"abcdefgh", VeryWeak, VeryWeak, "Sequences"},
+		{"zzzzzzzzzz", VeryWeak, VeryWeak, "Repeats"},
+		{"asdfgh", Weak, VeryWeak, "rows of keys"},
+		{"dragon1987", Weak, VeryWeak, ""},
+		{"correct-horse-battery-staple", VeryStrong, Strong, ""},
+		{"kX9#vQ2$mL7!pR4@", VeryStrong, VeryStrong, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			result := Estimate(tt.password)
+			if result.Score > tt.maxScore || result.Score < tt.minScore {
+				t.Errorf("Expected a score between %d and %d, got %d (%.1f bits)", tt.minScore, tt.maxScore, result.Score, result.Entropy)
+			}
+			if tt.warning != "" && !strings.Contains(result.Warning, tt.warning) {
+				t.Errorf("Expected a warning mentioning %q, got %q", tt.warning, result.Warning)
+			}
+		})
+	}
+}
+
+func TestEstimateRanksPatternsBelowRandom(t *testing.T) {
+	patterned := Estimate("abcabcabcabc")
+	random := Estimate("q7Rk2mZp9xWe")
+	if patterned.Entropy >= random.Entropy {
+		t.Errorf("Expected a repeated block (%.1f bits) to be weaker than random characters (%.1f bits)", patterned.Entropy, random.Entropy)
+	}
+}
+
+func TestEstimateEmpty(t *testing.T) {
+	if result := Estimate(""); result.Score != VeryWeak || result.Entropy != 0 {
+		t.Errorf("Expected an empty value to be very weak, got %+v", result)
+	}
+}
+
+func TestEstimateLongValue(t *testing.T) {
+	// API keys and similar long values must be analyzed quickly
+	result := Estimate(strings.Repeat("ab", 200) + strings.Repeat("x7Qz", 100))
+	if result.Entropy <= 0 {
+		t.Errorf("Expected a positive estimate, got %+v", result)
+	}
+}
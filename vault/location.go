@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultVaultName is used when no vault has been selected
+	DefaultVaultName = "default"
+
+	vaultExtension = ".db"
+)
+
+var vaultNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DataDir returns the directory holding named vaults: $XDG_DATA_HOME/vault-cli,
+// or ~/.local/share/vault-cli when XDG_DATA_HOME is not set
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "vault-cli"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".local", "share", "vault-cli"), nil
+}
+
+// ValidateName checks that name can be used as a vault file name
+func ValidateName(name string) error {
+	if !vaultNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid vault name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// PathForName returns the file backing the named vault
+func PathForName(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+vaultExtension), nil
+}
+
+// Resolve determines the vault file to use from value, which is a vault name or a path
+// to a database file (as given with --vault or the "vault" setting). An empty value
+// selects the default vault.
+func Resolve(value string) (string, error) {
+	if value != "" {
+		return resolveNameOrPath(value)
+	}
+
+	path, err := PathForName(DefaultVaultName)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep using the vault created by older versions in the home directory
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if legacy, ok := legacyPath(); ok {
+			return legacy, nil
+		}
+	}
+	return path, nil
+}
+
+// resolveNameOrPath treats value as a path if it looks like one, otherwise as a vault name
+func resolveNameOrPath(value string) (string, error) {
+	if strings.ContainsRune(value, os.PathSeparator) || strings.HasSuffix(value, vaultExtension) {
+		return filepath.Abs(value)
+	}
+	return PathForName(value)
+}
+
+// legacyPath returns ~/vault.db if it exists
+func legacyPath() (string, bool) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	path := filepath.Join(homeDir, "vault.db")
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// EnsureDir creates the directory holding path with owner-only permissions
+func EnsureDir(path string) error {
+	return os.MkdirAll(filepath.Dir(path), 0700)
+}
+
+// Exists checks that the named vault has been created
+func Exists(name string) error {
+	path, err := PathForName(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("vault '%s' does not exist", name)
+	}
+	return nil
+}
+
+// Create returns the path for a new named vault, failing if it already exists.
+// The caller initializes the database at the returned path.
+func Create(name string) (string, error) {
+	path, err := PathForName(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("vault '%s' already exists", name)
+	}
+	if err := EnsureDir(path); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %v", err)
+	}
+	return path, nil
+}
+
+// Info describes a named vault
+type Info struct {
+	Name    string
+	Path    string
+	Current bool
+}
+
+// List returns the named vaults in the data directory, sorted by name.
+// The vault stored at currentPath is marked as current.
+func List(currentPath string) ([]Info, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %v", err)
+	}
+
+	var vaults []Info
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), vaultExtension)
+		if file.IsDir() || !ok || ValidateName(name) != nil {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		vaults = append(vaults, Info{Name: name, Path: path, Current: path == currentPath})
+	}
+	sort.Slice(vaults, func(i, j int) bool { return vaults[i].Name < vaults[j].Name })
+	return vaults, nil
+}
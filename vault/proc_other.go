@@ -0,0 +1,10 @@
+//go:build !unix
+
+package vault
+
+import "syscall"
+
+// detachedProcAttr returns no special attributes on platforms without sessions
+func detachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
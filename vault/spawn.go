@@ -0,0 +1,44 @@
+package vault
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"vault-cli/agent"
+)
+
+// startAgent launches `vault-cli agent` as a detached background process and
+// hands it the key over a pipe, so the key never appears in argv or the environment.
+// It is a variable so tests can run the agent in-process.
+var startAgent = func(socketPath string, key []byte, options agent.Options) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, "agent",
+		"--socket", socketPath,
+		"--idle-timeout", options.IdleTimeout.String(),
+		"--max-lifetime", options.MaxLifetime.String(),
+	)
+	cmd.SysProcAttr = detachedProcAttr()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(stdin, hex.EncodeToString(key)+"\n"); err != nil {
+		cmd.Process.Kill()
+		return fmt.Errorf("failed to pass key to agent: %v", err)
+	}
+	stdin.Close()
+
+	// The agent outlives this process
+	return cmd.Process.Release()
+}
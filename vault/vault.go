@@ -1,34 +1,62 @@
 package vault
 
 import (
-	db "vault-cli/database" // Your package for DB interaction
+	"errors"
 	"fmt"
 	"sync"
+	"time"
+
+	"vault-cli/agent"
+	db "vault-cli/database" // Your package for DB interaction
 )
 
 var (
 	lockMutex sync.Mutex
 )
 
-// UnlockVault unlocks the vault and updates its state in the database
-func UnlockVault() error {
+// agentReadyTimeout bounds how long UnlockVault waits for a new agent to accept requests
+const agentReadyTimeout = 5 * time.Second
+
+// agentSocket returns the agent socket for the vault held by store
+func agentSocket(store db.Store) (string, error) {
+	state, err := store.State()
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault state: %w", err)
+	}
+	return agent.SocketPath(state.Location), nil
+}
+
+// UnlockVault starts an agent that holds the derived key for the vault in store in memory
+// until the session is locked or expires according to options. If the vault is already
+// unlocked, its session is renewed with options instead.
+func UnlockVault(store db.Store, key []byte, options agent.Options) error {
 	lockMutex.Lock()
 	defer lockMutex.Unlock()
 
-	// Check if the vault is already unlocked
-	isLocked, err := db.GetVaultState()
+	socketPath, err := agentSocket(store)
 	if err != nil {
-		return fmt.Errorf("failed to get vault state: %v", err)
+		return err
 	}
+	client := agent.Dial(socketPath)
 
-	if !isLocked {
-		fmt.Println("Vault is already unlocked.")
+	// Check if the vault is already unlocked
+	err = client.Ping()
+	if err != nil && !errors.Is(err, agent.ErrNotRunning) {
+		return err
+	}
+	if err == nil {
+		if err := client.Renew(options); err != nil {
+			return fmt.Errorf("failed to renew the session: %v", err)
+		}
+		fmt.Println("Vault is already unlocked; its session was renewed.")
 		return nil
 	}
 
 	// Unlock the vault
-	err = db.SetVaultState(false)
-	if err != nil {
+	if err := startAgent(socketPath, key, options); err != nil {
+		return fmt.Errorf("failed to start agent: %v", err)
+	}
+	if err := waitForAgent(client); err != nil {
 		return fmt.Errorf("failed to unlock the vault: %v", err)
 	}
 
@@ -36,17 +64,90 @@ func UnlockVault() error {
 	return nil
 }
 
-// LockVault locks the vault and updates its state in the database
-func LockVault() error {
+// LockVault wipes the key held by the vault's agent and stops it
+func LockVault(store db.Store) error {
 	lockMutex.Lock()
 	defer lockMutex.Unlock()
 
-	// Lock the vault manually
-	err := db.SetVaultState(true)
+	socketPath, err := agentSocket(store)
 	if err != nil {
+		return err
+	}
+
+	// Lock the vault manually
+	err = agent.Dial(socketPath).Lock()
+	if err != nil && !errors.Is(err, agent.ErrNotRunning) {
 		return fmt.Errorf("failed to lock the vault: %v", err)
 	}
 
 	fmt.Println("Vault locked.")
 	return nil
 }
+
+// GetVaultState reports whether the vault is locked, i.e. no live agent session exists
+func GetVaultState(store db.Store) (bool, error) {
+	socketPath, err := agentSocket(store)
+	if err != nil {
+		return true, err
+	}
+	err = agent.Dial(socketPath).Ping()
+	if errors.Is(err, agent.ErrNotRunning) {
+		return true, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// IsUnlocked reports whether a live agent session exists for the vault stored at path
+func IsUnlocked(path string) bool {
+	return agent.Dial(agent.SocketPath(path)).Ping() == nil
+}
+
+// Status returns the running session's status. ok is false when the vault is locked.
+func Status(store db.Store) (status agent.Status, ok bool, err error) {
+	socketPath, err := agentSocket(store)
+	if err != nil {
+		return agent.Status{}, false, err
+	}
+	status, err = agent.Dial(socketPath).Status()
+	if errors.Is(err, agent.ErrNotRunning) {
+		return agent.Status{}, false, nil
+	}
+	if err != nil {
+		return agent.Status{}, false, err
+	}
+	return status, true, nil
+}
+
+// Keyring returns a keyring backed by the vault's running agent, or database.ErrLocked if there is none
+func Keyring(store db.Store) (db.Keyring, error) {
+	socketPath, err := agentSocket(store)
+	if err != nil {
+		return nil, err
+	}
+	client := agent.Dial(socketPath)
+	if err := client.Ping(); err != nil {
+		if errors.Is(err, agent.ErrNotRunning) {
+			return nil, db.ErrLocked
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// waitForAgent polls until the agent answers or the timeout expires
+func waitForAgent(client *agent.Client) error {
+	deadline := time.Now().Add(agentReadyTimeout)
+	for {
+		err := client.Ping()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -1,42 +1,60 @@
 package vault
 
 import (
+	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"vault-cli/agent"
 	db "vault-cli/database" // Your package for DB interaction
 )
 
-const testDBName = "test_vault.db"
+// setup points the agent socket at a temporary directory, runs agents in-process and
+// returns an empty store for the vault
+func setup(t *testing.T) db.Store {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	store := db.NewMemoryStore(filepath.Join(t.TempDir(), "vault.db"))
 
-// Setup the database for testing
-func setup() error {
-	return db.InitDB(testDBName)
+	original := startAgent
+	startAgent = func(socketPath string, key []byte, options agent.Options) error {
+		server, err := agent.Listen(socketPath, key, options)
+		if err != nil {
+			return err
+		}
+		go server.Serve()
+		t.Cleanup(server.Close)
+		return nil
+	}
+	t.Cleanup(func() { startAgent = original })
+	return store
 }
 
-// Cleanup the test database
-func cleanup() {
-	_ = os.Remove(testDBName) // Deletes the entire test database file
+// testKey returns a fixed 32-byte key
+func testKey() []byte {
+	return bytes.Repeat([]byte{0x42}, 32)
 }
 
 // TestUnlockVault tests the UnlockVault function
 func TestUnlockVault(t *testing.T) {
-	if err := setup(); err != nil {
-		t.Fatalf("failed to initialize test database: %v", err)
-	}
-	defer cleanup()
+	store := setup(t)
 
-	// Mocking the initial vault state to locked
-	if err := db.SetVaultState(true); err != nil {
-		t.Fatalf("failed to set initial vault state: %v", err)
+	isLocked, err := GetVaultState(store)
+	if err != nil {
+		t.Fatalf("failed to get vault state: %v", err)
+	}
+	if !isLocked {
+		t.Fatal("expected vault to start locked")
 	}
 
-	err := UnlockVault()
+	err = UnlockVault(store, testKey(), agent.Options{})
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
-	isLocked, err := db.GetVaultState()
+	isLocked, err = GetVaultState(store)
 	if err != nil {
 		t.Errorf("failed to get vault state: %v", err)
 	}
@@ -46,12 +64,12 @@ func TestUnlockVault(t *testing.T) {
 	}
 
 	// Test unlocking an already unlocked vault
-	err = UnlockVault()
+	err = UnlockVault(store, testKey(), agent.Options{})
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
-	isLocked, err = db.GetVaultState()
+	isLocked, err = GetVaultState(store)
 	if err != nil {
 		t.Errorf("failed to get vault state: %v", err)
 	}
@@ -59,26 +77,30 @@ func TestUnlockVault(t *testing.T) {
 	if isLocked {
 		t.Error("expected vault to remain unlocked, but it is locked")
 	}
+
+	// Unlocking again renews the session with the new limits
+	if err := UnlockVault(store, testKey(), agent.Options{IdleTimeout: time.Hour}); err != nil {
+		t.Fatalf("failed to renew the session: %v", err)
+	}
+	if status, ok, err := Status(store); err != nil || !ok || status.IdleTimeout != time.Hour {
+		t.Errorf("expected the renewed idle timeout, got %+v, %v, %v", status, ok, err)
+	}
 }
 
 // TestLockVault tests the LockVault function
 func TestLockVault(t *testing.T) {
-	if err := setup(); err != nil {
-		t.Fatalf("failed to initialize test database: %v", err)
-	}
-	defer cleanup()
+	store := setup(t)
 
-	// Mocking the initial vault state to unlocked
-	if err := db.SetVaultState(false); err != nil {
-		t.Fatalf("failed to set initial vault state: %v", err)
+	if err := UnlockVault(store, testKey(), agent.Options{}); err != nil {
+		t.Fatalf("failed to unlock vault: %v", err)
 	}
 
-	err := LockVault()
+	err := LockVault(store)
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
-	isLocked, err := db.GetVaultState()
+	isLocked, err := GetVaultState(store)
 	if err != nil {
 		t.Errorf("failed to get vault state: %v", err)
 	}
@@ -86,4 +108,151 @@ func TestLockVault(t *testing.T) {
 	if !isLocked {
 		t.Error("expected vault to be locked, but it is still unlocked")
 	}
+
+	if _, err := Keyring(store); err != db.ErrLocked {
+		t.Errorf("expected ErrLocked after locking, got %v", err)
+	}
+
+	// Locking an already locked vault is not an error
+	if err := LockVault(store); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// TestKeyring tests that values round-trip through the agent
+func TestKeyring(t *testing.T) {
+	store := setup(t)
+
+	if err := UnlockVault(store, testKey(), agent.Options{}); err != nil {
+		t.Fatalf("failed to unlock vault: %v", err)
+	}
+
+	keyring, err := Keyring(store)
+	if err != nil {
+		t.Fatalf("failed to get keyring: %v", err)
+	}
+
+	aad := []byte("example.com")
+	ciphertext, err := keyring.Encrypt("mypassword", aad)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	// The agent's ciphertext must be readable with the same key locally
+	plaintext, err := db.NewLocalKeyring(testKey()).Decrypt(ciphertext, aad)
+	if err != nil {
+		t.Fatalf("failed to decrypt locally: %v", err)
+	}
+	if plaintext != "mypassword" {
+		t.Errorf("expected 'mypassword', got %q", plaintext)
+	}
+}
+
+// TestStatus tests that the session status reflects the unlock options
+func TestStatus(t *testing.T) {
+	store := setup(t)
+
+	if _, ok, err := Status(store); err != nil || ok {
+		t.Fatalf("expected no session while locked, got ok=%v err=%v", ok, err)
+	}
+
+	options := agent.Options{IdleTimeout: time.Minute, MaxLifetime: time.Hour}
+	if err := UnlockVault(store, testKey(), options); err != nil {
+		t.Fatalf("failed to unlock vault: %v", err)
+	}
+
+	status, ok, err := Status(store)
+	if err != nil || !ok {
+		t.Fatalf("expected a live session, got ok=%v err=%v", ok, err)
+	}
+	if status.IdleTimeout != time.Minute || status.MaxLifetime != time.Hour {
+		t.Errorf("expected session limits %v/%v, got %v/%v", time.Minute, time.Hour, status.IdleTimeout, status.MaxLifetime)
+	}
+	if remaining := status.Remaining(time.Now()); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("expected remaining time within the idle timeout, got %v", remaining)
+	}
+}
+
+// setupDataDir points the named vault directory and home directory at temporary directories
+func setupDataDir(t *testing.T) string {
+	t.Helper()
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	t.Setenv("HOME", t.TempDir())
+	return filepath.Join(dataHome, "vault-cli")
+}
+
+// TestResolve tests how vault names and paths map to database files
+func TestResolve(t *testing.T) {
+	dataDir := setupDataDir(t)
+
+	path, err := Resolve("")
+	if err != nil {
+		t.Fatalf("failed to resolve default vault: %v", err)
+	}
+	if want := filepath.Join(dataDir, "default.db"); path != want {
+		t.Errorf("expected default vault %q, got %q", want, path)
+	}
+
+	if path, _ := Resolve("personal"); path != filepath.Join(dataDir, "personal.db") {
+		t.Errorf("expected named vault in the data directory, got %q", path)
+	}
+
+	explicit := filepath.Join(t.TempDir(), "team.db")
+	if path, _ := Resolve(explicit); path != explicit {
+		t.Errorf("expected explicit path %q, got %q", explicit, path)
+	}
+
+	if _, err := Resolve("bad name!"); err == nil {
+		t.Error("expected error for invalid vault name")
+	}
+}
+
+// TestResolveLegacyPath tests that an existing ~/vault.db keeps being used
+func TestResolveLegacyPath(t *testing.T) {
+	setupDataDir(t)
+
+	legacy := filepath.Join(os.Getenv("HOME"), "vault.db")
+	if err := os.WriteFile(legacy, nil, 0600); err != nil {
+		t.Fatalf("failed to create legacy vault: %v", err)
+	}
+
+	path, err := Resolve("")
+	if err != nil {
+		t.Fatalf("failed to resolve vault: %v", err)
+	}
+	if path != legacy {
+		t.Errorf("expected legacy vault %q, got %q", legacy, path)
+	}
+}
+
+// TestList tests creating and listing named vaults
+func TestList(t *testing.T) {
+	dataDir := setupDataDir(t)
+
+	for _, name := range []string{"work", "personal"} {
+		if _, err := Create(name); err != nil {
+			t.Fatalf("failed to create vault: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dataDir, name+".db"), nil, 0600); err != nil {
+			t.Fatalf("failed to create vault file: %v", err)
+		}
+	}
+	if _, err := Create("work"); err == nil {
+		t.Error("expected error creating an existing vault")
+	}
+	if err := Exists("missing"); err == nil {
+		t.Error("expected error for a vault that does not exist")
+	}
+
+	vaults, err := List(filepath.Join(dataDir, "work.db"))
+	if err != nil {
+		t.Fatalf("failed to list vaults: %v", err)
+	}
+	if len(vaults) != 2 || vaults[0].Name != "personal" || vaults[1].Name != "work" {
+		t.Fatalf("expected vaults [personal work], got %+v", vaults)
+	}
+	if vaults[0].Current || !vaults[1].Current {
+		t.Errorf("expected only 'work' to be current, got %+v", vaults)
+	}
 }